@@ -0,0 +1,273 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpyum/internal/config"
+	"httpyum/internal/curl"
+	"httpyum/internal/openapi"
+	"httpyum/internal/parser"
+	"httpyum/internal/postman"
+)
+
+// runImport converts requests from another format and prints them or
+// appends them to cfg.Output.
+func runImport(cfg *config.Config) int {
+	if cfg.Format == config.FormatPostman {
+		return importPostman(cfg)
+	}
+	return importCurl(cfg)
+}
+
+// importCurl converts a curl command into a request. The command is read from
+// stdin when not given as arguments.
+func importCurl(cfg *config.Config) int {
+	var imp *curl.Import
+	var err error
+	switch len(cfg.Args) {
+	case 0:
+		data, readErr := io.ReadAll(os.Stdin)
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", readErr)
+			return 2
+		}
+		imp, err = curl.Parse(string(data))
+	case 1:
+		imp, err = curl.Parse(cfg.Args[0])
+	default:
+		imp, err = curl.ParseArgs(cfg.Args)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing curl command: %v\n", err)
+		return 2
+	}
+
+	for _, w := range imp.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	imp.Request.Name = cfg.RequestName
+	text := parser.FormatRequest(imp.Request)
+
+	if cfg.Output == "" {
+		fmt.Print(text)
+		return 0
+	}
+
+	if err := appendRequest(cfg.Output, text); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", cfg.Output, err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Added %s %s to %s\n", imp.Request.Method, imp.Request.URL, cfg.Output)
+	return 0
+}
+
+// importPostman converts a Postman collection. With a directory as output,
+// each top-level folder becomes its own .http file; Postman environments are
+// merged into http-client.env.json next to the output.
+func importPostman(cfg *config.Config) int {
+	f, err := os.Open(cfg.Args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	col, err := postman.ConvertCollection(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", cfg.Args[0], err)
+		return 2
+	}
+
+	for _, w := range col.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	if cfg.Output == "" {
+		merged := col.Merged()
+		fmt.Print(parser.FormatFile(merged.Variables, merged.Requests))
+		return 0
+	}
+
+	var paths []string
+	var files []postman.File
+	dir := cfg.Output
+	if strings.HasSuffix(cfg.Output, ".http") {
+		paths = append(paths, cfg.Output)
+		files = append(files, col.Merged())
+		dir = filepath.Dir(cfg.Output)
+	} else {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		for _, file := range col.Files {
+			paths = append(paths, filepath.Join(dir, httpFileName(file.Name)))
+			files = append(files, file)
+		}
+	}
+
+	for i, file := range files {
+		path := paths[i]
+		if err := appendRequest(path, parser.FormatFile(file.Variables, file.Requests)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "Added %d requests to %s\n", len(file.Requests), path)
+	}
+
+	for _, envPath := range cfg.EnvImports {
+		if err := importPostmanEnvironment(envPath, filepath.Join(dir, "http-client.env.json")); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing environment %s: %v\n", envPath, err)
+			return 2
+		}
+	}
+	return 0
+}
+
+// importPostmanEnvironment adds the variables of a Postman environment to an
+// http-client.env.json file, creating it if needed.
+func importPostmanEnvironment(path, envFile string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	name, vars, err := postman.ConvertEnvironment(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	envs := map[string]map[string]any{}
+	if data, err := os.ReadFile(envFile); err == nil {
+		if err := json.Unmarshal(data, &envs); err != nil {
+			return fmt.Errorf("%s: %w", envFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if envs[name] == nil {
+		envs[name] = map[string]any{}
+	}
+	for k, v := range vars {
+		envs[name][k] = v
+	}
+
+	data, err := json.MarshalIndent(envs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(envFile, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Added environment %q to %s\n", name, envFile)
+	return nil
+}
+
+// httpFileName turns a collection or folder name into a file name.
+func httpFileName(name string) string {
+	clean := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+	clean = strings.Trim(clean, "-")
+	if clean == "" {
+		clean = "requests"
+	}
+	return clean + ".http"
+}
+
+// runGenerate writes a .http file with one request per operation of an
+// OpenAPI or Swagger spec.
+func runGenerate(cfg *config.Config) int {
+	result, err := openapi.Generate(cfg.SpecFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	text := parser.FormatFile(result.Variables, result.Requests)
+	if cfg.Output == "" {
+		fmt.Print(text)
+		return 0
+	}
+
+	if _, err := os.Stat(cfg.Output); err == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists\n", cfg.Output)
+		return 2
+	}
+	if err := os.WriteFile(cfg.Output, []byte(text), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", cfg.Output, err)
+		return 2
+	}
+	fmt.Fprintf(os.Stderr, "Generated %d requests in %s\n", len(result.Requests), cfg.Output)
+	return 0
+}
+
+// appendRequest appends a formatted request to path, creating the file if
+// needed and keeping a blank line between it and the previous request.
+func appendRequest(path, text string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if len(existing) > 0 {
+		switch {
+		case strings.HasSuffix(string(existing), "\n\n"):
+		case strings.HasSuffix(string(existing), "\n"):
+			text = "\n" + text
+		default:
+			text = "\n\n" + text
+		}
+	}
+
+	_, err = f.WriteString(text)
+	return err
+}
+
+// runExport prints the selected requests as curl commands, with variables
+// from the file and environment substituted.
+func runExport(cfg *config.Config, parsedFile *parser.ParsedFile, envVars, environment map[string]string) int {
+	requests, err := parser.SelectRequests(parsedFile.Requests, cfg.Selectors)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	variables := parser.BuildVariableMap(parsedFile.Variables, envVars, environment)
+	for i, req := range requests {
+		if len(requests) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			title := req.Description
+			if title == "" {
+				title = req.Method + " " + req.URL
+			}
+			fmt.Printf("# %s\n", title)
+		}
+		fmt.Println(curl.Command(&req, variables))
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/config"
+	"httpyum/internal/cookies"
+	"httpyum/internal/diff"
+	"httpyum/internal/history"
+	"httpyum/internal/parser"
+)
+
+// diffSide is one of the two responses being compared.
+type diffSide struct {
+	label  string
+	result *client.ExecutionResult
+}
+
+// runDiff compares two responses and prints their differences. It returns
+// 0 when they match, 1 when they differ and 2 on errors.
+func runDiff(cfg *config.Config, parsedFile *parser.ParsedFile, envVars map[string]string, environments parser.Environments, tokens *client.TokenCache, jar *cookies.Store, store *history.Store) int {
+	variables := parser.BuildVariableMap(parsedFile.Variables, envVars, environments.Variables(cfg.Environment))
+	executor := client.NewExecutor(variables, client.Options{
+		Requests: parsedFile.Requests,
+		BaseDir:  filepath.Dir(parsedFile.Path),
+		OAuth2:   environments.OAuth2(cfg.Environment),
+		Tokens:   tokens,
+		Cookies:  cookieJar(jar, cfg.Environment),
+		Settings: requestSettings(cfg),
+		TLS:      environments.TLS(cfg.Environment),
+	})
+
+	var entries []*history.Entry
+	if store != nil {
+		var err error
+		if entries, err = store.ListFile(parsedFile.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+			return 2
+		}
+	}
+
+	run := func(req *parser.Request) diffSide {
+		result := executor.Execute(context.Background(), req)
+		if store != nil {
+			if err := store.Add(history.NewEntry(result, parsedFile.Path, cfg.Environment)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not save history: %v\n", err)
+			}
+		}
+		return diffSide{label: diffTitle(req) + " (now)", result: result}
+	}
+
+	resolve := func(arg string) (diffSide, error) {
+		if n, ok := strings.CutPrefix(arg, "@"); ok {
+			i, err := strconv.Atoi(n)
+			if err != nil || i < 1 {
+				return diffSide{}, fmt.Errorf("invalid history entry %q", arg)
+			}
+			if store == nil {
+				return diffSide{}, fmt.Errorf("history is disabled")
+			}
+			if i > len(entries) {
+				return diffSide{}, fmt.Errorf("no history entry %d (%d recorded)", i, len(entries))
+			}
+			e := entries[i-1]
+			return diffSide{label: historyLabel(e), result: e.Result()}, nil
+		}
+
+		req, err := selectOne(parsedFile.Requests, arg)
+		if err != nil {
+			return diffSide{}, err
+		}
+		return run(req), nil
+	}
+
+	var left, right diffSide
+	if len(cfg.Args) == 1 {
+		if strings.HasPrefix(cfg.Args[0], "@") {
+			fmt.Fprintln(os.Stderr, "Error: a history entry needs a second response to compare with")
+			return 2
+		}
+		req, err := selectOne(parsedFile.Requests, cfg.Args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		var previous *history.Entry
+		for _, e := range entries {
+			if e.RequestID == req.ID && e.Error == "" {
+				previous = e
+				break
+			}
+		}
+		if previous == nil {
+			fmt.Fprintf(os.Stderr, "Error: no earlier response to %q in the history\n", diffTitle(req))
+			return 2
+		}
+		left = diffSide{label: historyLabel(previous), result: previous.Result()}
+		right = run(req)
+	} else {
+		var err error
+		if left, err = resolve(cfg.Args[0]); err == nil {
+			right, err = resolve(cfg.Args[1])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	for _, side := range []diffSide{left, right} {
+		if side.result.Error != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s failed: %v\n", side.label, side.result.Error)
+			return 2
+		}
+	}
+
+	result := diff.Responses(left.result.Response, right.result.Response, diff.Options{AllHeaders: cfg.AllHeaders})
+	if cfg.NoHeaders {
+		result.Headers = nil
+	}
+
+	fmt.Printf("--- %s\n", left.label)
+	fmt.Printf("+++ %s\n", right.label)
+	if result.Equal() {
+		fmt.Println("No differences")
+		return 0
+	}
+	for _, c := range result.Changes() {
+		fmt.Println(c.String())
+	}
+	return 1
+}
+
+// selectOne resolves a selector that must match exactly one request.
+func selectOne(requests []parser.Request, selector string) (*parser.Request, error) {
+	selected, err := parser.SelectRequests(requests, []string{selector})
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) != 1 {
+		return nil, fmt.Errorf("%q matches %d requests, expected one", selector, len(selected))
+	}
+	return &selected[0], nil
+}
+
+func diffTitle(req *parser.Request) string {
+	if req.Description != "" {
+		return req.Description
+	}
+	if req.Name != "" {
+		return req.Name
+	}
+	return req.Method + " " + req.URL
+}
+
+func historyLabel(e *history.Entry) string {
+	return fmt.Sprintf("%s (%s)", e.Title(), e.Time.Local().Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/config"
+	"httpyum/internal/cookies"
+	"httpyum/internal/parser"
+)
+
+// runGraphQL introspects the endpoint of a GraphQL request and prints the
+// operations it offers. It returns 0 on success and 2 on errors.
+func runGraphQL(cfg *config.Config, parsedFile *parser.ParsedFile, envVars map[string]string, environments parser.Environments, tokens *client.TokenCache, jar *cookies.Store) int {
+	var req *parser.Request
+	if len(cfg.Args) == 1 {
+		selected, err := selectOne(parsedFile.Requests, cfg.Args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		req = selected
+	} else {
+		for i := range parsedFile.Requests {
+			if parsedFile.Requests[i].IsGraphQL() {
+				req = &parsedFile.Requests[i]
+				break
+			}
+		}
+		if req == nil {
+			fmt.Fprintf(os.Stderr, "Error: no GraphQL request in %s; name the request to introspect\n", cfg.FilePath)
+			return 2
+		}
+	}
+
+	variables := parser.BuildVariableMap(parsedFile.Variables, envVars, environments.Variables(cfg.Environment))
+	executor := client.NewExecutor(variables, client.Options{
+		Requests: parsedFile.Requests,
+		BaseDir:  filepath.Dir(parsedFile.Path),
+		OAuth2:   environments.OAuth2(cfg.Environment),
+		Tokens:   tokens,
+		Cookies:  cookieJar(jar, cfg.Environment),
+		Settings: requestSettings(cfg),
+		TLS:      environments.TLS(cfg.Environment),
+	})
+
+	schema, err := executor.Introspect(context.Background(), req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	sections := []struct {
+		title     string
+		operation string
+		fields    []client.GraphQLField
+	}{
+		{"Queries", "query", schema.Queries},
+		{"Mutations", "mutation", schema.Mutations},
+		{"Subscriptions", "subscription", schema.Subscriptions},
+	}
+
+	if cfg.GraphQLNames {
+		for _, section := range sections {
+			for _, f := range section.fields {
+				fmt.Printf("%s %s\n", section.operation, f.Name)
+			}
+		}
+		return 0
+	}
+
+	first := true
+	for _, section := range sections {
+		if len(section.fields) == 0 {
+			continue
+		}
+		if !first {
+			fmt.Println()
+		}
+		first = false
+		fmt.Printf("%s:\n", section.title)
+		for _, f := range section.fields {
+			fmt.Printf("  %s\n", f.Signature)
+			if description, _, _ := strings.Cut(f.Description, "\n"); description != "" {
+				fmt.Printf("      %s\n", description)
+			}
+		}
+	}
+	if first {
+		fmt.Println("The schema has no operations.")
+	}
+	return 0
+}
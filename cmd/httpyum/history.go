@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"httpyum/internal/config"
+	"httpyum/internal/history"
+	"httpyum/internal/runner"
+)
+
+// openHistory returns the history store of the project containing filePath.
+func openHistory(filePath string) (*history.Store, error) {
+	stateDir, err := config.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return history.Open(stateDir, filepath.Dir(filePath))
+}
+
+// runHistory lists or prints the history of the file, or clears the history
+// of its project. Entries are numbered newest first, matching --show.
+func runHistory(cfg *config.Config) int {
+	store, err := openHistory(cfg.FilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening history: %v\n", err)
+		return 2
+	}
+
+	if cfg.HistoryClear {
+		if err := store.Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error clearing history: %v\n", err)
+			return 2
+		}
+		fmt.Fprintln(os.Stderr, "History cleared")
+		return 0
+	}
+
+	entries, err := store.ListFile(cfg.FilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		return 2
+	}
+
+	if cfg.HistoryShow > 0 {
+		if cfg.HistoryShow > len(entries) {
+			fmt.Fprintf(os.Stderr, "Error: no history entry %d (%d recorded)\n", cfg.HistoryShow, len(entries))
+			return 2
+		}
+		e := entries[cfg.HistoryShow-1]
+		fmt.Printf("%s", e.Time.Local().Format("2006-01-02 15:04:05"))
+		if e.Environment != "" {
+			fmt.Printf(" (env: %s)", e.Environment)
+		}
+		fmt.Println()
+		runner.PrintResult(os.Stdout, e.Result(), runner.Options{
+			ShowHeaders: !cfg.NoHeaders,
+			ShowBody:    true,
+		})
+		if e.Truncated {
+			fmt.Println("(response body truncated)")
+		}
+		return 0
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(os.Stderr, "No history recorded")
+		return 0
+	}
+
+	if cfg.HistoryLimit > 0 && len(entries) > cfg.HistoryLimit {
+		entries = entries[:cfg.HistoryLimit]
+	}
+	for i, e := range entries {
+		status := e.Status
+		if e.Error != "" {
+			status = "error"
+		}
+		fmt.Printf("%3d  %s  %-20s %s %s", i+1, e.Time.Local().Format("2006-01-02 15:04:05"), status, e.Method, e.URL)
+		if e.Error == "" {
+			fmt.Printf("  %s", e.Duration)
+		}
+		if title := e.Title(); title != e.Method+" "+e.URL {
+			fmt.Printf("  # %s", title)
+		}
+		fmt.Println()
+	}
+	return 0
+}
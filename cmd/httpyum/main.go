@@ -2,10 +2,19 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"httpyum/internal/client"
 	"httpyum/internal/config"
+	"httpyum/internal/cookies"
+	"httpyum/internal/history"
 	"httpyum/internal/parser"
+	"httpyum/internal/runner"
+	"httpyum/internal/snapshot"
 	"httpyum/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,14 +27,16 @@ func main() {
 		os.Exit(1)
 	}
 
-	file, err := os.Open(cfg.FilePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
-		os.Exit(1)
+	switch cfg.Command {
+	case config.CommandImport:
+		os.Exit(runImport(cfg))
+	case config.CommandGenerate:
+		os.Exit(runGenerate(cfg))
+	case config.CommandHistory:
+		os.Exit(runHistory(cfg))
 	}
-	defer file.Close()
 
-	parsedFile, err := parser.Parse(file)
+	parsedFile, err := parser.ParseFile(cfg.FilePath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
 		os.Exit(1)
@@ -36,10 +47,64 @@ func main() {
 		os.Exit(1)
 	}
 
-	envVars := parser.LoadSystemEnv()
+	envVars, err := parser.LoadEnv(cfg.FilePath, cfg.EnvFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading env file: %v\n", err)
+		os.Exit(1)
+	}
+
+	environments, err := parser.LoadEnvironments(filepath.Dir(cfg.FilePath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading environments: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.Environment != "" {
+		if _, ok := environments[cfg.Environment]; !ok {
+			fmt.Fprintf(os.Stderr, "Unknown environment %q (available: %s)\n", cfg.Environment, strings.Join(environments.Names(), ", "))
+			os.Exit(1)
+		}
+	}
+
+	var store *history.Store
+	if !cfg.NoHistory {
+		if store, err = openHistory(cfg.FilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: history disabled: %v\n", err)
+		} else {
+			store.SetSecretHeaders(cfg.SecretHeaders)
+		}
+	}
+
+	jar, err := openCookies(cfg.FilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cookies will not be stored: %v\n", err)
+	}
 
-	showHeaders := !cfg.NoHeaders
-	model := ui.NewModel(parsedFile, envVars, showHeaders)
+	tokens, err := openTokenCache()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: OAuth2 tokens will not be cached: %v\n", err)
+	}
+
+	switch cfg.Command {
+	case config.CommandRun, config.CommandSnapshot:
+		os.Exit(runHeadless(cfg, parsedFile, envVars, environments, tokens, jar, store))
+	case config.CommandExport:
+		os.Exit(runExport(cfg, parsedFile, envVars, environments.Variables(cfg.Environment)))
+	case config.CommandDiff:
+		os.Exit(runDiff(cfg, parsedFile, envVars, environments, tokens, jar, store))
+	case config.CommandGraphQL:
+		os.Exit(runGraphQL(cfg, parsedFile, envVars, environments, tokens, jar))
+	}
+
+	model := ui.NewModel(parsedFile, envVars, ui.Options{
+		ShowHeaders:  !cfg.NoHeaders,
+		Environments: environments,
+		Environment:  cfg.Environment,
+		History:      store,
+		Tokens:       tokens,
+		Cookies:      jar,
+		Settings:     requestSettings(cfg),
+	})
 
 	p := tea.NewProgram(model, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
@@ -47,3 +112,119 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runHeadless executes requests without the TUI and returns the exit code:
+// 0 when every request passed, 1 when any failed and 2 on usage errors.
+// The snapshot command runs the same way with responses checked against
+// their snapshots instead of printed.
+func runHeadless(cfg *config.Config, parsedFile *parser.ParsedFile, envVars map[string]string, environments parser.Environments, tokens *client.TokenCache, jar *cookies.Store, store *history.Store) int {
+	opts := runner.Options{
+		Environment:  environments.Variables(cfg.Environment),
+		OAuth2:       environments.OAuth2(cfg.Environment),
+		Tokens:       tokens,
+		Cookies:      cookieJar(jar, cfg.Environment),
+		Settings:     requestSettings(cfg),
+		TLS:          environments.TLS(cfg.Environment),
+		Selectors:    cfg.Selectors,
+		ExpectStatus: cfg.ExpectStatus,
+		ShowHeaders:  !cfg.NoHeaders,
+		ShowBody:     !cfg.NoBody,
+		FailFast:     cfg.FailFast,
+		Report:       cfg.Report,
+		SuiteName:    cfg.FilePath,
+
+		History:         store,
+		EnvironmentName: cfg.Environment,
+	}
+
+	if cfg.Command == config.CommandSnapshot {
+		snapshots, err := snapshot.Load(cfg.FilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading snapshots: %v\n", err)
+			return 2
+		}
+		rules := snapshot.Rules{IgnorePaths: cfg.IgnorePaths, IgnoreHeaders: cfg.IgnoreHeaders}
+		if err := rules.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		opts.Snapshots = snapshots
+		opts.SnapshotRules = rules
+		opts.UpdateSnapshots = cfg.UpdateSnapshots
+		opts.ShowHeaders = false
+		opts.ShowBody = false
+	}
+
+	var reportOut io.Writer
+	if cfg.ReportFile != "" {
+		f, err := os.Create(cfg.ReportFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating report file: %v\n", err)
+			return 2
+		}
+		defer f.Close()
+		reportOut = f
+	}
+
+	opts.ReportOut = reportOut
+
+	summary, err := runner.Run(os.Stdout, parsedFile, envVars, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if summary.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// requestSettings returns the request settings given on the command line.
+// A --proxy without --no-proxy is bypassed for the hosts in NO_PROXY.
+func requestSettings(cfg *config.Config) client.Settings {
+	noProxy := cfg.NoProxy
+	if noProxy == "" && cfg.Proxy != "" {
+		noProxy = os.Getenv("NO_PROXY")
+		if noProxy == "" {
+			noProxy = os.Getenv("no_proxy")
+		}
+	}
+	return client.Settings{
+		Timeout:           cfg.Timeout,
+		ConnectionTimeout: cfg.ConnectionTimeout,
+		MaxRedirects:      cfg.MaxRedirects,
+		NoRedirect:        cfg.NoRedirect,
+		Insecure:          cfg.Insecure,
+		Proxy:             cfg.Proxy,
+		NoProxy:           noProxy,
+	}
+}
+
+// openTokenCache returns the OAuth2 token cache shared by all projects. On
+// error the returned cache keeps tokens in memory only.
+func openTokenCache() (*client.TokenCache, error) {
+	cacheDir, err := config.CacheDir()
+	if err != nil {
+		return client.NewTokenCache(""), err
+	}
+	return client.NewTokenCache(filepath.Join(cacheDir, "oauth2-tokens.json")), nil
+}
+
+// openCookies returns the cookie store of the project containing filePath.
+func openCookies(filePath string) (*cookies.Store, error) {
+	stateDir, err := config.StateDir()
+	if err != nil {
+		return nil, err
+	}
+	return cookies.Open(stateDir, filepath.Dir(filePath))
+}
+
+// cookieJar returns the jar of environment, or nil when cookies are not
+// stored.
+func cookieJar(store *cookies.Store, environment string) http.CookieJar {
+	if store == nil {
+		return nil
+	}
+	return store.Jar(environment)
+}
@@ -0,0 +1,184 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+// AssertionResult is the outcome of evaluating one assertion.
+type AssertionResult struct {
+	Name    string
+	Passed  bool
+	Actual  string
+	Message string
+}
+
+// EvaluateAssertions checks each assertion against resp. Expected values
+// are passed through substitute first so they may contain variables.
+func EvaluateAssertions(assertions []parser.Assertion, resp *Response, substitute func(string) (string, error)) []AssertionResult {
+	results := make([]AssertionResult, 0, len(assertions))
+	for _, a := range assertions {
+		expected, err := substitute(a.Expected)
+		if err != nil {
+			results = append(results, AssertionResult{Name: a.Raw, Message: err.Error()})
+			continue
+		}
+		results = append(results, evaluateAssertion(a, expected, resp))
+	}
+	return results
+}
+
+func evaluateAssertion(a parser.Assertion, expected string, resp *Response) AssertionResult {
+	result := AssertionResult{Name: a.Raw}
+
+	actual, found, err := assertionSubject(a, resp)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Actual = actual
+
+	switch a.Operator {
+	case "exists":
+		result.Passed = found
+	case "!exists":
+		result.Passed = !found
+	default:
+		if !found {
+			result.Message = fmt.Sprintf("%s not found", describeSubject(a))
+			return result
+		}
+		passed, err := compare(a.Subject, a.Operator, actual, expected)
+		if err != nil {
+			result.Message = err.Error()
+			return result
+		}
+		result.Passed = passed
+	}
+
+	if !result.Passed && result.Message == "" {
+		if found {
+			result.Message = fmt.Sprintf("%s was %q", describeSubject(a), truncateValue(actual, 80))
+		} else {
+			result.Message = fmt.Sprintf("%s not found", describeSubject(a))
+		}
+	}
+	return result
+}
+
+func assertionSubject(a parser.Assertion, resp *Response) (string, bool, error) {
+	switch a.Subject {
+	case "status":
+		return strconv.Itoa(resp.StatusCode), true, nil
+	case "duration":
+		return resp.Duration.String(), true, nil
+	case "size":
+		return strconv.FormatInt(resp.Size, 10), true, nil
+	case "header":
+		values := resp.Headers.Values(a.Path)
+		if len(values) == 0 {
+			return "", false, nil
+		}
+		return strings.Join(values, ", "), true, nil
+	case "body":
+		if a.Path == "" {
+			return string(resp.Body), len(resp.Body) > 0, nil
+		}
+		return QueryBody(resp.Body, a.Path)
+	}
+	return "", false, fmt.Errorf("unknown assertion subject %q", a.Subject)
+}
+
+func describeSubject(a parser.Assertion) string {
+	if a.Path != "" {
+		if a.Subject == "body" {
+			return "body." + a.Path
+		}
+		return a.Subject + " " + a.Path
+	}
+	return a.Subject
+}
+
+func compare(subject, op, actual, expected string) (bool, error) {
+	switch op {
+	case "contains":
+		return strings.Contains(actual, expected), nil
+	case "!contains":
+		return !strings.Contains(actual, expected), nil
+	case "startsWith":
+		return strings.HasPrefix(actual, expected), nil
+	case "endsWith":
+		return strings.HasSuffix(actual, expected), nil
+	case "matches":
+		re, err := regexp.Compile(expected)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", expected, err)
+		}
+		return re.MatchString(actual), nil
+	}
+
+	var a, e float64
+	var numeric bool
+	if subject == "duration" {
+		actualDur, err1 := time.ParseDuration(actual)
+		expectedDur, err2 := parseAssertDuration(expected)
+		if err1 != nil || err2 != nil {
+			return false, fmt.Errorf("invalid duration %q", expected)
+		}
+		a, e, numeric = float64(actualDur), float64(expectedDur), true
+	} else {
+		var err1, err2 error
+		a, err1 = strconv.ParseFloat(actual, 64)
+		e, err2 = strconv.ParseFloat(expected, 64)
+		numeric = err1 == nil && err2 == nil
+	}
+
+	switch op {
+	case "==":
+		if numeric {
+			return a == e, nil
+		}
+		return actual == expected, nil
+	case "!=":
+		if numeric {
+			return a != e, nil
+		}
+		return actual != expected, nil
+	}
+
+	if !numeric {
+		return false, fmt.Errorf("cannot compare %q %s %q: not numbers", actual, op, expected)
+	}
+	switch op {
+	case "<":
+		return a < e, nil
+	case "<=":
+		return a <= e, nil
+	case ">":
+		return a > e, nil
+	case ">=":
+		return a >= e, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", op)
+}
+
+// parseAssertDuration accepts Go durations ("500ms", "2s") and bare numbers,
+// which are taken as milliseconds.
+func parseAssertDuration(s string) (time.Duration, error) {
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(ms * float64(time.Millisecond)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+func truncateValue(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
@@ -0,0 +1,335 @@
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+// prepareAuth rewrites the Authorization header of resolved. Basic
+// credentials written as "user:pass" or "user pass" are base64-encoded in
+// place. Digest and AWS credentials are removed and returned so the request
+// can be authenticated once it is built; other values are left as they are.
+func prepareAuth(resolved *ResolvedRequest) (*parser.Auth, error) {
+	for i, h := range resolved.Headers {
+		if !strings.EqualFold(h.Key, "Authorization") {
+			continue
+		}
+
+		auth, err := parser.ParseAuth(h.Value)
+		if err != nil || auth == nil {
+			return nil, err
+		}
+		if auth.Scheme == parser.AuthBasic {
+			resolved.Headers[i].Value = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.User+":"+auth.Password))
+			return nil, nil
+		}
+		resolved.Headers = append(resolved.Headers[:i:i], resolved.Headers[i+1:]...)
+		return auth, nil
+	}
+	return nil, nil
+}
+
+// setAuthorization records a header added while authenticating in the
+// resolved request, so the request is shown as it was sent.
+func setAuthorization(resolved *ResolvedRequest, key, value string) {
+	for i, h := range resolved.Headers {
+		if strings.EqualFold(h.Key, key) {
+			resolved.Headers[i].Value = value
+			return
+		}
+	}
+	resolved.Headers = append(resolved.Headers, parser.Header{Key: key, Value: value})
+}
+
+// --- Digest (RFC 7616) ---
+
+// digestChallenge returns the parameters of the Digest challenge in a 401
+// response, or nil when the server did not offer one.
+func digestChallenge(resp *http.Response) map[string]string {
+	for _, value := range resp.Header.Values("WWW-Authenticate") {
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+		if strings.EqualFold(scheme, "Digest") {
+			return parseAuthParams(rest)
+		}
+	}
+	return nil
+}
+
+// parseAuthParams parses comma-separated key=value pairs whose values may
+// be quoted.
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			var sb strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+			}
+			value = sb.String()
+			s = s[min(i+1, len(s)):]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			value = strings.TrimSpace(s[:end])
+			s = s[end:]
+		}
+		params[key] = value
+
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), ","))
+	}
+	return params
+}
+
+// digestAuthorization answers challenge for a request with the given
+// method, request URI and body.
+func digestAuthorization(challenge map[string]string, creds *parser.Auth, method, uri, body string) (string, error) {
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", fmt.Errorf("digest auth: unsupported algorithm %s", algorithm)
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	realm, nonce := challenge["realm"], challenge["nonce"]
+
+	var qop string
+	for _, q := range strings.Split(challenge["qop"], ",") {
+		switch q = strings.TrimSpace(q); q {
+		case "auth":
+			qop = q
+		case "auth-int":
+			if qop == "" {
+				qop = q
+			}
+		}
+	}
+
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	const nc = "00000001"
+
+	ha1 := h(creds.User + ":" + realm + ":" + creds.Password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	if qop == "auth-int" {
+		ha2 = h(method + ":" + uri + ":" + h(body))
+	}
+
+	var response string
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	} else {
+		response = h(ha1 + ":" + nonce + ":" + ha2)
+	}
+
+	parts := []string{
+		fmt.Sprintf("username=%q", creds.User),
+		fmt.Sprintf("realm=%q", realm),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+		"algorithm=" + algorithm,
+		fmt.Sprintf("response=%q", response),
+	}
+	if qop != "" {
+		parts = append(parts, "qop="+qop, "nc="+nc, fmt.Sprintf("cnonce=%q", cnonce))
+	}
+	if opaque, ok := challenge["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf("opaque=%q", opaque))
+	}
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// --- AWS Signature Version 4 ---
+
+// signAWS adds the X-Amz-* and Authorization headers to req. Region and
+// service default to those in an *.amazonaws.com host name.
+func signAWS(req *http.Request, body string, creds *parser.Auth, now time.Time) error {
+	region, service := creds.Region, creds.Service
+	if region == "" || service == "" {
+		r, s := awsEndpoint(req.URL.Hostname())
+		if region == "" {
+			region = r
+		}
+		if service == "" {
+			service = s
+		}
+	}
+	if region == "" || service == "" {
+		return fmt.Errorf("AWS auth: cannot infer region and service from %s; add region:<region> service:<service>", req.URL.Host)
+	}
+
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Canonical headers: host and every header set on the request.
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.Join(strings.Fields(strings.Join(v, ",")), " ")
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if service != "s3" {
+		path = awsEscape(path, false)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.Password), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.User, scope, signedHeaders, signature))
+	return nil
+}
+
+// awsEndpoint infers region and service from hosts such as
+// sqs.eu-west-1.amazonaws.com, abc.execute-api.us-east-1.amazonaws.com or
+// iam.amazonaws.com (global services sign for us-east-1).
+func awsEndpoint(host string) (region, service string) {
+	name, ok := strings.CutSuffix(host, ".amazonaws.com")
+	if !ok {
+		return "", ""
+	}
+	labels := strings.Split(name, ".")
+	last := labels[len(labels)-1]
+	if len(labels) >= 2 && isAWSRegion(last) {
+		return last, labels[len(labels)-2]
+	}
+	return "us-east-1", last
+}
+
+func isAWSRegion(s string) bool {
+	return strings.Count(s, "-") >= 2 && s[len(s)-1] >= '0' && s[len(s)-1] <= '9'
+}
+
+func awsCanonicalQuery(values url.Values) string {
+	var pairs []string
+	for k, vs := range values {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters, keeping
+// slashes unless encodeSlash is set.
+func awsEscape(s string, encodeSlash bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			sb.WriteByte(c)
+		default:
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpyum/internal/parser"
+)
+
+// payload is a request body as it is sent: text, with the files included by
+// "< path" lines streamed from disk when the request is written.
+type payload struct {
+	parts  []parser.BodyPart
+	length int64
+}
+
+// buildPayload resolves the file references in the body of resolved
+// against the executor's base directory. "<@ path" files are read and
+// substituted now; "< path" files are only checked. Multipart bodies get
+// CRLF line breaks, and a boundary in their Content-Type if it was left
+// out; their parts are written by hand in the body, so one that does not
+// end with the closing delimiter fails here instead of at the server.
+func (e *Executor) buildPayload(ctx context.Context, resolved *ResolvedRequest, vars map[string]string) (*payload, error) {
+	if resolved.Body == "" {
+		return nil, nil
+	}
+	multipart := isMultipart(resolved)
+
+	p := &payload{}
+	parts := parser.SplitBody(resolved.Body)
+	for i, part := range parts {
+		if part.Path == "" {
+			text := part.Text
+			if multipart {
+				text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+			} else if i == len(parts)-1 && i > 0 && strings.TrimSpace(text) == "" {
+				// Blank lines after a file are not part of the body.
+				continue
+			}
+			p.addText(text)
+			continue
+		}
+
+		path := part.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(e.baseDir, path)
+		}
+		if part.Substitute {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			text, err := e.substitute(ctx, string(data), vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", part.Path, err)
+			}
+			p.addText(text)
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s is a directory", part.Path)
+		}
+		p.parts = append(p.parts, parser.BodyPart{Path: path})
+		p.length += info.Size()
+	}
+
+	if multipart {
+		addBoundary(resolved)
+		if err := checkMultipart(resolved, p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func (p *payload) addText(text string) {
+	if n := len(p.parts); n > 0 && p.parts[n-1].Path == "" {
+		p.parts[n-1].Text += text
+	} else {
+		p.parts = append(p.parts, parser.BodyPart{Text: text})
+	}
+	p.length += int64(len(text))
+}
+
+// reader returns a reader of the whole body that opens each file when it
+// is reached.
+func (p *payload) reader() io.ReadCloser {
+	return &payloadReader{parts: p.parts}
+}
+
+// bytes reads the whole body into memory, for authentication schemes that
+// hash it.
+func (p *payload) bytes() ([]byte, error) {
+	r := p.reader()
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type payloadReader struct {
+	parts   []parser.BodyPart
+	current io.Reader
+	file    *os.File
+}
+
+func (r *payloadReader) Read(b []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part := r.parts[0]
+			r.parts = r.parts[1:]
+			if part.Path == "" {
+				r.current = strings.NewReader(part.Text)
+			} else {
+				f, err := os.Open(part.Path)
+				if err != nil {
+					return 0, err
+				}
+				r.file, r.current = f, f
+			}
+		}
+
+		n, err := r.current.Read(b)
+		if err == io.EOF {
+			r.closeFile()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *payloadReader) Close() error {
+	r.closeFile()
+	r.parts = nil
+	return nil
+}
+
+func (r *payloadReader) closeFile() {
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+}
+
+// isMultipart reports whether resolved has a multipart Content-Type.
+func isMultipart(resolved *ResolvedRequest) bool {
+	for _, h := range resolved.Headers {
+		if strings.EqualFold(h.Key, "Content-Type") {
+			return strings.HasPrefix(strings.ToLower(strings.TrimSpace(h.Value)), "multipart/")
+		}
+	}
+	return false
+}
+
+// addBoundary adds the boundary of a multipart body to its Content-Type
+// when it is missing, taking it from the body's first delimiter line.
+func addBoundary(resolved *ResolvedRequest) {
+	first, _, _ := strings.Cut(strings.TrimLeft(resolved.Body, "\r\n"), "\n")
+	boundary, ok := strings.CutPrefix(strings.TrimSpace(first), "--")
+	if !ok || boundary == "" {
+		return
+	}
+	for i, h := range resolved.Headers {
+		if !strings.EqualFold(h.Key, "Content-Type") {
+			continue
+		}
+		if _, params, err := mime.ParseMediaType(h.Value); err == nil && params["boundary"] != "" {
+			return
+		}
+		resolved.Headers[i].Value = strings.TrimRight(strings.TrimSpace(h.Value), ";") + "; boundary=" + boundary
+		return
+	}
+}
+
+// checkMultipart reports a multipart body without a boundary, or whose
+// last line is not the closing delimiter "--boundary--".
+func checkMultipart(resolved *ResolvedRequest, p *payload) error {
+	boundary := ""
+	for _, h := range resolved.Headers {
+		if strings.EqualFold(h.Key, "Content-Type") {
+			if _, params, err := mime.ParseMediaType(h.Value); err == nil {
+				boundary = params["boundary"]
+			}
+			break
+		}
+	}
+	if boundary == "" {
+		return fmt.Errorf("multipart body has no boundary: set it in Content-Type or start the body with a --boundary line")
+	}
+
+	closing := "--" + boundary + "--"
+	if n := len(p.parts); n > 0 && p.parts[n-1].Path == "" {
+		text := strings.TrimRight(p.parts[n-1].Text, " \t\r\n")
+		if text == closing || strings.HasSuffix(text, "\n"+closing) {
+			return nil
+		}
+	}
+	return fmt.Errorf("multipart body does not end with the closing delimiter %s", closing)
+}
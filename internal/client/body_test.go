@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"httpyum/internal/parser"
+)
+
+func TestBuildPayloadMultipart(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.txt"), []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	e := NewExecutor(nil, Options{BaseDir: dir})
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantType    string
+		wantErr     string
+	}{
+		{
+			name:        "complete",
+			contentType: "multipart/form-data; boundary=B",
+			body:        "--B\nContent-Disposition: form-data; name=\"a\"\n\n1\n--B--\n",
+			want:        "--B\r\nContent-Disposition: form-data; name=\"a\"\r\n\r\n1\r\n--B--\r\n",
+			wantType:    "multipart/form-data; boundary=B",
+		},
+		{
+			name:        "boundary from body",
+			contentType: "multipart/form-data",
+			body:        "--B\nContent-Disposition: form-data; name=\"f\"\n\n< ./report.txt\n--B--",
+			want:        "--B\r\nContent-Disposition: form-data; name=\"f\"\r\n\r\ndata\r\n--B--",
+			wantType:    "multipart/form-data; boundary=B",
+		},
+		{
+			name:        "missing closing delimiter",
+			contentType: "multipart/form-data; boundary=B",
+			body:        "--B\nContent-Disposition: form-data; name=\"a\"\n\n1\n--B\n",
+			wantErr:     "closing delimiter --B--",
+		},
+		{
+			name:        "ends with file",
+			contentType: "multipart/form-data; boundary=B",
+			body:        "--B\nContent-Disposition: form-data; name=\"f\"\n\n< ./report.txt",
+			wantErr:     "closing delimiter --B--",
+		},
+		{
+			name:        "no boundary",
+			contentType: "multipart/form-data",
+			body:        "Content-Disposition: form-data; name=\"a\"\n\n1\n",
+			wantErr:     "no boundary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := &ResolvedRequest{
+				Headers: []parser.Header{{Key: "Content-Type", Value: tt.contentType}},
+				Body:    tt.body,
+			}
+			p, err := e.buildPayload(context.Background(), resolved, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildPayload() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := p.bytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("body = %q, want %q", data, tt.want)
+			}
+			if int64(len(data)) != p.length {
+				t.Errorf("length = %d, want %d", p.length, len(data))
+			}
+			if got := resolved.Headers[0].Value; got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+		})
+	}
+}
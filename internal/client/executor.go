@@ -2,90 +2,287 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"httpyum/internal/parser"
+
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 type Executor struct {
-	client    *http.Client
+	// client sends requests of the executor's own, such as OAuth2 token
+	// requests, with the default settings and without cookies.
+	client     *http.Client
+	settings   Settings
+	jar        http.CookieJar
+	tls        []*parser.TLSConfig
+	mu         sync.Mutex
+	transports map[transportKey]*http.Transport
+	// reflected caches the descriptors of gRPC services fetched with
+	// server reflection, by server address and service.
+	reflected map[string]*protoregistry.Files
+
+	// running serializes executions, which share the results of named
+	// requests and the globals set by scripts. A cancelled request may
+	// still be finishing when the next one starts.
+	running sync.Mutex
+	// state guards replacing globals and adding results, which happen
+	// while running is held, against Substitute, which reads them without
+	// waiting for a cancelled request to finish.
+	state sync.Mutex
+
 	variables map[string]string
+	globals   map[string]string
+	requests  []parser.Request
+	results   map[string]*ExecutionResult
+	pending   map[string]bool
+	baseDir   string
+
+	oauth2      map[string]*parser.OAuth2Config
+	tokens      *TokenCache
+	tokenStatus map[string]*TokenStatus
+	promptFunc  func(string)
+}
+
+// Options configures an Executor.
+type Options struct {
+	// Requests are all requests of the file. Named requests among them can
+	// be referenced from other requests and are executed on demand.
+	Requests []parser.Request
+	// BaseDir is the directory relative file references are resolved
+	// against, usually the directory of the .http file.
+	BaseDir string
+
+	// OAuth2 are the providers that {{$oauth2 name}} and
+	// "Authorization: OAuth2 name" refer to.
+	OAuth2 map[string]*parser.OAuth2Config
+	// Tokens caches OAuth2 tokens between runs. Nil keeps them in memory.
+	Tokens *TokenCache
+	// Settings are the defaults for timeouts, redirects and TLS
+	// verification, which requests can override with annotations.
+	Settings Settings
+	// TLS are the client certificates, CAs and other TLS settings of the
+	// hosts that need them.
+	TLS []*parser.TLSConfig
+	// Cookies stores cookies from responses and sends them with later
+	// requests, except those annotated with "# @no-cookie-jar".
+	Cookies http.CookieJar
+	// Prompt shows instructions of interactive OAuth2 flows, such as the
+	// URL to open. Nil writes them to stderr.
+	Prompt func(message string)
 }
 
-func NewExecutor(variables map[string]string) *Executor {
-	return &Executor{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+var referenceRegex = regexp.MustCompile(`\{\{\s*([\w-]+)\.(request|response)\.(body|headers)\.(.+?)\s*\}\}`)
+
+func NewExecutor(variables map[string]string, opts Options) *Executor {
+	tokens := opts.Tokens
+	if tokens == nil {
+		tokens = NewTokenCache("")
+	}
+	e := &Executor{
+		settings:   opts.Settings,
+		jar:        opts.Cookies,
+		tls:        opts.TLS,
+		transports: make(map[transportKey]*http.Transport),
+		reflected:  make(map[string]*protoregistry.Files),
+
 		variables: variables,
+		globals:   make(map[string]string),
+		requests:  opts.Requests,
+		results:   make(map[string]*ExecutionResult),
+		pending:   make(map[string]bool),
+		baseDir:   opts.BaseDir,
+
+		oauth2:      opts.OAuth2,
+		tokens:      tokens,
+		tokenStatus: make(map[string]*TokenStatus),
+		promptFunc:  opts.Prompt,
 	}
+	e.client = e.httpClient(e.settings.forRequest(&parser.Request{}), false)
+	return e
 }
 
-func (e *Executor) Execute(req *parser.Request) *ExecutionResult {
-	startTime := time.Now()
+// Execute sends the request and returns its result. Results of named
+// requests are kept so later requests can reference them. Cancelling ctx
+// abandons the request, and the requests it depends on, with a "request
+// cancelled" error.
+func (e *Executor) Execute(ctx context.Context, req *parser.Request) *ExecutionResult {
+	e.running.Lock()
+	defer e.running.Unlock()
+	return e.executeNamed(ctx, req, nil)
+}
 
-	url := parser.SubstituteVariables(req.URL, e.variables)
+// executeNamed executes req, following its response on stream when that is
+// set, and keeps the result when req is named and was not cancelled.
+func (e *Executor) executeNamed(ctx context.Context, req *parser.Request, stream *ResponseStream) *ExecutionResult {
+	if req.Name != "" {
+		e.pending[req.Name] = true
+		defer delete(e.pending, req.Name)
+	}
 
-	var bodyReader io.Reader
-	if req.Body != "" {
-		body := parser.SubstituteVariables(req.Body, e.variables)
-		bodyReader = strings.NewReader(body)
+	result := e.execute(ctx, req, stream)
+	if saver, ok := e.jar.(interface{ SaveError() error }); ok && !req.NoCookieJar {
+		if err := saver.SaveError(); err != nil {
+			result.Logs = append(result.Logs, "warning: could not save cookies: "+err.Error())
+		}
+	}
+	if cancelled(ctx, req, result) {
+		return result
+	}
+
+	if req.Name != "" {
+		e.setResult(req.Name, result)
 	}
+	return result
+}
+
+// setResult records the result of the named request.
+func (e *Executor) setResult(name string, result *ExecutionResult) {
+	e.state.Lock()
+	defer e.state.Unlock()
+	e.results[name] = result
+}
+
+// cancelled reports whether result failed because ctx was cancelled, and
+// if so gives it a "request cancelled" error in place of the one the
+// cancellation caused.
+func cancelled(ctx context.Context, req *parser.Request, result *ExecutionResult) bool {
+	if result.Error == nil || !errors.Is(context.Cause(ctx), context.Canceled) {
+		return false
+	}
+	result.Error = NewExecutionError(req.ID, "request cancelled", context.Canceled)
+	result.Success = false
+	return true
+}
 
-	httpReq, err := http.NewRequest(req.Method, url, bodyReader)
+func (e *Executor) execute(ctx context.Context, req *parser.Request, stream *ResponseStream) *ExecutionResult {
+	startTime := time.Now()
+
+	p, failed := e.prepare(ctx, req)
+	if failed != nil {
+		return failed
+	}
+	resolved, settings := p.resolved, p.settings
+	if req.IsGRPC() {
+		return e.executeGRPC(ctx, req, p)
+	}
+	if req.IsWebSocket() {
+		return e.executeWebSocket(ctx, req, p)
+	}
+
+	body, err := e.buildPayload(ctx, resolved, p.vars)
 	if err != nil {
 		return &ExecutionResult{
-			Request: req,
-			Error:   NewExecutionError(req.ID, "failed to create request", err),
-			Success: false,
+			Request:  req,
+			Resolved: resolved,
+			Error:    NewExecutionError(req.ID, "failed to read request body", err),
+			Success:  false,
 		}
 	}
 
-	for _, h := range req.Headers {
-		substitutedValue := parser.SubstituteVariables(h.Value, e.variables)
-		httpReq.Header.Add(h.Key, substitutedValue)
+	httpClient := e.httpClient(settings, !req.NoCookieJar)
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var timer *time.Timer
+	if stream != nil {
+		// A stream may outlast the timeout, which then only bounds waiting
+		// for the response headers.
+		httpClient.Timeout = 0
+		timer = time.AfterFunc(settings.Timeout, func() { cancel(context.DeadlineExceeded) })
+		defer timer.Stop()
 	}
 
-	httpResp, err := e.client.Do(httpReq)
+	httpResp, err := e.send(ctx, httpClient, resolved, body, p.creds)
+	proxy := usedProxy(httpClient)
 	if err != nil {
+		err = cancelCause(ctx, err)
 		duration := time.Since(startTime)
 		return &ExecutionResult{
-			Request: req,
-			Error:   NewExecutionError(req.ID, "request failed", err),
-			Success: false,
+			Request:  req,
+			Resolved: resolved,
+			Error:    NewExecutionError(req.ID, failure(err, settings, "request failed"), err),
+			Success:  false,
 			Response: &Response{
 				Duration:    duration,
 				RequestTime: startTime,
+				Proxy:       proxy,
 			},
 		}
 	}
 	defer httpResp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(httpResp.Body)
+	contentType := httpResp.Header.Get("Content-Type")
+	var bodyBytes []byte
+	var events []StreamEvent
+	stopped := false
+	if IsStreaming(contentType) {
+		var add func(StreamEvent)
+		if stream != nil {
+			timer.Stop()
+			stream.started(&ExecutionResult{
+				Request:  req,
+				Resolved: resolved,
+				Response: &Response{
+					StatusCode:  httpResp.StatusCode,
+					Status:      httpResp.Status,
+					Headers:     httpResp.Header,
+					ContentType: contentType,
+					RequestTime: startTime,
+					Proxy:       proxy,
+				},
+				Tokens:  e.tokenStatuses(req),
+				Logs:    p.logs,
+				Success: true,
+			})
+			add = stream.add
+		}
+		bodyBytes, events, err = readStream(httpResp.Body, contentType, add)
+		if err != nil && errors.Is(context.Cause(ctx), errStreamStopped) {
+			err, stopped = nil, true
+		}
+		var netErr net.Error
+		if stream == nil && errors.As(err, &netErr) && netErr.Timeout() {
+			// Without a view to stop it, a stream ends at the timeout
+			// with the events received until then.
+			err, stopped = nil, true
+		}
+	} else {
+		bodyBytes, err = io.ReadAll(httpResp.Body)
+	}
 	if err != nil {
+		err = cancelCause(ctx, err)
 		duration := time.Since(startTime)
 		return &ExecutionResult{
-			Request: req,
-			Error:   NewExecutionError(req.ID, "failed to read response body", err),
-			Success: false,
+			Request:  req,
+			Resolved: resolved,
+			Error:    NewExecutionError(req.ID, failure(err, settings, "failed to read response body"), err),
+			Success:  false,
 			Response: &Response{
 				StatusCode:  httpResp.StatusCode,
 				Status:      httpResp.Status,
 				Headers:     httpResp.Header,
+				Body:        bodyBytes,
+				ContentType: contentType,
 				Duration:    duration,
 				RequestTime: startTime,
+				Size:        int64(len(bodyBytes)),
+				Proxy:       proxy,
 			},
+			Events: events,
 		}
 	}
 
 	duration := time.Since(startTime)
 
-	contentType := httpResp.Header.Get("Content-Type")
-
 	response := &Response{
 		StatusCode:  httpResp.StatusCode,
 		Status:      httpResp.Status,
@@ -95,13 +292,434 @@ func (e *Executor) Execute(req *parser.Request) *ExecutionResult {
 		Duration:    duration,
 		RequestTime: startTime,
 		Size:        int64(len(bodyBytes)),
+		Proxy:       proxy,
+	}
+
+	result := &ExecutionResult{
+		Request:  req,
+		Resolved: resolved,
+		Response: response,
+		Tokens:   e.tokenStatuses(req),
+		Logs:     p.logs,
+		Events:   events,
+		Stopped:  stopped,
+		Success:  true,
+	}
+	if req.IsGraphQL() {
+		result.GraphQLErrors = ParseGraphQLErrors(bodyBytes)
 	}
+	return e.check(ctx, req, result, p.requestVars)
+}
 
-	return &ExecutionResult{
+// prepared is a request ready to be sent: resolved, with its OAuth2 token
+// applied, and with the settings that apply to it.
+type prepared struct {
+	resolved    *ResolvedRequest
+	creds       *parser.Auth
+	settings    Settings
+	vars        map[string]string
+	requestVars map[string]string
+	logs        []string
+}
+
+// prepare runs the pre-request scripts of req and resolves it. When that
+// fails, it returns the result to report instead.
+func (e *Executor) prepare(ctx context.Context, req *parser.Request) (*prepared, *ExecutionResult) {
+	requestVars := make(map[string]string)
+	logs, err := e.runPreRequestScripts(req, requestVars)
+	if err != nil {
+		return nil, &ExecutionResult{
+			Request: req,
+			Logs:    logs,
+			Error:   NewExecutionError(req.ID, "pre-request script failed", err),
+			Success: false,
+		}
+	}
+
+	vars := e.scope(requestVars)
+	resolved, err := e.resolve(ctx, req, vars)
+	if err != nil {
+		return nil, &ExecutionResult{
+			Request: req,
+			Error:   NewExecutionError(req.ID, "failed to resolve variables", err),
+			Success: false,
+		}
+	}
+
+	if req.IsGraphQL() {
+		if err := e.prepareGraphQL(ctx, resolved, vars); err != nil {
+			return nil, &ExecutionResult{
+				Request:  req,
+				Resolved: resolved,
+				Error:    NewExecutionError(req.ID, "failed to build GraphQL request", err),
+				Success:  false,
+			}
+		}
+	}
+
+	if err := e.applyOAuth2Header(ctx, resolved, vars); err != nil {
+		return nil, &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Tokens:   e.tokenStatuses(req),
+			Error:    NewExecutionError(req.ID, "failed to get OAuth2 token", err),
+			Success:  false,
+		}
+	}
+
+	creds, err := prepareAuth(resolved)
+	if err != nil {
+		return nil, &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Error:    NewExecutionError(req.ID, "invalid Authorization header", err),
+			Success:  false,
+		}
+	}
+
+	settings := e.settings.forRequest(req)
+	settings.Proxy = parser.SubstituteVariables(settings.Proxy, vars)
+	return &prepared{
+		resolved:    resolved,
+		creds:       creds,
+		settings:    settings,
+		vars:        vars,
+		requestVars: requestVars,
+		logs:        logs,
+	}, nil
+}
+
+// executeGRPC calls a GRPC request once it is resolved.
+func (e *Executor) executeGRPC(ctx context.Context, req *parser.Request, p *prepared) *ExecutionResult {
+	resolved, settings := p.resolved, p.settings
+	if p.creds != nil {
+		return &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Error:    NewExecutionError(req.ID, "invalid Authorization header", fmt.Errorf("%s authentication is not supported for gRPC calls", p.creds.Scheme)),
+			Success:  false,
+		}
+	}
+
+	response, err := e.invokeGRPC(ctx, req, resolved, settings)
+	if err != nil {
+		return &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Tokens:   e.tokenStatuses(req),
+			Logs:     p.logs,
+			Error:    NewExecutionError(req.ID, failure(err, settings, "gRPC call failed"), err),
+			Success:  false,
+		}
+	}
+
+	result := &ExecutionResult{
 		Request:  req,
+		Resolved: resolved,
 		Response: response,
+		Tokens:   e.tokenStatuses(req),
+		Logs:     p.logs,
 		Success:  true,
 	}
+	return e.check(ctx, req, result, p.requestVars)
+}
+
+// check runs the response handlers of req and then evaluates its
+// assertions, with the variables the handlers set.
+func (e *Executor) check(ctx context.Context, req *parser.Request, result *ExecutionResult, requestVars map[string]string) *ExecutionResult {
+	if len(req.ResponseHandlers) > 0 {
+		e.runResponseHandlers(req, result, requestVars)
+	}
+
+	if len(req.Assertions) > 0 {
+		vars := e.scope(requestVars)
+		substitute := func(text string) (string, error) { return e.substitute(ctx, text, vars) }
+		result.Assertions = append(EvaluateAssertions(req.Assertions, result.Response, substitute), result.Assertions...)
+	}
+
+	return result
+}
+
+// cancelCause returns the cause of the cancellation of ctx in place of err
+// when ctx is why the request failed, so that a timeout reads as one.
+func cancelCause(ctx context.Context, err error) error {
+	if errors.Is(err, context.Canceled) {
+		if cause := context.Cause(ctx); cause != nil {
+			return cause
+		}
+	}
+	return err
+}
+
+// send builds the HTTP request from resolved and body and sends it with
+// httpClient, authenticating with creds when set. A Digest challenge is
+// answered by sending the request again. Headers added for authentication
+// are recorded in resolved.
+func (e *Executor) send(ctx context.Context, httpClient *http.Client, resolved *ResolvedRequest, body *payload, creds *parser.Auth) (*http.Response, error) {
+	httpReq, err := newHTTPRequest(ctx, resolved, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Signing schemes hash the whole body, so it is read into memory.
+	var content []byte
+	if creds != nil && body != nil && (creds.Scheme == parser.AuthAWS || creds.Scheme == parser.AuthDigest) {
+		if content, err = body.bytes(); err != nil {
+			return nil, err
+		}
+	}
+
+	if creds != nil && creds.Scheme == parser.AuthAWS {
+		if err := signAWS(httpReq, string(content), creds, time.Now()); err != nil {
+			return nil, err
+		}
+		for _, key := range []string{"X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token", "Authorization"} {
+			if value := httpReq.Header.Get(key); value != "" {
+				setAuthorization(resolved, key, value)
+			}
+		}
+	}
+
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil || creds == nil || creds.Scheme != parser.AuthDigest || httpResp.StatusCode != http.StatusUnauthorized {
+		return httpResp, err
+	}
+
+	challenge := digestChallenge(httpResp)
+	if challenge == nil {
+		return httpResp, nil
+	}
+	io.Copy(io.Discard, httpResp.Body)
+	httpResp.Body.Close()
+
+	authorization, err := digestAuthorization(challenge, creds, httpReq.Method, httpReq.URL.RequestURI(), string(content))
+	if err != nil {
+		return nil, err
+	}
+	setAuthorization(resolved, "Authorization", authorization)
+
+	retry, err := newHTTPRequest(ctx, resolved, body)
+	if err != nil {
+		return nil, err
+	}
+	return httpClient.Do(retry)
+}
+
+func newHTTPRequest(ctx context.Context, resolved *ResolvedRequest, body *payload) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, resolved.Method, resolved.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && body.length > 0 {
+		httpReq.Body = body.reader()
+		httpReq.ContentLength = body.length
+		httpReq.GetBody = func() (io.ReadCloser, error) { return body.reader(), nil }
+	}
+	for _, h := range resolved.Headers {
+		httpReq.Header.Add(h.Key, h.Value)
+	}
+	return httpReq, nil
+}
+
+// resolve substitutes variables and response references in the URL, headers
+// and body of req.
+func (e *Executor) resolve(ctx context.Context, req *parser.Request, vars map[string]string) (*ResolvedRequest, error) {
+	url, err := e.substitute(ctx, req.URL, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := &ResolvedRequest{
+		Method: req.Method,
+		URL:    url,
+	}
+
+	for _, h := range req.Headers {
+		value, err := e.substitute(ctx, h.Value, vars)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Headers = append(resolved.Headers, parser.Header{Key: h.Key, Value: value})
+	}
+
+	if req.Body != "" {
+		body, err := e.substitute(ctx, req.Body, vars)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Body = body
+	}
+
+	return resolved, nil
+}
+
+// scope returns the variables visible to a request: file and environment
+// variables overlaid with script globals and request-scoped variables.
+func (e *Executor) scope(requestVars map[string]string) map[string]string {
+	if len(e.globals) == 0 && len(requestVars) == 0 {
+		return e.variables
+	}
+
+	vars := make(map[string]string, len(e.variables)+len(e.globals)+len(requestVars))
+	for k, v := range e.variables {
+		vars[k] = v
+	}
+	for k, v := range e.globals {
+		vars[k] = v
+	}
+	for k, v := range requestVars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// maxSubstitutionPasses bounds nested variable expansion, e.g. a file
+// variable whose value refers to a script global.
+const maxSubstitutionPasses = 5
+
+func (e *Executor) substitute(ctx context.Context, text string, vars map[string]string) (string, error) {
+	for i := 0; i < maxSubstitutionPasses && strings.Contains(text, "{{"); i++ {
+		next := parser.SubstituteVariables(text, vars)
+		if next == text {
+			break
+		}
+		text = next
+	}
+
+	var firstErr error
+	text = oauth2Regex.ReplaceAllStringFunc(text, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		token, err := e.oauth2Token(ctx, oauth2Regex.FindStringSubmatch(match)[1], vars)
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		return token.AccessToken
+	})
+	if firstErr != nil {
+		return text, firstErr
+	}
+
+	text = referenceRegex.ReplaceAllStringFunc(text, func(match string) string {
+		if firstErr != nil {
+			return match
+		}
+		m := referenceRegex.FindStringSubmatch(match)
+		value, ok, err := e.resolveReference(ctx, m[1], m[2], m[3], m[4])
+		if err != nil {
+			firstErr = err
+			return match
+		}
+		if !ok {
+			return match
+		}
+		return value
+	})
+
+	return text, firstErr
+}
+
+// Substitute replaces the variables in text as the next request would see
+// them: file and environment variables, script globals and references to
+// named requests that have already run. Nothing is executed, so references
+// to other requests and OAuth2 tokens are left as they are. It does not wait
+// for a request being executed.
+func (e *Executor) Substitute(text string) string {
+	e.state.Lock()
+	defer e.state.Unlock()
+
+	vars := e.scope(nil)
+	for i := 0; i < maxSubstitutionPasses && strings.Contains(text, "{{"); i++ {
+		next := parser.SubstituteVariables(text, vars)
+		if next == text {
+			break
+		}
+		text = next
+	}
+	return referenceRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := referenceRegex.FindStringSubmatch(match)
+		if _, ok := e.results[m[1]]; !ok {
+			return match
+		}
+		value, ok, err := e.resolveReference(context.Background(), m[1], m[2], m[3], m[4])
+		if err != nil || !ok {
+			return match
+		}
+		return value
+	})
+}
+
+// resolveReference evaluates {{name.(request|response).(body|headers).path}},
+// executing the named request first if it has not run yet. ok is false when
+// no request has that name, leaving the expression untouched.
+func (e *Executor) resolveReference(ctx context.Context, name, source, part, path string) (value string, ok bool, err error) {
+	result, ok, err := e.namedResult(ctx, name)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+
+	if source == "request" {
+		if result.Resolved == nil {
+			return "", true, fmt.Errorf("request %q was not sent", name)
+		}
+		if part == "headers" {
+			for _, h := range result.Resolved.Headers {
+				if strings.EqualFold(h.Key, path) {
+					return h.Value, true, nil
+				}
+			}
+			return "", true, fmt.Errorf("request %q has no header %q", name, path)
+		}
+		value, found, err := QueryBody([]byte(result.Resolved.Body), path)
+		if err != nil {
+			return "", true, fmt.Errorf("%s.request.body.%s: %w", name, path, err)
+		}
+		if !found {
+			return "", true, fmt.Errorf("%s.request.body.%s matched nothing", name, path)
+		}
+		return value, true, nil
+	}
+
+	if result.Response == nil || result.Error != nil {
+		return "", true, fmt.Errorf("request %q failed: %v", name, result.Error)
+	}
+	if part == "headers" {
+		values := result.Response.Headers.Values(path)
+		if len(values) == 0 {
+			return "", true, fmt.Errorf("response of %q has no header %q", name, path)
+		}
+		return strings.Join(values, ", "), true, nil
+	}
+	value, found, err := QueryBody(result.Response.Body, path)
+	if err != nil {
+		return "", true, fmt.Errorf("%s.response.body.%s: %w", name, path, err)
+	}
+	if !found {
+		return "", true, fmt.Errorf("%s.response.body.%s matched nothing", name, path)
+	}
+	return value, true, nil
+}
+
+// namedResult returns the latest result of the request with the given name,
+// executing it if needed.
+func (e *Executor) namedResult(ctx context.Context, name string) (*ExecutionResult, bool, error) {
+	if result, ok := e.results[name]; ok {
+		return result, true, nil
+	}
+
+	for i := range e.requests {
+		if e.requests[i].Name != name {
+			continue
+		}
+		if e.pending[name] {
+			return nil, true, fmt.Errorf("circular reference to request %q", name)
+		}
+		return e.executeNamed(ctx, &e.requests[i], nil), true, nil
+	}
+
+	return nil, false, nil
 }
 
 func FormatSize(bytes int64) string {
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+func TestSubstituteDoesNotWaitForRunning(t *testing.T) {
+	e := NewExecutor(map[string]string{"host": "example.com"}, Options{})
+	e.setGlobals(map[string]string{"token": "t1"})
+	e.setResult("login", &ExecutionResult{
+		Request:  &parser.Request{Name: "login"},
+		Response: &Response{Body: []byte(`{"id": 9007199254740993}`)},
+	})
+
+	// A cancelled request still finishing holds running.
+	e.running.Lock()
+	defer e.running.Unlock()
+
+	done := make(chan string)
+	go func() {
+		done <- e.Substitute("https://{{host}}/users/{{login.response.body.$.id}}?t={{token}}&o={{other.response.body.$.id}}")
+	}()
+	select {
+	case got := <-done:
+		want := "https://example.com/users/9007199254740993?t=t1&o={{other.response.body.$.id}}"
+		if got != want {
+			t.Errorf("Substitute() = %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Substitute waited for the running request")
+	}
+}
@@ -0,0 +1,248 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"httpyum/internal/parser"
+)
+
+// GraphQLError is an entry of the "errors" array of a GraphQL response.
+type GraphQLError struct {
+	Message string
+	// Path is the response field the error belongs to, such as
+	// "user.posts.0.title".
+	Path string
+	// Locations are the "line:column" positions in the query.
+	Locations []string
+	// Code is extensions.code, set by many servers.
+	Code string
+}
+
+func (e GraphQLError) String() string {
+	s := e.Message
+	if e.Code != "" {
+		s = e.Code + ": " + s
+	}
+	if e.Path != "" {
+		s += " (at " + e.Path + ")"
+	}
+	return s
+}
+
+// ParseGraphQLErrors returns the errors of a GraphQL response body, or nil
+// when it has none or is not JSON.
+func ParseGraphQLErrors(body []byte) []GraphQLError {
+	var doc struct {
+		Errors []struct {
+			Message   string `json:"message"`
+			Path      []any  `json:"path"`
+			Locations []struct {
+				Line   int `json:"line"`
+				Column int `json:"column"`
+			} `json:"locations"`
+			Extensions struct {
+				Code any `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &doc) != nil {
+		return nil
+	}
+
+	var errs []GraphQLError
+	for _, raw := range doc.Errors {
+		e := GraphQLError{Message: raw.Message}
+		path := make([]string, len(raw.Path))
+		for i, p := range raw.Path {
+			path[i] = fmt.Sprint(p)
+		}
+		e.Path = strings.Join(path, ".")
+		for _, l := range raw.Locations {
+			e.Locations = append(e.Locations, fmt.Sprintf("%d:%d", l.Line, l.Column))
+		}
+		if raw.Extensions.Code != nil {
+			e.Code = fmt.Sprint(raw.Extensions.Code)
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// prepareGraphQL turns a resolved GraphQL request into the HTTP request it
+// is sent as, reading the files its query is included from.
+func (e *Executor) prepareGraphQL(ctx context.Context, resolved *ResolvedRequest, vars map[string]string) error {
+	body := resolved.Body
+	if body != "" {
+		p, err := e.buildPayload(ctx, &ResolvedRequest{Body: body}, vars)
+		if err != nil {
+			return err
+		}
+		data, err := p.bytes()
+		if err != nil {
+			return err
+		}
+		body = string(data)
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("the request has no query")
+	}
+
+	req, err := parser.GraphQLHTTP(parser.Request{Method: resolved.Method, URL: resolved.URL, Headers: resolved.Headers, Body: body})
+	if err != nil {
+		return err
+	}
+	resolved.Method, resolved.URL, resolved.Headers, resolved.Body = req.Method, req.URL, req.Headers, req.Body
+	return nil
+}
+
+// introspectionQuery asks for the root operation types and their fields,
+// with enough of each type reference to print field signatures.
+const introspectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      name
+      fields(includeDeprecated: false) {
+        name
+        description
+        args { name type { ...TypeRef } }
+        type { ...TypeRef }
+      }
+    }
+  }
+}
+
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// GraphQLSchema lists the operations a GraphQL server offers.
+type GraphQLSchema struct {
+	Queries       []GraphQLField
+	Mutations     []GraphQLField
+	Subscriptions []GraphQLField
+}
+
+// GraphQLField is a field of a root operation type.
+type GraphQLField struct {
+	Name        string
+	Description string
+	// Signature is the field with its arguments and type, such as
+	// "user(id: ID!): User".
+	Signature string
+}
+
+type typeRef struct {
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	OfType *typeRef `json:"ofType"`
+}
+
+func (t *typeRef) String() string {
+	if t == nil {
+		return "?"
+	}
+	switch t.Kind {
+	case "NON_NULL":
+		return t.OfType.String() + "!"
+	case "LIST":
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// Introspect runs an introspection query against the endpoint of req, with
+// its headers and authentication, and returns the operations the server
+// offers.
+func (e *Executor) Introspect(ctx context.Context, req *parser.Request) (*GraphQLSchema, error) {
+	introspection := *req
+	introspection.Name = ""
+	introspection.Method = "GRAPHQL"
+	introspection.Body = introspectionQuery
+	introspection.Assertions = nil
+	introspection.ResponseHandlers = nil
+	e.running.Lock()
+	result := e.execute(ctx, &introspection, nil)
+	e.running.Unlock()
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	resp := result.Response
+	if errs := ParseGraphQLErrors(resp.Body); len(errs) > 0 {
+		return nil, fmt.Errorf("introspection failed: %s", errs[0])
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("introspection failed: %s", resp.Status)
+	}
+
+	var doc struct {
+		Data struct {
+			Schema *struct {
+				QueryType        *struct{ Name string } `json:"queryType"`
+				MutationType     *struct{ Name string } `json:"mutationType"`
+				SubscriptionType *struct{ Name string } `json:"subscriptionType"`
+				Types            []struct {
+					Name   string `json:"name"`
+					Fields []struct {
+						Name        string `json:"name"`
+						Description string `json:"description"`
+						Args        []struct {
+							Name string   `json:"name"`
+							Type *typeRef `json:"type"`
+						} `json:"args"`
+						Type *typeRef `json:"type"`
+					} `json:"fields"`
+				} `json:"types"`
+			} `json:"__schema"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp.Body, &doc); err != nil {
+		return nil, fmt.Errorf("introspection failed: the response is not JSON: %w", err)
+	}
+	schema := doc.Data.Schema
+	if schema == nil {
+		return nil, fmt.Errorf("introspection failed: the response has no __schema")
+	}
+
+	fields := func(root *struct{ Name string }) []GraphQLField {
+		if root == nil {
+			return nil
+		}
+		var out []GraphQLField
+		for _, t := range schema.Types {
+			if t.Name != root.Name {
+				continue
+			}
+			for _, f := range t.Fields {
+				args := make([]string, len(f.Args))
+				for i, a := range f.Args {
+					args[i] = a.Name + ": " + a.Type.String()
+				}
+				signature := f.Name
+				if len(args) > 0 {
+					signature += "(" + strings.Join(args, ", ") + ")"
+				}
+				out = append(out, GraphQLField{
+					Name:        f.Name,
+					Description: f.Description,
+					Signature:   signature + ": " + f.Type.String(),
+				})
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+		return out
+	}
+
+	return &GraphQLSchema{
+		Queries:       fields(schema.QueryType),
+		Mutations:     fields(schema.MutationType),
+		Subscriptions: fields(schema.SubscriptionType),
+	}, nil
+}
@@ -0,0 +1,688 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"httpyum/internal/parser"
+
+	"github.com/bufbuild/protocompile"
+	"golang.org/x/net/proxy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// grpcTarget is the request line of a GRPC request:
+// [grpc:// | grpcs://]host:port/package.Service/Method.
+type grpcTarget struct {
+	address string
+	service string
+	method  string
+	tls     bool
+}
+
+// parseGRPCTarget parses the URL of a GRPC request. grpcs:// and https://
+// connect with TLS, and the port defaults to 443 with TLS and 80 without.
+func parseGRPCTarget(raw string) (*grpcTarget, error) {
+	t := &grpcTarget{}
+	rest := strings.TrimSpace(raw)
+	if scheme, after, ok := strings.Cut(rest, "://"); ok {
+		switch strings.ToLower(scheme) {
+		case "grpcs", "https":
+			t.tls = true
+		case "grpc", "http":
+		default:
+			return nil, fmt.Errorf("unsupported scheme %q (use grpc:// or grpcs://)", scheme)
+		}
+		rest = after
+	}
+
+	address, path, _ := strings.Cut(rest, "/")
+	service, method, ok := strings.Cut(path, "/")
+	if address == "" || !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return nil, fmt.Errorf("expected host:port/package.Service/Method, got %q", raw)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		port := "80"
+		if t.tls {
+			port = "443"
+		}
+		address = net.JoinHostPort(strings.Trim(address, "[]"), port)
+	}
+	t.address, t.service, t.method = address, service, method
+	return t, nil
+}
+
+// invokeGRPC calls the method of a GRPC request. The JSON body is converted
+// to the request message, or to a stream of them for client streaming
+// methods, using the request's .proto files or server reflection. A call
+// that ends with a non-OK status still returns a Response; its StatusCode
+// is the HTTP equivalent of the gRPC code.
+func (e *Executor) invokeGRPC(ctx context.Context, req *parser.Request, resolved *ResolvedRequest, settings Settings) (*Response, error) {
+	target, err := parseGRPCTarget(resolved.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settings.Timeout)
+	defer cancel()
+
+	conn, dialer, err := e.dialGRPC(target, settings)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	files, err := e.grpcFiles(ctx, conn, req, target)
+	if err != nil {
+		return nil, err
+	}
+	md, err := findMethod(files, target)
+	if err != nil {
+		return nil, err
+	}
+	types := dynamicpb.NewTypes(files)
+
+	messages, err := grpcMessages(resolved.Body, md, types)
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing, err := grpcMetadata(resolved.Headers)
+	if err != nil {
+		return nil, err
+	}
+	ctx = metadata.NewOutgoingContext(ctx, outgoing)
+
+	startTime := time.Now()
+	desc := &grpc.StreamDesc{
+		StreamName:    string(md.Name()),
+		ServerStreams: md.IsStreamingServer(),
+		ClientStreams: md.IsStreamingClient(),
+	}
+	fullMethod := "/" + target.service + "/" + target.method
+
+	stream, err := conn.NewStream(ctx, desc, fullMethod)
+	if err != nil {
+		// The call never started: the server could not be reached.
+		return nil, callError(err)
+	}
+	var replies []proto.Message
+	err = sendAndReceive(stream, messages, md, &replies)
+
+	response := &Response{
+		RequestTime: startTime,
+		Duration:    time.Since(startTime),
+		ContentType: "application/json",
+		Headers:     http.Header{},
+		Trailers:    http.Header{},
+		Proxy:       dialer.usedProxy(),
+	}
+	header, _ := stream.Header()
+	copyMetadata(response.Headers, header)
+	copyMetadata(response.Trailers, stream.Trailer())
+
+	st := status.Convert(err)
+	response.StatusCode = grpcHTTPStatus(st.Code())
+	response.Status = fmt.Sprintf("%d %s", st.Code(), grpcCodeName(st.Code()))
+	if st.Message() != "" {
+		response.Status += ": " + st.Message()
+	}
+	response.Trailers.Set("Grpc-Status", fmt.Sprint(int(st.Code())))
+	if st.Message() != "" {
+		response.Trailers.Set("Grpc-Message", st.Message())
+	}
+
+	marshal := protojson.MarshalOptions{Resolver: types}
+	switch {
+	case len(replies) == 0 && st.Code() != codes.OK:
+		// The status, with its details, is the most useful body of a
+		// failed call.
+		if body, err := marshal.Marshal(st.Proto()); err == nil {
+			response.Body = body
+		}
+	case md.IsStreamingServer():
+		parts := make([]string, len(replies))
+		for i, reply := range replies {
+			data, err := marshal.Marshal(reply)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = string(data)
+		}
+		response.Body = []byte("[" + strings.Join(parts, ",") + "]")
+	case len(replies) > 0:
+		if response.Body, err = marshal.Marshal(replies[0]); err != nil {
+			return nil, err
+		}
+	}
+	response.Size = int64(len(response.Body))
+	return response, nil
+}
+
+// sendAndReceive sends messages on stream and collects the replies until
+// the server ends the call.
+func sendAndReceive(stream grpc.ClientStream, messages []proto.Message, md protoreflect.MethodDescriptor, replies *[]proto.Message) error {
+	for _, msg := range messages {
+		if err := stream.SendMsg(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				// The server ended the call; RecvMsg returns its status.
+				break
+			}
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		reply := dynamicpb.NewMessage(md.Output())
+		if err := stream.RecvMsg(reply); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		*replies = append(*replies, reply)
+	}
+}
+
+// dialGRPC opens a connection to the target, with the TLS configuration
+// that matches its host when it uses TLS, through the proxy the settings
+// choose for it. The connection is established on the first call.
+func (e *Executor) dialGRPC(target *grpcTarget, settings Settings) (*grpc.ClientConn, *grpcDialer, error) {
+	creds := insecure.NewCredentials()
+	if target.tls {
+		host, port, _ := net.SplitHostPort(target.address)
+		config, err := e.tlsClientConfig(matchTLS(e.tls, host, port), settings.Insecure)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(config)
+	}
+
+	dialer := &grpcDialer{proxy: proxyFunc(settings), scheme: "http"}
+	if target.tls {
+		dialer.scheme = "https"
+	}
+	// The dialer picks the proxy, so the target is passed to it unresolved
+	// for NO_PROXY to match its host name.
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithNoProxy(),
+		grpc.WithContextDialer(dialer.dial),
+	}
+	if settings.ConnectionTimeout > 0 {
+		opts = append(opts, grpc.WithConnectParams(grpc.ConnectParams{MinConnectTimeout: settings.ConnectionTimeout}))
+	}
+	conn, err := grpc.NewClient("passthrough:///"+target.address, opts...)
+	return conn, dialer, err
+}
+
+// grpcDialer connects a gRPC call to its server through the proxy chosen
+// as for HTTP requests: --proxy, "# @proxy", or HTTP_PROXY and HTTPS_PROXY,
+// bypassed for the hosts in --no-proxy or NO_PROXY. It records the proxy
+// it went through.
+type grpcDialer struct {
+	proxy  func(*http.Request) (*url.URL, error)
+	scheme string
+
+	mu   sync.Mutex
+	used string
+}
+
+func (d *grpcDialer) dial(ctx context.Context, address string) (net.Conn, error) {
+	var proxyURL *url.URL
+	if d.proxy != nil {
+		u, err := d.proxy(&http.Request{URL: &url.URL{Scheme: d.scheme, Host: address}})
+		if err != nil {
+			return nil, err
+		}
+		proxyURL = u
+	}
+
+	d.mu.Lock()
+	d.used = ""
+	if proxyURL != nil {
+		d.used = proxyURL.Redacted()
+	}
+	d.mu.Unlock()
+
+	if proxyURL == nil {
+		var direct net.Dialer
+		return direct.DialContext(ctx, "tcp", address)
+	}
+	if proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h" {
+		socks, err := proxy.FromURL(proxyURL, &net.Dialer{})
+		if err != nil {
+			return nil, err
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	}
+	return dialConnect(ctx, proxyURL, address)
+}
+
+// usedProxy returns the proxy the connection went through, without its
+// password.
+func (d *grpcDialer) usedProxy() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.used
+}
+
+// dialConnect opens a tunnel to address through an HTTP or HTTPS proxy
+// with a CONNECT request. Failures are reported with the "proxyconnect"
+// operation, like those of HTTP requests.
+func dialConnect(ctx context.Context, proxyURL *url.URL, address string) (net.Conn, error) {
+	fail := func(err error) error {
+		return &net.OpError{Op: "proxyconnect", Net: "tcp", Err: err}
+	}
+
+	proxyAddress := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fail(err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fail(err)
+		}
+		conn = tlsConn
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		connect.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, fail(err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, connect)
+	if err != nil {
+		conn.Close()
+		return nil, fail(err)
+	}
+	// A successful CONNECT response has no body: what follows is the
+	// tunnel.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fail(fmt.Errorf("proxy refused the tunnel: %s", resp.Status))
+	}
+	conn.SetDeadline(time.Time{})
+
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were read ahead into
+// reader.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// grpcFiles returns the descriptors of the request's service: compiled from
+// its .proto files, or fetched with server reflection and cached per server.
+func (e *Executor) grpcFiles(ctx context.Context, conn *grpc.ClientConn, req *parser.Request, target *grpcTarget) (*protoregistry.Files, error) {
+	if len(req.Protos) > 0 {
+		return e.compileProtos(ctx, req.Protos)
+	}
+
+	key := target.address + "/" + target.service
+	e.mu.Lock()
+	files, ok := e.reflected[key]
+	e.mu.Unlock()
+	if ok {
+		return files, nil
+	}
+
+	files, err := reflectFiles(ctx, conn, target.service)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.reflected[key] = files
+	e.mu.Unlock()
+	return files, nil
+}
+
+// compileProtos parses .proto files, resolving imports against the
+// directory of each file and the base directory. The well-known types are
+// always available.
+func (e *Executor) compileProtos(ctx context.Context, protos []string) (*protoregistry.Files, error) {
+	importPaths := []string{}
+	names := make([]string, len(protos))
+	for i, p := range protos {
+		p = parser.SubstituteVariables(p, e.variables)
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(e.baseDir, p)
+		}
+		importPaths = append(importPaths, filepath.Dir(p))
+		names[i] = filepath.Base(p)
+	}
+	importPaths = append(importPaths, e.baseDir)
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: importPaths}),
+	}
+	compiled, err := compiler.Compile(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("compiling .proto files: %w", err)
+	}
+
+	files := new(protoregistry.Files)
+	for _, f := range compiled {
+		if err := registerFile(files, f); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// registerFile adds f and its imports to files.
+func registerFile(files *protoregistry.Files, f protoreflect.FileDescriptor) error {
+	if _, err := files.FindFileByPath(f.Path()); err == nil {
+		return nil
+	}
+	imports := f.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		if err := registerFile(files, imports.Get(i).FileDescriptor); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(f)
+}
+
+// reflectionMethods are the server reflection methods tried in order; the
+// v1alpha messages are identical to the v1 ones on the wire.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// reflectFiles asks the server for the file defining service and the files
+// it imports.
+func reflectFiles(ctx context.Context, conn *grpc.ClientConn, service string) (*protoregistry.Files, error) {
+	var err error
+	for _, method := range reflectionMethods {
+		var files *protoregistry.Files
+		files, err = reflectFilesWith(ctx, conn, method, service)
+		switch status.Code(err) {
+		case codes.OK:
+			return files, nil
+		case codes.Unimplemented:
+			continue
+		case codes.NotFound:
+			return nil, fmt.Errorf("server reflection: %s; add # @proto with the service's .proto file", status.Convert(err).Message())
+		}
+		return nil, fmt.Errorf("server reflection: %w", callError(err))
+	}
+	return nil, fmt.Errorf("the server does not support reflection; add # @proto with the service's .proto file")
+}
+
+func reflectFilesWith(ctx context.Context, conn *grpc.ClientConn, method, service string) (*protoregistry.Files, error) {
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, method)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	ask := func(req *reflectionpb.ServerReflectionRequest) ([][]byte, error) {
+		if err := stream.SendMsg(req); err != nil {
+			return nil, err
+		}
+		resp := new(reflectionpb.ServerReflectionResponse)
+		if err := stream.RecvMsg(resp); err != nil {
+			return nil, err
+		}
+		if e := resp.GetErrorResponse(); e != nil {
+			return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+		}
+		return resp.GetFileDescriptorResponse().GetFileDescriptorProto(), nil
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := map[string]bool{}
+	var add func(raw [][]byte) error
+	add = func(raw [][]byte) error {
+		var missing []string
+		for _, data := range raw {
+			fd := new(descriptorpb.FileDescriptorProto)
+			if err := proto.Unmarshal(data, fd); err != nil {
+				return err
+			}
+			if seen[fd.GetName()] {
+				continue
+			}
+			seen[fd.GetName()] = true
+			set.File = append(set.File, fd)
+			missing = append(missing, fd.GetDependency()...)
+		}
+		for _, name := range missing {
+			if seen[name] {
+				continue
+			}
+			raw, err := ask(&reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: name},
+			})
+			if err != nil {
+				// Servers often leave out the well-known types.
+				if fd, gerr := protoregistry.GlobalFiles.FindFileByPath(name); gerr == nil {
+					seen[name] = true
+					set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+					continue
+				}
+				return fmt.Errorf("fetching %s: %w", name, err)
+			}
+			if err := add(raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	raw, err := ask(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := add(raw); err != nil {
+		return nil, err
+	}
+	return protodesc.NewFiles(set)
+}
+
+// callError turns the status of a call that failed before reaching the
+// server into a plain error, which reports a timeout as one.
+func callError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	if st.Code() == codes.DeadlineExceeded {
+		if st.Message() == context.DeadlineExceeded.Error() {
+			return context.DeadlineExceeded
+		}
+		return fmt.Errorf("%s: %w", st.Message(), context.DeadlineExceeded)
+	}
+	return errors.New(st.Message())
+}
+
+// findMethod looks up the method of target in files.
+func findMethod(files *protoregistry.Files, target *grpcTarget) (protoreflect.MethodDescriptor, error) {
+	d, err := files.FindDescriptorByName(protoreflect.FullName(target.service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found", target.service)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", target.service)
+	}
+	md := sd.Methods().ByName(protoreflect.Name(target.method))
+	if md == nil {
+		methods := make([]string, sd.Methods().Len())
+		for i := range methods {
+			methods[i] = string(sd.Methods().Get(i).Name())
+		}
+		return nil, fmt.Errorf("service %s has no method %s (available: %s)", target.service, target.method, strings.Join(methods, ", "))
+	}
+	return md, nil
+}
+
+// grpcMessages converts a body of JSON objects to request messages. An
+// empty body is an empty message; only client streaming methods take more
+// than one.
+func grpcMessages(body string, md protoreflect.MethodDescriptor, types *dynamicpb.Types) ([]proto.Message, error) {
+	unmarshal := protojson.UnmarshalOptions{Resolver: types}
+	var messages []proto.Message
+	dec := json.NewDecoder(strings.NewReader(body))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("request body: %w", err)
+		}
+		msg := dynamicpb.NewMessage(md.Input())
+		if err := unmarshal.Unmarshal(raw, msg); err != nil {
+			return nil, fmt.Errorf("request body is not a valid %s: %w", md.Input().FullName(), err)
+		}
+		messages = append(messages, msg)
+	}
+
+	switch {
+	case len(messages) == 0:
+		messages = append(messages, dynamicpb.NewMessage(md.Input()))
+	case len(messages) > 1 && !md.IsStreamingClient():
+		return nil, fmt.Errorf("%s takes one message, the body has %d", md.Name(), len(messages))
+	}
+	return messages, nil
+}
+
+// grpcMetadata converts request headers to metadata. Values of "-bin" keys
+// are base64-decoded.
+func grpcMetadata(headers []parser.Header) (metadata.MD, error) {
+	md := metadata.MD{}
+	for _, h := range headers {
+		key := strings.ToLower(h.Key)
+		value := h.Value
+		if strings.HasSuffix(key, "-bin") {
+			data, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				if data, err = base64.RawStdEncoding.DecodeString(value); err != nil {
+					return nil, fmt.Errorf("header %s: expected a base64 value", h.Key)
+				}
+			}
+			value = string(data)
+		}
+		md.Append(key, value)
+	}
+	return md, nil
+}
+
+func copyMetadata(dst http.Header, md metadata.MD) {
+	for key, values := range md {
+		for _, v := range values {
+			dst.Add(key, v)
+		}
+	}
+}
+
+// grpcCodeName returns the canonical name of a status code, such as
+// NOT_FOUND.
+func grpcCodeName(code codes.Code) string {
+	name := code.String()
+	var sb strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(rune(name[i-1])) {
+			sb.WriteByte('_')
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// grpcHTTPStatus maps a status code to the HTTP status with the same
+// meaning, as gRPC gateways do, so status checks and assertions work the
+// same for gRPC calls.
+func grpcHTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
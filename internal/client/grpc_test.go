@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// connectProxy is an HTTP proxy that only tunnels, recording the targets
+// it was asked for.
+func connectProxy(t *testing.T) (*httptest.Server, chan string) {
+	targets := make(chan string, 4)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targets <- r.Host
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") == "Basic dTpiYWQ=" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go io.Copy(upstream, conn)
+		io.Copy(conn, upstream)
+		conn.Close()
+		upstream.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, targets
+}
+
+// echoServer accepts connections and writes back what it reads.
+func echoServer(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func TestGRPCDialerProxy(t *testing.T) {
+	proxy, targets := connectProxy(t)
+	target := echoServer(t)
+	_, port, _ := net.SplitHostPort(target)
+
+	tests := []struct {
+		name      string
+		settings  Settings
+		address   string
+		wantProxy string
+		wantErr   string
+	}{
+		{
+			name:      "proxy",
+			settings:  Settings{Proxy: proxy.URL},
+			address:   target,
+			wantProxy: proxy.URL,
+		},
+		{
+			name:     "bypassed",
+			settings: Settings{Proxy: proxy.URL, NoProxy: "example.com"},
+			address:  "localhost:" + port,
+		},
+		{
+			name:     "direct",
+			settings: Settings{Proxy: proxy.URL, Direct: true},
+			address:  target,
+		},
+		{
+			name:     "refused",
+			settings: Settings{Proxy: strings.Replace(proxy.URL, "http://", "http://u:bad@", 1)},
+			address:  target,
+			wantErr:  "proxyconnect tcp: proxy refused the tunnel: 407",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for len(targets) > 0 {
+				<-targets
+			}
+			dialer := &grpcDialer{proxy: proxyFunc(tt.settings), scheme: "http"}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			conn, err := dialer.dial(ctx, tt.address)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("dial() error = %v, want %q", err, tt.wantErr)
+				}
+				if got := failure(err, tt.settings, "request failed"); got != "could not connect through the proxy" {
+					t.Errorf("failure() = %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+				t.Fatalf("read %q, %v through the connection", buf, err)
+			}
+
+			if got := dialer.usedProxy(); got != tt.wantProxy {
+				t.Errorf("usedProxy() = %q, want %q", got, tt.wantProxy)
+			}
+			proxied := len(targets) > 0
+			if proxied != (tt.wantProxy != "") {
+				t.Errorf("proxied = %v, want %v", proxied, tt.wantProxy != "")
+			}
+			if proxied {
+				if got := <-targets; got != tt.address {
+					t.Errorf("CONNECT %q, want %q", got, tt.address)
+				}
+			}
+		})
+	}
+}
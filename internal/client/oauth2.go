@@ -0,0 +1,610 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+var oauth2Regex = regexp.MustCompile(`\{\{\s*\$oauth2\s+([\w.-]+)\s*\}\}`)
+
+const (
+	// expiryMargin renews tokens this long before they expire, so they do
+	// not expire while a request is in flight.
+	expiryMargin = 30 * time.Second
+	// authorizeTimeout bounds how long interactive flows wait for the user.
+	authorizeTimeout = 5 * time.Minute
+	// defaultRedirectURL is the loopback redirect of the authorization code
+	// flow when none is configured; port 0 picks a free port.
+	defaultRedirectURL = "http://127.0.0.1:0/callback"
+)
+
+// Token is an OAuth2 access token.
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"`
+}
+
+// valid reports whether the token can still be sent. Tokens without an
+// expiry are valid until the server rejects them.
+func (t *Token) valid(now time.Time) bool {
+	return t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(expiryMargin).Before(t.Expiry))
+}
+
+// authorization returns the Authorization header value for the token.
+func (t *Token) authorization() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer " + t.AccessToken
+	}
+	return t.TokenType + " " + t.AccessToken
+}
+
+// TokenStatus describes the OAuth2 token a request was sent with. Source is
+// "cached", "refreshed" or "fetched".
+type TokenStatus struct {
+	Provider string
+	Source   string
+	Token    string
+	Expiry   time.Time
+}
+
+// TokenCache keeps OAuth2 tokens in a JSON file so they are reused between
+// runs. The file is read before and written after every change, so
+// concurrent httpyum processes share tokens.
+type TokenCache struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]*Token
+}
+
+// NewTokenCache returns a cache stored at path, or kept in memory when path
+// is empty.
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{path: path, tokens: make(map[string]*Token)}
+}
+
+func (c *TokenCache) get(key string) *Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	return c.tokens[key]
+}
+
+func (c *TokenCache) put(key string, token *Token) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.load()
+	c.tokens[key] = token
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// load replaces the tokens with those on disk. A missing or unreadable file
+// leaves them as they are.
+func (c *TokenCache) load() {
+	if c.path == "" {
+		return
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	tokens := make(map[string]*Token)
+	if json.Unmarshal(data, &tokens) == nil {
+		c.tokens = tokens
+	}
+}
+
+// tokenKey identifies the tokens of a provider configuration, so that a
+// provider whose settings differ between environments gets separate tokens.
+func tokenKey(cfg *parser.OAuth2Config) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		cfg.GrantType, cfg.TokenURL, cfg.ClientID, cfg.Scope, cfg.Audience, cfg.Username,
+	}, "\n")))
+	return cfg.Name + "-" + hex.EncodeToString(sum[:6])
+}
+
+// oauth2Token returns a valid token of the named provider: the cached one,
+// a refreshed one or a newly requested one.
+func (e *Executor) oauth2Token(ctx context.Context, name string, vars map[string]string) (*Token, error) {
+	provider, ok := e.oauth2[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown OAuth2 provider %q", name)
+	}
+	cfg := resolveOAuth2Config(provider, vars)
+	key := tokenKey(cfg)
+	now := time.Now()
+
+	token := e.tokens.get(key)
+	source := "cached"
+	if token == nil || !token.valid(now) {
+		var refreshed *Token
+		if token != nil && token.RefreshToken != "" {
+			// A failed refresh falls back to running the grant again.
+			refreshed, _ = e.refreshToken(ctx, cfg, token)
+		}
+		token, source = refreshed, "refreshed"
+		if token == nil {
+			var err error
+			if token, err = e.requestToken(ctx, cfg); err != nil {
+				return nil, fmt.Errorf("OAuth2 provider %q: %w", name, err)
+			}
+			source = "fetched"
+		}
+		if err := e.tokens.put(key, token); err != nil {
+			e.prompt(fmt.Sprintf("Warning: could not cache OAuth2 token: %v", err))
+		}
+	}
+
+	e.tokenStatus[name] = &TokenStatus{Provider: name, Source: source, Token: token.AccessToken, Expiry: token.Expiry}
+	return token, nil
+}
+
+// resolveOAuth2Config substitutes variables in the provider settings.
+func resolveOAuth2Config(provider *parser.OAuth2Config, vars map[string]string) *parser.OAuth2Config {
+	cfg := *provider
+	for _, field := range []*string{
+		&cfg.AuthURL, &cfg.TokenURL, &cfg.DeviceAuthURL, &cfg.RedirectURL,
+		&cfg.ClientID, &cfg.ClientSecret, &cfg.Scope, &cfg.Audience,
+		&cfg.Username, &cfg.Password,
+	} {
+		*field = parser.SubstituteVariables(*field, vars)
+	}
+	return &cfg
+}
+
+// applyOAuth2Header replaces "Authorization: OAuth2 <name>" with the
+// provider's token.
+func (e *Executor) applyOAuth2Header(ctx context.Context, resolved *ResolvedRequest, vars map[string]string) error {
+	for i, h := range resolved.Headers {
+		if !strings.EqualFold(h.Key, "Authorization") {
+			continue
+		}
+		scheme, name, _ := strings.Cut(strings.TrimSpace(h.Value), " ")
+		if !strings.EqualFold(scheme, "OAuth2") {
+			continue
+		}
+		token, err := e.oauth2Token(ctx, strings.TrimSpace(name), vars)
+		if err != nil {
+			return err
+		}
+		resolved.Headers[i].Value = token.authorization()
+	}
+	return nil
+}
+
+// oauth2Providers returns the providers req refers to.
+func oauth2Providers(req *parser.Request) []string {
+	var names []string
+	add := func(name string) {
+		for _, n := range names {
+			if n == name {
+				return
+			}
+		}
+		names = append(names, name)
+	}
+
+	texts := []string{req.URL, req.Body}
+	for _, h := range req.Headers {
+		texts = append(texts, h.Value)
+		if strings.EqualFold(h.Key, "Authorization") {
+			if scheme, name, ok := strings.Cut(strings.TrimSpace(h.Value), " "); ok && strings.EqualFold(scheme, "OAuth2") {
+				add(strings.TrimSpace(name))
+			}
+		}
+	}
+	for _, text := range texts {
+		for _, m := range oauth2Regex.FindAllStringSubmatch(text, -1) {
+			add(m[1])
+		}
+	}
+	return names
+}
+
+// tokenStatuses returns the status of the tokens req was sent with.
+func (e *Executor) tokenStatuses(req *parser.Request) []TokenStatus {
+	var statuses []TokenStatus
+	for _, name := range oauth2Providers(req) {
+		if status, ok := e.tokenStatus[name]; ok {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses
+}
+
+func (e *Executor) prompt(message string) {
+	if e.promptFunc != nil {
+		e.promptFunc(message)
+		return
+	}
+	fmt.Fprintln(os.Stderr, message)
+}
+
+// requestToken runs the provider's grant.
+func (e *Executor) requestToken(ctx context.Context, cfg *parser.OAuth2Config) (*Token, error) {
+	switch cfg.GrantType {
+	case parser.GrantClientCredentials:
+		return e.tokenRequest(ctx, cfg, url.Values{"grant_type": {"client_credentials"}}, true)
+	case parser.GrantPassword:
+		return e.tokenRequest(ctx, cfg, url.Values{
+			"grant_type": {"password"},
+			"username":   {cfg.Username},
+			"password":   {cfg.Password},
+		}, true)
+	case parser.GrantAuthorizationCode:
+		return e.authorizationCode(ctx, cfg)
+	case parser.GrantDeviceCode:
+		return e.deviceCode(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported grant type %q", cfg.GrantType)
+	}
+}
+
+func (e *Executor) refreshToken(ctx context.Context, cfg *parser.OAuth2Config, token *Token) (*Token, error) {
+	refreshed, err := e.tokenRequest(ctx, cfg, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+// tokenRequest posts form to the token endpoint. withScope adds the
+// configured scope and audience.
+func (e *Executor) tokenRequest(ctx context.Context, cfg *parser.OAuth2Config, form url.Values, withScope bool) (*Token, error) {
+	if withScope {
+		setScope(form, cfg)
+	}
+	fields, err := e.oauth2Post(ctx, cfg, cfg.TokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	return parseToken(fields, time.Now())
+}
+
+func setScope(form url.Values, cfg *parser.OAuth2Config) {
+	if cfg.Scope != "" {
+		form.Set("scope", cfg.Scope)
+	}
+	if cfg.Audience != "" {
+		form.Set("audience", cfg.Audience)
+	}
+}
+
+// oauth2Error is an error response from an authorization server.
+type oauth2Error struct {
+	Code        string
+	Description string
+	Status      string
+}
+
+func (e *oauth2Error) Error() string {
+	msg := e.Status
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += " (" + e.Description + ")"
+	}
+	return msg
+}
+
+// oauth2Post posts a form to an authorization server endpoint with the
+// client's credentials and decodes the JSON response.
+func (e *Executor) oauth2Post(ctx context.Context, cfg *parser.OAuth2Config, endpoint string, form url.Values) (map[string]json.RawMessage, error) {
+	basicAuth := cfg.ClientSecret != "" && !cfg.ClientAuthInBody
+	if !basicAuth {
+		form.Set("client_id", cfg.ClientID)
+		if cfg.ClientSecret != "" {
+			form.Set("client_secret", cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if basicAuth {
+		// RFC 6749 section 2.3.1: credentials are form-encoded first.
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		// Some servers answer with a form-encoded body.
+		values, perr := url.ParseQuery(string(body))
+		if perr != nil || len(values) == 0 {
+			return nil, fmt.Errorf("%s: unexpected response from %s", resp.Status, endpoint)
+		}
+		fields = make(map[string]json.RawMessage)
+		for k := range values {
+			data, _ := json.Marshal(values.Get(k))
+			fields[k] = data
+		}
+	}
+
+	if code := stringField(fields, "error"); code != "" || resp.StatusCode/100 != 2 {
+		return nil, &oauth2Error{Code: code, Description: stringField(fields, "error_description"), Status: resp.Status}
+	}
+	return fields, nil
+}
+
+func parseToken(fields map[string]json.RawMessage, now time.Time) (*Token, error) {
+	token := &Token{
+		AccessToken:  stringField(fields, "access_token"),
+		TokenType:    stringField(fields, "token_type"),
+		RefreshToken: stringField(fields, "refresh_token"),
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if seconds, err := strconv.Atoi(stringField(fields, "expires_in")); err == nil && seconds > 0 {
+		token.Expiry = now.Add(time.Duration(seconds) * time.Second)
+	}
+	return token, nil
+}
+
+// stringField returns a string or number field of a JSON object as a string.
+func stringField(fields map[string]json.RawMessage, key string) string {
+	raw, ok := fields[key]
+	if !ok {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var n json.Number
+	if json.Unmarshal(raw, &n) == nil {
+		return n.String()
+	}
+	return ""
+}
+
+// authorizationCode runs the authorization code flow: the user authorizes in
+// the browser, which redirects to a loopback server that receives the code.
+func (e *Executor) authorizationCode(ctx context.Context, cfg *parser.OAuth2Config) (*Token, error) {
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		redirectURL = defaultRedirectURL
+	}
+	redirect, err := url.Parse(redirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redirect URL: %w", err)
+	}
+	if ip := net.ParseIP(redirect.Hostname()); redirect.Scheme != "http" || redirect.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return nil, fmt.Errorf("Redirect URL must be a loopback http URL such as %s", defaultRedirectURL)
+	}
+	port := redirect.Port()
+	if port == "" {
+		port = "80"
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(redirect.Hostname(), port))
+	if err != nil {
+		return nil, fmt.Errorf("cannot listen for the redirect: %w", err)
+	}
+	defer listener.Close()
+	_, actualPort, _ := net.SplitHostPort(listener.Addr().String())
+	redirect.Host = net.JoinHostPort(redirect.Hostname(), actualPort)
+	if redirect.Path == "" {
+		redirect.Path = "/"
+	}
+
+	state, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {cfg.ClientID},
+		"redirect_uri":  {redirect.String()},
+		"state":         {state},
+	}
+	setScope(query, cfg)
+	if cfg.PKCE {
+		sum := sha256.Sum256([]byte(verifier))
+		query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:]))
+		query.Set("code_challenge_method", "S256")
+	}
+	authURL := cfg.AuthURL
+	if strings.Contains(authURL, "?") {
+		authURL += "&" + query.Encode()
+	} else {
+		authURL += "?" + query.Encode()
+	}
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != redirect.Path {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		var cb callback
+		switch {
+		case q.Get("error") != "":
+			cb.err = &oauth2Error{Code: q.Get("error"), Description: q.Get("error_description"), Status: "authorization failed"}
+		case q.Get("state") != state:
+			cb.err = fmt.Errorf("authorization response has an unexpected state")
+		case q.Get("code") == "":
+			cb.err = fmt.Errorf("authorization response has no code")
+		default:
+			cb.code = q.Get("code")
+		}
+		if cb.err != nil {
+			fmt.Fprintf(w, "Authorization failed: %v\n", cb.err)
+		} else {
+			fmt.Fprintln(w, "Authorization complete. You can close this window and return to httpyum.")
+		}
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	e.prompt(fmt.Sprintf("Authorize %s in your browser:\n%s", cfg.Name, authURL))
+	openBrowser(authURL)
+
+	var cb callback
+	select {
+	case cb = <-callbacks:
+	case <-time.After(authorizeTimeout):
+		return nil, fmt.Errorf("timed out waiting for authorization")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if cb.err != nil {
+		return nil, cb.err
+	}
+
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {cb.code},
+		"redirect_uri": {redirect.String()},
+	}
+	if cfg.PKCE {
+		form.Set("code_verifier", verifier)
+	}
+	return e.tokenRequest(ctx, cfg, form, false)
+}
+
+// deviceCode runs the device authorization flow (RFC 8628): the user enters
+// a code on another device while the token endpoint is polled.
+func (e *Executor) deviceCode(ctx context.Context, cfg *parser.OAuth2Config) (*Token, error) {
+	form := url.Values{}
+	setScope(form, cfg)
+	fields, err := e.oauth2Post(ctx, cfg, cfg.DeviceAuthURL, form)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceCode := stringField(fields, "device_code")
+	userCode := stringField(fields, "user_code")
+	verificationURI := stringField(fields, "verification_uri")
+	if verificationURI == "" {
+		verificationURI = stringField(fields, "verification_url")
+	}
+	if deviceCode == "" || verificationURI == "" {
+		return nil, fmt.Errorf("device authorization response has no device_code or verification_uri")
+	}
+
+	interval := 5 * time.Second
+	if n, err := strconv.Atoi(stringField(fields, "interval")); err == nil && n > 0 {
+		interval = time.Duration(n) * time.Second
+	}
+	deadline := time.Now().Add(authorizeTimeout)
+	if n, err := strconv.Atoi(stringField(fields, "expires_in")); err == nil && n > 0 {
+		deadline = time.Now().Add(time.Duration(n) * time.Second)
+	}
+
+	e.prompt(fmt.Sprintf("Authorize %s: open %s and enter code %s", cfg.Name, verificationURI, userCode))
+	if complete := stringField(fields, "verification_uri_complete"); complete != "" {
+		openBrowser(complete)
+	} else {
+		openBrowser(verificationURI)
+	}
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		token, err := e.tokenRequest(ctx, cfg, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {deviceCode},
+		}, false)
+		if oerr, ok := err.(*oauth2Error); ok {
+			switch oerr.Code {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += 5 * time.Second
+				continue
+			}
+		}
+		return token, err
+	}
+	return nil, fmt.Errorf("device code expired before authorization")
+}
+
+// openBrowser opens target in the default browser, ignoring failures: the
+// URL is also shown to the user.
+func openBrowser(target string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
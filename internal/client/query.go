@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"httpyum/internal/jsonpath"
+)
+
+// QueryBody extracts a value from a body. path is "*" for the whole body, a
+// JSONPath expression starting with "$" for JSON bodies, or an absolute XPath
+// expression starting with "/" for XML bodies. found is false when the path
+// matches nothing.
+func QueryBody(body []byte, path string) (value string, found bool, err error) {
+	path = strings.TrimSpace(path)
+
+	switch {
+	case path == "*" || path == "":
+		return string(body), true, nil
+
+	case strings.HasPrefix(path, "$"):
+		// Numbers are kept as written, so IDs beyond float64 precision
+		// are passed on unchanged.
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			return "", false, fmt.Errorf("body is not valid JSON: %w", err)
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return "", false, fmt.Errorf("body is not valid JSON: unexpected data after the value")
+		}
+		matches, err := jsonpath.Query(doc, path)
+		if err != nil {
+			return "", false, err
+		}
+		if len(matches) == 0 {
+			return "", false, nil
+		}
+		return JSONValueString(matches[0]), true, nil
+
+	case strings.HasPrefix(path, "/"):
+		matches, err := queryXPath(body, path)
+		if err != nil {
+			return "", false, err
+		}
+		if len(matches) == 0 {
+			return "", false, nil
+		}
+		return matches[0], true, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported body path %q (use *, $.json.path or /xml/path)", path)
+}
+
+// JSONValueString renders a decoded JSON value for substitution: strings are
+// returned unquoted, integers exactly as written, other numbers without
+// exponent notation, and objects and arrays as compact JSON.
+func JSONValueString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return val
+	case json.Number:
+		if f, err := val.Float64(); err == nil && strings.ContainsAny(val.String(), ".eE") {
+			return strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		return val.String()
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestQueryBodyJSON(t *testing.T) {
+	body := []byte(`{
+		"id": 9007199254740993,
+		"price": 12.50,
+		"ratio": 1e-3,
+		"big": 1.5e21,
+		"name": "widget",
+		"active": true,
+		"missing": null,
+		"tags": ["a", "b", "c"],
+		"owner": {"id": 7, "name": "ann"},
+		"items": [{"id": 1, "sku": "x"}, {"id": 2, "sku": "y"}],
+		"odd key": "spaced"
+	}`)
+
+	tests := []struct {
+		path      string
+		want      string
+		wantFound bool
+	}{
+		{"$.id", "9007199254740993", true},
+		{"$.price", "12.5", true},
+		{"$.ratio", "0.001", true},
+		{"$.big", "1500000000000000000000", true},
+		{"$.name", "widget", true},
+		{"$.active", "true", true},
+		{"$.missing", "null", true},
+		{"$.owner", `{"id":7,"name":"ann"}`, true},
+		{"$.owner.name", "ann", true},
+		{"$['owner']['id']", "7", true},
+		{`$["odd key"]`, "spaced", true},
+		{"$.tags", `["a","b","c"]`, true},
+		{"$.tags[0]", "a", true},
+		{"$.tags[-1]", "c", true},
+		{"$.tags[*]", "a", true},
+		{"$.items[1].sku", "y", true},
+		{"$.items[*].sku", "x", true},
+		{"$..sku", "x", true},
+		{"$.owner.*", "7", true},
+		{"*", "", true},
+		{"$.nope", "", false},
+		{"$.owner.nope", "", false},
+		{"$.tags[3]", "", false},
+		{"$.name.first", "", false},
+		{"$..nope", "", false},
+	}
+	for _, tt := range tests {
+		got, found, err := QueryBody(body, tt.path)
+		if err != nil {
+			t.Errorf("QueryBody(%q) error = %v", tt.path, err)
+			continue
+		}
+		if tt.path == "*" {
+			tt.want = string(body)
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("QueryBody(%q) = %q, %v, want %q, %v", tt.path, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
+
+func TestQueryBodyXML(t *testing.T) {
+	body := []byte(`<?xml version="1.0"?>
+<users>
+  <user id="7" role="admin"><name>Ann</name><email>ann@example.com</email></user>
+  <user id="8"><name>Bob</name></user>
+  <meta>count <b>2</b></meta>
+</users>`)
+
+	tests := []struct {
+		path      string
+		want      string
+		wantFound bool
+	}{
+		{"/users/user/name", "Ann", true},
+		{"/users/user[2]/name", "Bob", true},
+		{"/users/user[last()]/name", "Bob", true},
+		{"//user[@id='8']/name", "Bob", true},
+		{`//user[@id="7"]/email`, "ann@example.com", true},
+		{"//user[@role]/name", "Ann", true},
+		{"//user/@id", "7", true},
+		{"/users/user[2]/@id", "8", true},
+		{"//name/text()", "Ann", true},
+		{"/users/meta", "count 2", true},
+		{"/users/*[3]", "count 2", true},
+		{"/users/user[3]/name", "", false},
+		{"//user[@id='9']", "", false},
+		{"//user/@missing", "", false},
+		{"/users/group", "", false},
+	}
+	for _, tt := range tests {
+		got, found, err := QueryBody(body, tt.path)
+		if err != nil {
+			t.Errorf("QueryBody(%q) error = %v", tt.path, err)
+			continue
+		}
+		if got != tt.want || found != tt.wantFound {
+			t.Errorf("QueryBody(%q) = %q, %v, want %q, %v", tt.path, got, found, tt.want, tt.wantFound)
+		}
+	}
+}
+
+func TestQueryBodyErrors(t *testing.T) {
+	tests := []struct {
+		body, path, wantErr string
+	}{
+		{`{"a": 1}`, "$.a[x]", "bad index"},
+		{`{"a": 1}`, "$.a[0", "unterminated"},
+		{`{"a": 1`, "$.a", "not valid JSON"},
+		{`{"a": 1} {"b": 2}`, "$.a", "not valid JSON"},
+		{`<a><b/></a>`, "/a/text()/b", "must be the last step"},
+		{`<a><b/></a>`, "/a/b[position()=1]", "unsupported XPath predicate"},
+		{`{"a": 1}`, "a.b", "unsupported body path"},
+	}
+	for _, tt := range tests {
+		_, _, err := QueryBody([]byte(tt.body), tt.path)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("QueryBody(%q, %q) error = %v, want %q", tt.body, tt.path, err, tt.wantErr)
+		}
+	}
+}
+
+func TestJSONValueString(t *testing.T) {
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{nil, "null"},
+		{"text", "text"},
+		{true, "true"},
+		{json.Number("12345678901234567890"), "12345678901234567890"},
+		{json.Number("-3"), "-3"},
+		{json.Number("2.50"), "2.5"},
+		{json.Number("1E+2"), "100"},
+		{float64(1e21), "1000000000000000000000"},
+		{[]any{json.Number("1"), "a"}, `[1,"a"]`},
+		{map[string]any{"id": json.Number("9007199254740993")}, `{"id":9007199254740993}`},
+	}
+	for _, tt := range tests {
+		if got := JSONValueString(tt.value); got != tt.want {
+			t.Errorf("JSONValueString(%#v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"httpyum/internal/parser"
+	"httpyum/internal/script"
+)
+
+// runPreRequestScripts runs the pre-request scripts of req in order. Values
+// set with request.variables.set are stored in requestVars.
+func (e *Executor) runPreRequestScripts(req *parser.Request, requestVars map[string]string) ([]string, error) {
+	var logs []string
+	if len(req.PreRequestScripts) == 0 {
+		return logs, nil
+	}
+
+	scriptReq := &script.Request{
+		Method:      req.Method,
+		URL:         req.URL,
+		Headers:     headerMap(req.Headers),
+		Body:        req.Body,
+		Variables:   requestVars,
+		Environment: e.variables,
+	}
+
+	for _, s := range req.PreRequestScripts {
+		name, source, err := e.loadScript(s)
+		if err != nil {
+			return logs, err
+		}
+		globals := maps.Clone(e.globals)
+		result, err := script.RunPreRequest(name, source, globals, scriptReq)
+		e.setGlobals(globals)
+		if result != nil {
+			logs = append(logs, result.Logs...)
+		}
+		if err != nil {
+			return logs, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return logs, nil
+}
+
+// runResponseHandlers runs the response handler scripts of req, recording
+// client.test outcomes and script errors as assertion results.
+func (e *Executor) runResponseHandlers(req *parser.Request, result *ExecutionResult, requestVars map[string]string) {
+	scriptReq := &script.Request{
+		Method:      result.Resolved.Method,
+		URL:         result.Resolved.URL,
+		Headers:     headerMap(result.Resolved.Headers),
+		Body:        result.Resolved.Body,
+		Variables:   requestVars,
+		Environment: e.variables,
+	}
+	scriptResp := &script.Response{
+		Status:      result.Response.StatusCode,
+		Headers:     result.Response.Headers,
+		Body:        result.Response.Body,
+		ContentType: result.Response.ContentType,
+	}
+
+	for _, s := range req.ResponseHandlers {
+		name, source, err := e.loadScript(s)
+		if err != nil {
+			result.Assertions = append(result.Assertions, AssertionResult{Name: "response handler " + name, Message: err.Error()})
+			continue
+		}
+
+		globals := maps.Clone(e.globals)
+		scriptResult, err := script.RunResponseHandler(name, source, globals, scriptReq, scriptResp)
+		e.setGlobals(globals)
+		if scriptResult != nil {
+			result.Logs = append(result.Logs, scriptResult.Logs...)
+			for _, t := range scriptResult.Tests {
+				result.Assertions = append(result.Assertions, AssertionResult{
+					Name:    "test " + t.Name,
+					Passed:  t.Passed,
+					Message: t.Message,
+				})
+			}
+		}
+		if err != nil {
+			result.Assertions = append(result.Assertions, AssertionResult{Name: "response handler " + name, Message: err.Error()})
+		}
+	}
+}
+
+// setGlobals replaces the globals with those a script left. Scripts change
+// a copy, so Substitute never reads globals being changed.
+func (e *Executor) setGlobals(globals map[string]string) {
+	e.state.Lock()
+	defer e.state.Unlock()
+	e.globals = globals
+}
+
+// loadScript returns a display name and the source of s, reading it from
+// disk relative to the executor's base directory when it refers to a file.
+func (e *Executor) loadScript(s parser.Script) (string, string, error) {
+	if s.Path == "" {
+		return fmt.Sprintf("script at line %d", s.Line), s.Source, nil
+	}
+
+	path := s.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(e.baseDir, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s.Path, "", fmt.Errorf("error reading script: %w", err)
+	}
+	return s.Path, string(data), nil
+}
+
+func headerMap(headers []parser.Header) http.Header {
+	h := make(http.Header)
+	for _, header := range headers {
+		h.Add(header.Key, header.Value)
+	}
+	return h
+}
@@ -0,0 +1,252 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"httpyum/internal/parser"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Defaults for Settings fields left zero.
+const (
+	DefaultTimeout      = 30 * time.Second
+	DefaultMaxRedirects = 10
+)
+
+// Settings control how requests are sent. Requests override them with the
+// "# @timeout", "# @connection-timeout", "# @no-redirect",
+// "# @max-redirects", "# @insecure", "# @proxy" and "# @no-proxy"
+// annotations.
+type Settings struct {
+	// Timeout bounds the whole exchange, including reading the body.
+	Timeout time.Duration
+	// ConnectionTimeout bounds establishing the connection. Zero leaves it
+	// to Timeout.
+	ConnectionTimeout time.Duration
+	// MaxRedirects is how many redirects are followed. NoRedirect returns
+	// redirect responses as they are.
+	MaxRedirects int
+	NoRedirect   bool
+	// Insecure skips TLS certificate verification.
+	Insecure bool
+
+	// Proxy is the URL of the proxy to send requests through, with an
+	// http, https, socks5 or socks5h scheme and credentials if it needs
+	// them. Without Proxy the HTTP_PROXY and HTTPS_PROXY environment
+	// variables apply. Hosts matching NoProxy, in the NO_PROXY syntax, and
+	// then also localhost bypass the proxy; without NoProxy, NO_PROXY
+	// applies to the environment's proxies. Direct bypasses every proxy.
+	Proxy   string
+	NoProxy string
+	Direct  bool
+}
+
+// forRequest returns s overridden by the annotations of req, with defaults
+// filled in.
+func (s Settings) forRequest(req *parser.Request) Settings {
+	if req.Timeout > 0 {
+		s.Timeout = req.Timeout
+	}
+	if req.ConnectionTimeout > 0 {
+		s.ConnectionTimeout = req.ConnectionTimeout
+	}
+	if req.MaxRedirects > 0 {
+		s.MaxRedirects = req.MaxRedirects
+		s.NoRedirect = false
+	}
+	if req.NoRedirect {
+		s.NoRedirect = true
+	}
+	if req.Insecure {
+		s.Insecure = true
+	}
+	if req.Proxy != "" {
+		s.Proxy, s.NoProxy, s.Direct = req.Proxy, "", false
+	}
+	if req.NoProxy {
+		s.Direct = true
+	}
+
+	if s.Timeout <= 0 {
+		s.Timeout = DefaultTimeout
+	}
+	if s.MaxRedirects <= 0 {
+		s.MaxRedirects = DefaultMaxRedirects
+	}
+	return s
+}
+
+// transportKey identifies the transport settings, so requests with the same
+// ones share connections.
+type transportKey struct {
+	connectionTimeout time.Duration
+	insecure          bool
+	tls               *parser.TLSConfig
+	proxy             string
+	noProxy           string
+	direct            bool
+}
+
+// transport returns the shared transport for s and the TLS configuration
+// of the request's host, which may be nil.
+func (e *Executor) transport(s Settings, cfg *parser.TLSConfig) (*http.Transport, error) {
+	key := transportKey{
+		connectionTimeout: s.ConnectionTimeout,
+		insecure:          s.Insecure,
+		tls:               cfg,
+		proxy:             s.Proxy,
+		noProxy:           s.NoProxy,
+		direct:            s.Direct,
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if t, ok := e.transports[key]; ok {
+		return t, nil
+	}
+
+	tlsConfig, err := e.tlsClientConfig(cfg, s.Insecure)
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = tlsConfig
+	t.Proxy = proxyFunc(s)
+	if s.ConnectionTimeout > 0 {
+		dialer := &net.Dialer{Timeout: s.ConnectionTimeout, KeepAlive: 30 * time.Second}
+		t.DialContext = dialer.DialContext
+		t.TLSHandshakeTimeout = s.ConnectionTimeout
+	}
+	e.transports[key] = t
+	return t, nil
+}
+
+// proxyFunc returns the function that picks the proxy of each request for
+// s, or nil to connect directly.
+func proxyFunc(s Settings) func(*http.Request) (*url.URL, error) {
+	if s.Direct {
+		return nil
+	}
+	if s.Proxy == "" && s.NoProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+	if s.NoProxy == "" {
+		// Without bypass rules every request goes through the proxy,
+		// including those to localhost.
+		raw := s.Proxy
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return func(*http.Request) (*url.URL, error) {
+				return nil, fmt.Errorf("invalid proxy %q: %w", s.Proxy, err)
+			}
+		}
+		return http.ProxyURL(u)
+	}
+
+	config := httpproxy.FromEnvironment()
+	if s.Proxy != "" {
+		config.HTTPProxy, config.HTTPSProxy = s.Proxy, s.Proxy
+	}
+	config.NoProxy = s.NoProxy
+	proxy := config.ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		u, err := proxy(r.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", s.Proxy, err)
+		}
+		return u, nil
+	}
+}
+
+// router sends each request, redirects included, through the transport for
+// the TLS configuration of its host, so client certificates are only
+// presented to the hosts they are configured for. It records the proxy the
+// last request went through.
+type router struct {
+	e        *Executor
+	settings Settings
+	proxy    string
+}
+
+func (r *router) RoundTrip(req *http.Request) (*http.Response, error) {
+	var cfg *parser.TLSConfig
+	if req.URL.Scheme == "https" {
+		cfg = matchTLS(r.e.tls, req.URL.Hostname(), req.URL.Port())
+	}
+	t, err := r.e.transport(r.settings, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r.proxy = ""
+	if t.Proxy != nil {
+		u, err := t.Proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			r.proxy = u.Redacted()
+		}
+	}
+	return t.RoundTrip(req)
+}
+
+// usedProxy returns the proxy the last request sent with c went through.
+func usedProxy(c *http.Client) string {
+	if r, ok := c.Transport.(*router); ok {
+		return r.proxy
+	}
+	return ""
+}
+
+// httpClient returns a client that sends requests with s, storing cookies
+// in the executor's jar when cookies is set.
+func (e *Executor) httpClient(s Settings, cookies bool) *http.Client {
+	c := &http.Client{
+		Transport: &router{e: e, settings: s},
+		Timeout:   s.Timeout,
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if s.NoRedirect {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > s.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", s.MaxRedirects)
+			}
+			return nil
+		},
+	}
+	if e.jar != nil && cookies {
+		c.Jar = e.jar
+	}
+	return c
+}
+
+// failure describes why a request sent with s failed, naming the timeout
+// that expired or explaining the failed TLS handshake if that is the cause.
+func failure(err error, s Settings, action string) string {
+	if explanation := explainTLS(err); explanation != "" {
+		return "TLS handshake failed: " + explanation
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && (opErr.Op == "proxyconnect" || opErr.Op == "socks connect") {
+		return "could not connect through the proxy"
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		return action
+	}
+	if s.ConnectionTimeout > 0 && (errors.As(err, &opErr) && opErr.Op == "dial" || strings.Contains(err.Error(), "TLS handshake timeout")) {
+		return fmt.Sprintf("connection timed out after %s", s.ConnectionTimeout)
+	}
+	return fmt.Sprintf("request timed out after %s", s.Timeout)
+}
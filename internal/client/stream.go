@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+// StreamEvent is an event of a text/event-stream response, or a line of an
+// NDJSON response with only Data set.
+type StreamEvent struct {
+	Time  time.Time
+	Event string
+	ID    string
+	Data  string
+}
+
+// streamingTypes are the media types of responses read event by event.
+var streamingTypes = map[string]bool{
+	"text/event-stream":       true,
+	"application/x-ndjson":    true,
+	"application/ndjson":      true,
+	"application/jsonl":       true,
+	"application/x-jsonlines": true,
+	"application/stream+json": true,
+}
+
+// IsStreaming reports whether responses of contentType are streams of
+// server-sent events or JSON lines, which are shown as they arrive.
+func IsStreaming(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return streamingTypes[mediaType]
+}
+
+// IsEventStream reports whether contentType is text/event-stream.
+func IsEventStream(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/event-stream"
+}
+
+// errStreamStopped is the cause of the cancellation of a stream stopped
+// with ResponseStream.Stop.
+var errStreamStopped = errors.New("stream stopped")
+
+// readStream reads a streaming body, calling add with each event, or each
+// line of JSON lines, as soon as it is complete. It returns the whole body
+// and the events read, also when reading fails part way.
+func readStream(body io.Reader, contentType string, add func(StreamEvent)) ([]byte, []StreamEvent, error) {
+	var raw bytes.Buffer
+	reader := bufio.NewReader(io.TeeReader(body, &raw))
+	eventStream := IsEventStream(contentType)
+
+	var events []StreamEvent
+	emit := func(e StreamEvent) {
+		e.Time = time.Now()
+		events = append(events, e)
+		if add != nil {
+			add(e)
+		}
+	}
+
+	var event StreamEvent
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		// A line cut off by a failed read is not an event.
+		if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return raw.Bytes(), events, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if !eventStream {
+			if strings.TrimSpace(line) != "" {
+				emit(StreamEvent{Data: line})
+			}
+			continue
+		}
+
+		if line == "" {
+			// A blank line dispatches the event, if it has data.
+			if data != nil {
+				event.Data = strings.Join(data, "\n")
+				emit(event)
+			}
+			event, data = StreamEvent{ID: event.ID}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = value
+		}
+	}
+}
+
+// ParseStream returns the events of a streaming response body that was
+// read in full, without the times they arrived.
+func ParseStream(contentType string, body []byte) []StreamEvent {
+	_, events, _ := readStream(bytes.NewReader(body), contentType, nil)
+	for i := range events {
+		events[i].Time = time.Time{}
+	}
+	return events
+}
+
+// ResponseStream is a request being executed in the background whose
+// response, when it is a stream of server-sent events or JSON lines, can be
+// followed event by event while it is received.
+type ResponseStream struct {
+	cancel context.CancelCauseFunc
+
+	mu      sync.Mutex
+	partial *ExecutionResult
+	events  []StreamEvent
+	result  *ExecutionResult
+	changed chan struct{}
+}
+
+// ExecuteStream executes req in the background like Execute. When its
+// response is a stream, it is followed until it ends or Stop is called;
+// the request's timeout then only bounds waiting for the response headers.
+func (e *Executor) ExecuteStream(ctx context.Context, req *parser.Request) *ResponseStream {
+	ctx, cancel := context.WithCancelCause(ctx)
+	s := &ResponseStream{cancel: cancel, changed: make(chan struct{})}
+	go func() {
+		e.running.Lock()
+		result := e.executeNamed(ctx, req, s)
+		e.running.Unlock()
+		cancel(nil)
+		s.mu.Lock()
+		s.result = result
+		s.notify()
+		s.mu.Unlock()
+	}()
+	return s
+}
+
+// started records the result of a streaming response whose headers
+// arrived.
+func (s *ResponseStream) started(partial *ExecutionResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.partial = partial
+	s.notify()
+}
+
+func (s *ResponseStream) add(event StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	s.notify()
+}
+
+// notify wakes those waiting on Changed. s.mu must be held.
+func (s *ResponseStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Changed returns a channel that is closed when the response starts
+// streaming, an event arrives or the request completes.
+func (s *ResponseStream) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Partial returns the result of a streaming response received so far, with
+// the events that arrived, or nil before its headers arrive.
+func (s *ResponseStream) Partial() *ExecutionResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.partial == nil {
+		return nil
+	}
+	partial := *s.partial
+	response := *partial.Response
+	response.Duration = time.Since(response.RequestTime)
+	partial.Response = &response
+	partial.Events = append([]StreamEvent(nil), s.events...)
+	return &partial
+}
+
+// Result returns the result of the request once it completed, or nil.
+func (s *ResponseStream) Result() *ExecutionResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result
+}
+
+// Stop stops receiving a streaming response. The result then holds the
+// events received so far.
+func (s *ResponseStream) Stop() {
+	s.cancel(errStreamStopped)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsStreaming(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/event-stream", true},
+		{"text/event-stream; charset=utf-8", true},
+		{"Application/X-NDJSON", true},
+		{"application/jsonl", true},
+		{"application/stream+json", true},
+		{"application/json", false},
+		{"text/plain", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsStreaming(tt.contentType); got != tt.want {
+			t.Errorf("IsStreaming(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []StreamEvent
+	}{
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			body:        ": comment\nretry: 1000\n\nevent: token\nid: 1\ndata: {\"n\":1}\n\ndata: line one\ndata: line two\n\n",
+			want: []StreamEvent{
+				{Event: "token", ID: "1", Data: `{"n":1}`},
+				{ID: "1", Data: "line one\nline two"},
+			},
+		},
+		{
+			name:        "CRLF and no space after colon",
+			contentType: "text/event-stream; charset=utf-8",
+			body:        "event:ping\r\ndata:x\r\n\r\n",
+			want:        []StreamEvent{{Event: "ping", Data: "x"}},
+		},
+		{
+			name:        "empty data is an event",
+			contentType: "text/event-stream",
+			body:        "data:\n\n",
+			want:        []StreamEvent{{Data: ""}},
+		},
+		{
+			name:        "event without data is dropped",
+			contentType: "text/event-stream",
+			body:        "event: ping\n\n",
+			want:        nil,
+		},
+		{
+			name:        "unterminated event is dropped",
+			contentType: "text/event-stream",
+			body:        "data: a\n\ndata: b",
+			want:        []StreamEvent{{Data: "a"}},
+		},
+		{
+			name:        "JSON lines",
+			contentType: "application/x-ndjson",
+			body:        "{\"i\":0}\n\n{\"i\":1}\r\n{\"i\":2}",
+			want:        []StreamEvent{{Data: `{"i":0}`}, {Data: `{"i":1}`}, {Data: `{"i":2}`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseStream(tt.contentType, []byte(tt.body))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseStream() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadStreamFailedRead(t *testing.T) {
+	failure := errors.New("timeout")
+	body := io.MultiReader(strings.NewReader("{\"i\":0}\n{\"i\":"), &errReader{failure})
+
+	var added []StreamEvent
+	raw, events, err := readStream(body, "application/x-ndjson", func(e StreamEvent) {
+		added = append(added, e)
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("readStream() error = %v, want %v", err, failure)
+	}
+	if string(raw) != "{\"i\":0}\n{\"i\":" {
+		t.Errorf("raw = %q", raw)
+	}
+	if len(events) != 1 || events[0].Data != `{"i":0}` || events[0].Time.IsZero() {
+		t.Errorf("events = %#v, want the complete line only", events)
+	}
+	if !reflect.DeepEqual(added, events) {
+		t.Errorf("added = %#v, want %#v", added, events)
+	}
+}
+
+type errReader struct{ err error }
+
+func (r *errReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,205 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpyum/internal/parser"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// matchTLS returns the configuration whose host pattern matches host most
+// specifically: exact names over wildcards, longer wildcards over shorter
+// ones, and patterns with a port over those without. Earlier configurations
+// win ties.
+func matchTLS(configs []*parser.TLSConfig, host, port string) *parser.TLSConfig {
+	host = strings.ToLower(host)
+	var best *parser.TLSConfig
+	bestScore := -1
+	for _, cfg := range configs {
+		pattern, patternPort := cfg.Host, ""
+		if h, p, err := net.SplitHostPort(cfg.Host); err == nil {
+			pattern, patternPort = h, p
+		}
+		if patternPort != "" && patternPort != port {
+			continue
+		}
+
+		score := -1
+		switch {
+		case pattern == "*" || pattern == "":
+			score = 0
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				score = len(pattern)
+			}
+		case pattern == host:
+			score = 1 << 16
+		}
+		if score < 0 {
+			continue
+		}
+		if patternPort != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = cfg, score
+		}
+	}
+	return best
+}
+
+// tlsClientConfig loads the certificates of cfg into a tls.Config. insecure
+// skips server verification regardless of cfg.
+func (e *Executor) tlsClientConfig(cfg *parser.TLSConfig, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if cfg == nil {
+		return config, nil
+	}
+
+	subst := func(s string) string {
+		return parser.SubstituteVariables(s, e.variables)
+	}
+	path := func(p string) string {
+		p = subst(p)
+		if p != "" && !filepath.IsAbs(p) {
+			p = filepath.Join(e.baseDir, p)
+		}
+		return p
+	}
+
+	config.InsecureSkipVerify = insecure || cfg.Insecure
+	config.MinVersion = cfg.MinVersion
+	config.ServerName = subst(cfg.ServerName)
+
+	if len(cfg.CACertificates) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, caPath := range cfg.CACertificates {
+			data, err := os.ReadFile(path(caPath))
+			if err != nil {
+				return nil, fmt.Errorf("TLS configuration for %s: reading CA certificates: %w", cfg.Host, err)
+			}
+			if !pool.AppendCertsFromPEM(data) {
+				return nil, fmt.Errorf("TLS configuration for %s: no PEM certificates in %s", cfg.Host, caPath)
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if cfg.ClientCertificate != "" {
+		cert, err := loadClientCertificate(path(cfg.ClientCertificate), path(cfg.ClientCertificateKey), subst(cfg.Passphrase))
+		if err != nil {
+			return nil, fmt.Errorf("TLS configuration for %s: client certificate: %w", cfg.Host, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// loadClientCertificate reads a PKCS#12 bundle, recognized by its .p12 or
+// .pfx extension, or a PEM certificate and key. Without keyPath the key is
+// read from the certificate file.
+func loadClientCertificate(certPath, keyPath, passphrase string) (tls.Certificate, error) {
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	switch strings.ToLower(filepath.Ext(certPath)) {
+	case ".p12", ".pfx":
+		key, leaf, chain, err := pkcs12.DecodeChain(data, passphrase)
+		if err != nil {
+			if errors.Is(err, pkcs12.ErrIncorrectPassword) {
+				return tls.Certificate{}, fmt.Errorf("wrong passphrase for %s", certPath)
+			}
+			return tls.Certificate{}, fmt.Errorf("reading %s: %w", certPath, err)
+		}
+		cert := tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: key, Leaf: leaf}
+		for _, c := range chain {
+			cert.Certificate = append(cert.Certificate, c.Raw)
+		}
+		return cert, nil
+	}
+
+	keyData := data
+	if keyPath != "" {
+		if keyData, err = os.ReadFile(keyPath); err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+	if block := findPEMKey(keyData); block != nil && (block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "") {
+		return tls.Certificate{}, fmt.Errorf("encrypted PEM keys are not supported; decrypt the key or use a PKCS#12 bundle")
+	}
+	return tls.X509KeyPair(data, keyData)
+}
+
+// findPEMKey returns the first private key block in data.
+func findPEMKey(data []byte) *pem.Block {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil
+		}
+		if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			return block
+		}
+	}
+}
+
+// tlsAlerts explains the TLS alerts servers commonly send during a failed
+// handshake, keyed by the alert's description.
+var tlsAlerts = map[string]string{
+	"tls: handshake failure":               "the server rejected the handshake; it may require a client certificate, or a TLS version or cipher suite this client does not offer",
+	"tls: bad certificate":                 "the server rejected the client certificate",
+	"tls: certificate unknown":             "the server rejected the client certificate",
+	"tls: unknown certificate authority":   "the server does not trust the CA that issued the client certificate",
+	"tls: protocol version not supported":  "the server does not support the TLS versions offered; check minVersion",
+	"tls: unrecognized name":               "the server does not recognize the requested server name; check serverName",
+	"tls: certificate required":            "the server requires a client certificate; configure clientCertificate in SSLConfiguration",
+	"tls: expired certificate":             "the server rejected the client certificate as expired",
+	"tls: unsupported certificate":         "the server does not support the type of the client certificate",
+	"tls: inappropriate fallback detected": "the server refused a downgraded TLS version; check minVersion",
+	"tls: insufficient security level":     "the server requires stronger ciphers than this client offered",
+}
+
+// explainTLS describes a TLS handshake failure in plain words, or returns ""
+// when err is not one.
+func explainTLS(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	var remote *net.OpError
+	var record tls.RecordHeaderError
+
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "the server certificate is signed by an unknown authority; add its CA to caCertificates in SSLConfiguration, or use # @insecure"
+	case errors.As(err, &hostname):
+		return fmt.Sprintf("the server certificate is not valid for %s; check the URL or set serverName in SSLConfiguration", hostname.Host)
+	case errors.As(err, &invalid):
+		if invalid.Reason == x509.Expired {
+			return "the server certificate has expired or is not yet valid"
+		}
+		return "the server certificate is invalid: " + invalid.Error()
+	case errors.As(err, &remote) && remote.Op == "remote error":
+		if explanation, ok := tlsAlerts[remote.Err.Error()]; ok {
+			return explanation
+		}
+		return "the server aborted the handshake: " + remote.Err.Error()
+	case errors.As(err, &record), strings.Contains(err.Error(), "server gave HTTP response to HTTPS client"):
+		return "the server did not answer with TLS; it may only serve plain http"
+	}
+	return ""
+}
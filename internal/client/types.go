@@ -16,11 +16,49 @@ type Response struct {
 	Duration    time.Duration
 	RequestTime time.Time
 	Size        int64
+	// Proxy is the proxy the request went through, without its password.
+	Proxy string
+	// Trailers are the trailing metadata of a gRPC call, with its
+	// Grpc-Status and Grpc-Message.
+	Trailers http.Header
+}
+
+// ResolvedRequest is a request after variable substitution, as it was sent.
+type ResolvedRequest struct {
+	Method  string
+	URL     string
+	Headers []parser.Header
+	Body    string
 }
 
 type ExecutionResult struct {
-	Request  *parser.Request
-	Response *Response
-	Error    error
-	Success  bool
+	Request    *parser.Request
+	Resolved   *ResolvedRequest
+	Response   *Response
+	Assertions []AssertionResult
+	Tokens     []TokenStatus
+	Logs       []string
+	Error      error
+	Success    bool
+	// GraphQLErrors are the errors a GraphQL response reports, usually
+	// with a 200 status.
+	GraphQLErrors []GraphQLError
+	// Frames are the messages sent and received on a WebSocket
+	// connection.
+	Frames []WebSocketFrame
+	// Events are the server-sent events or JSON lines of a streaming
+	// response, and Stopped is set when it was stopped before it ended.
+	Events  []StreamEvent
+	Stopped bool
+}
+
+// FailedAssertions returns the number of assertions that did not pass.
+func (r *ExecutionResult) FailedAssertions() int {
+	failed := 0
+	for _, a := range r.Assertions {
+		if !a.Passed {
+			failed++
+		}
+	}
+	return failed
 }
@@ -0,0 +1,437 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"httpyum/internal/parser"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketFrame is a message sent or received on a WebSocket connection.
+type WebSocketFrame struct {
+	Time time.Time
+	// Sent is set for messages sent to the server.
+	Sent bool
+	Data []byte
+	// Binary is set for binary messages; others are text.
+	Binary bool
+}
+
+// Text returns the message as text, or a placeholder with its size for
+// binary messages.
+func (f WebSocketFrame) Text() string {
+	if f.Binary {
+		return fmt.Sprintf("(binary, %s)", FormatSize(int64(len(f.Data))))
+	}
+	return string(f.Data)
+}
+
+// WebSocketSession is an open WebSocket connection. Messages received from
+// the server are recorded as they arrive until the connection closes.
+type WebSocketSession struct {
+	// Result is the result of the handshake, with its 101 response.
+	Result *ExecutionResult
+
+	e           *Executor
+	req         *parser.Request
+	p           *prepared
+	conn        *websocket.Conn
+	ctx         context.Context
+	cancel      context.CancelFunc
+	readerDone  chan struct{}
+	closeMu     sync.Mutex
+	mu          sync.Mutex
+	frames      []WebSocketFrame
+	received    int
+	changed     chan struct{}
+	closed      bool
+	err         error
+	closeResult *ExecutionResult
+}
+
+// webSocketCloseWait is how long closing a connection waits for the server
+// to acknowledge the close frame.
+const webSocketCloseWait = time.Second
+
+// webSocketHeaders are headers the handshake sets itself; requests must not
+// set them.
+var webSocketHeaders = []string{"Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions"}
+
+// OpenWebSocket connects to the URL of a WEBSOCKET request and sends the
+// messages of its body in the background, leaving the connection open.
+// When the connection cannot be opened, the session is nil and the result
+// reports why. ctx bounds opening the connection, not the session.
+func (e *Executor) OpenWebSocket(ctx context.Context, req *parser.Request) (*WebSocketSession, *ExecutionResult) {
+	e.running.Lock()
+	defer e.running.Unlock()
+	p, failed := e.prepare(ctx, req)
+	if failed != nil {
+		cancelled(ctx, req, failed)
+		return nil, failed
+	}
+	s, result := e.dialWebSocket(ctx, req, p)
+	if s == nil {
+		cancelled(ctx, req, result)
+		return nil, result
+	}
+	go s.run(s.ctx, parser.WebSocketSteps(p.resolved.Body))
+	return s, result
+}
+
+// executeWebSocket runs a WEBSOCKET request once it is resolved: it
+// connects, sends the messages of its body, waiting for the server where
+// they say so, and closes the connection. The body of the response is a
+// JSON array of the messages received.
+func (e *Executor) executeWebSocket(ctx context.Context, req *parser.Request, p *prepared) *ExecutionResult {
+	ctx, cancel := context.WithTimeout(ctx, p.settings.Timeout)
+	defer cancel()
+
+	s, result := e.dialWebSocket(ctx, req, p)
+	if s == nil {
+		return result
+	}
+	err := s.run(ctx, parser.WebSocketSteps(p.resolved.Body))
+	result = s.close()
+	if err != nil && result.Error == nil {
+		result.Error = NewExecutionError(req.ID, failure(err, p.settings, "WebSocket session failed"), err)
+		result.Success = false
+	}
+	return result
+}
+
+// dialWebSocket performs the handshake of a WEBSOCKET request. It returns
+// a nil session and the failed result when the server does not accept it.
+func (e *Executor) dialWebSocket(ctx context.Context, req *parser.Request, p *prepared) (*WebSocketSession, *ExecutionResult) {
+	resolved, settings := p.resolved, p.settings
+	fail := func(message string, err error, response *Response) *ExecutionResult {
+		return &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Response: response,
+			Tokens:   e.tokenStatuses(req),
+			Logs:     p.logs,
+			Error:    NewExecutionError(req.ID, message, err),
+			Success:  false,
+		}
+	}
+
+	if p.creds != nil {
+		return nil, fail("invalid Authorization header", fmt.Errorf("%s authentication is not supported for WebSocket connections", p.creds.Scheme), nil)
+	}
+	u, err := url.Parse(resolved.URL)
+	if err != nil {
+		return nil, fail("invalid WebSocket URL", err, nil)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fail("invalid WebSocket URL", fmt.Errorf("unsupported scheme %q, expected ws or wss", u.Scheme), nil)
+	}
+
+	header := http.Header{}
+	for _, h := range resolved.Headers {
+		if isWebSocketHeader(h.Key) {
+			continue
+		}
+		header.Add(h.Key, h.Value)
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            proxyFunc(settings),
+		HandshakeTimeout: settings.Timeout,
+	}
+	if settings.ConnectionTimeout > 0 {
+		dialer.HandshakeTimeout = settings.ConnectionTimeout
+		dialer.NetDialContext = (&net.Dialer{Timeout: settings.ConnectionTimeout}).DialContext
+	}
+	if u.Scheme == "wss" {
+		dialer.TLSClientConfig, err = e.tlsClientConfig(matchTLS(e.tls, u.Hostname(), u.Port()), settings.Insecure)
+		if err != nil {
+			return nil, fail("WebSocket connection failed", err, nil)
+		}
+	}
+	if e.jar != nil && !req.NoCookieJar {
+		dialer.Jar = e.jar
+	}
+
+	// The dialer only gives up the handshake at ctx's deadline, so the
+	// connection is closed when ctx is cancelled before it completes.
+	netDial := dialer.NetDialContext
+	if netDial == nil {
+		netDial = (&net.Dialer{}).DialContext
+	}
+	stop := func() bool { return false }
+	dialer.NetDialContext = func(dialCtx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := netDial(dialCtx, network, addr)
+		if err == nil {
+			stop = context.AfterFunc(ctx, func() { conn.Close() })
+		}
+		return conn, err
+	}
+
+	startTime := time.Now()
+	conn, httpResp, err := dialer.DialContext(ctx, resolved.URL, header)
+	stop()
+	var response *Response
+	if httpResp != nil {
+		body, _ := io.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		response = &Response{
+			StatusCode:  httpResp.StatusCode,
+			Status:      httpResp.Status,
+			Headers:     httpResp.Header,
+			Body:        body,
+			ContentType: httpResp.Header.Get("Content-Type"),
+			Duration:    time.Since(startTime),
+			RequestTime: startTime,
+			Size:        int64(len(body)),
+		}
+	}
+	if errors.Is(err, websocket.ErrBadHandshake) && response != nil {
+		return nil, fail("WebSocket handshake failed", fmt.Errorf("server responded %s", response.Status), response)
+	}
+	if err != nil {
+		return nil, fail(failure(err, settings, "WebSocket connection failed"), err, response)
+	}
+
+	sessionCtx, cancel := context.WithCancel(context.Background())
+	s := &WebSocketSession{
+		Result: &ExecutionResult{
+			Request:  req,
+			Resolved: resolved,
+			Response: response,
+			Tokens:   e.tokenStatuses(req),
+			Logs:     p.logs,
+			Success:  true,
+		},
+		e:          e,
+		req:        req,
+		p:          p,
+		conn:       conn,
+		ctx:        sessionCtx,
+		cancel:     cancel,
+		readerDone: make(chan struct{}),
+		changed:    make(chan struct{}),
+	}
+	go s.read()
+	return s, s.Result
+}
+
+func isWebSocketHeader(key string) bool {
+	for _, h := range webSocketHeaders {
+		if strings.EqualFold(h, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// read records the messages from the server until the connection closes.
+func (s *WebSocketSession) read() {
+	defer close(s.readerDone)
+	for {
+		kind, data, err := s.conn.ReadMessage()
+		if err != nil {
+			s.mu.Lock()
+			if !s.closed && !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				s.err = err
+			}
+			s.closed = true
+			s.notify()
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Lock()
+		s.frames = append(s.frames, WebSocketFrame{Time: time.Now(), Data: data, Binary: kind == websocket.BinaryMessage})
+		s.received++
+		s.notify()
+		s.mu.Unlock()
+	}
+}
+
+// notify wakes those waiting on Changed. s.mu must be held.
+func (s *WebSocketSession) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// run sends the messages of steps, each once the server has sent as many
+// messages as it waits for since the previous one was sent.
+func (s *WebSocketSession) run(ctx context.Context, steps []parser.WebSocketStep) error {
+	s.mu.Lock()
+	mark := s.received
+	s.mu.Unlock()
+
+	for _, step := range steps {
+		if err := s.waitFor(ctx, mark+step.Wait); err != nil {
+			return err
+		}
+		if !step.Send {
+			continue
+		}
+		s.mu.Lock()
+		mark = s.received
+		s.mu.Unlock()
+		if err := s.Send(step.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitFor waits until n messages have been received.
+func (s *WebSocketSession) waitFor(ctx context.Context, n int) error {
+	for {
+		s.mu.Lock()
+		received, closed, err, changed := s.received, s.closed, s.err, s.changed
+		s.mu.Unlock()
+		if received >= n {
+			return nil
+		}
+		if closed {
+			if err != nil {
+				return err
+			}
+			return errors.New("the server closed the connection while waiting for a message")
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.Canceled) {
+				return ctx.Err()
+			}
+			return fmt.Errorf("waiting for a message from the server: %w", ctx.Err())
+		}
+	}
+}
+
+// Send sends a text message.
+func (s *WebSocketSession) Send(message string) error {
+	// The lock is held while writing so that replies are recorded after
+	// the message.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errors.New("the connection is closed")
+	}
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		return err
+	}
+	s.frames = append(s.frames, WebSocketFrame{Time: time.Now(), Sent: true, Data: []byte(message)})
+	s.notify()
+	return nil
+}
+
+// Frames returns the messages sent and received so far, in order.
+func (s *WebSocketSession) Frames() []WebSocketFrame {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]WebSocketFrame(nil), s.frames...)
+}
+
+// Changed returns a channel that is closed when a message is sent or
+// received, or the connection closes.
+func (s *WebSocketSession) Changed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.changed
+}
+
+// Closed reports whether the connection is closed, and the error that
+// closed it unless it closed normally.
+func (s *WebSocketSession) Closed() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed, s.err
+}
+
+// Close closes the connection and returns the result of the session: the
+// handshake response with the messages received as its body, checked
+// against the request's handlers and assertions. Results of named requests
+// are kept like those of Execute. Closing again returns the same result.
+func (s *WebSocketSession) Close() *ExecutionResult {
+	s.e.running.Lock()
+	defer s.e.running.Unlock()
+	return s.close()
+}
+
+// close closes the session like Close, with the executor's running lock
+// held.
+func (s *WebSocketSession) close() *ExecutionResult {
+	s.closeMu.Lock()
+	defer s.closeMu.Unlock()
+	if s.closeResult != nil {
+		return s.closeResult
+	}
+
+	s.cancel()
+	s.mu.Lock()
+	closed := s.closed
+	s.closed = true
+	s.mu.Unlock()
+	if !closed {
+		message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		s.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(webSocketCloseWait))
+		select {
+		case <-s.readerDone:
+		case <-time.After(webSocketCloseWait):
+		}
+	}
+	s.conn.Close()
+	<-s.readerDone
+
+	result := s.Result
+	frames := s.Frames()
+	result.Frames = frames
+	if result.Response != nil {
+		body := webSocketBody(frames)
+		result.Response.Body = body
+		result.Response.Size = int64(len(body))
+		result.Response.ContentType = "application/json"
+		result.Response.Duration = time.Since(result.Response.RequestTime)
+	}
+	if _, err := s.Closed(); err != nil {
+		result.Error = NewExecutionError(s.req.ID, "WebSocket connection failed", err)
+		result.Success = false
+	}
+
+	s.closeResult = s.e.check(context.Background(), s.req, result, s.p.requestVars)
+	if s.req.Name != "" {
+		s.e.setResult(s.req.Name, s.closeResult)
+	}
+	return s.closeResult
+}
+
+// webSocketBody returns the messages received as a JSON array: messages
+// that are JSON as they are, other text messages as strings and binary
+// messages as base64 strings.
+func webSocketBody(frames []WebSocketFrame) []byte {
+	messages := []json.RawMessage{}
+	for _, f := range frames {
+		if f.Sent {
+			continue
+		}
+		var message json.RawMessage
+		switch {
+		case f.Binary:
+			message, _ = json.Marshal(base64.StdEncoding.EncodeToString(f.Data))
+		case json.Valid(f.Data):
+			message = f.Data
+		default:
+			message, _ = json.Marshal(string(f.Data))
+		}
+		messages = append(messages, message)
+	}
+	body, _ := json.MarshalIndent(messages, "", "  ")
+	return body
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xmlNode is a minimal element tree used to evaluate simple XPath
+// expressions against XML response bodies.
+type xmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// innerText returns the concatenated character data of the node and all of
+// its descendants.
+func (n *xmlNode) innerText() string {
+	var sb strings.Builder
+	sb.WriteString(n.text.String())
+	for _, c := range n.children {
+		sb.WriteString(c.innerText())
+	}
+	return sb.String()
+}
+
+func parseXML(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+
+	root := &xmlNode{}
+	stack := []*xmlNode{root}
+
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				node.attrs[a.Name.Local] = a.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			stack[len(stack)-1].text.Write(t)
+		}
+	}
+
+	return root, nil
+}
+
+// queryXPath evaluates an absolute XPath expression such as
+// /root/user[2]/name, //user[@id='7']/name, //user/@id or //name/text() and
+// returns the string values of the matches.
+func queryXPath(data []byte, expr string) ([]string, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XML: %w", err)
+	}
+
+	if !strings.HasPrefix(expr, "/") {
+		return nil, fmt.Errorf("XPath must be absolute: %s", expr)
+	}
+
+	nodes := []*xmlNode{root}
+	rest := expr
+	for rest != "" {
+		descendant := strings.HasPrefix(rest, "//")
+		rest = strings.TrimLeft(rest, "/")
+
+		var stepExpr string
+		stepExpr, rest = splitXPathStep(rest)
+
+		name, predicate := stepExpr, ""
+		if i := strings.Index(stepExpr, "["); i >= 0 && strings.HasSuffix(stepExpr, "]") {
+			name, predicate = stepExpr[:i], stepExpr[i+1:len(stepExpr)-1]
+		}
+
+		if strings.HasPrefix(name, "@") || name == "text()" {
+			if rest != "" {
+				return nil, fmt.Errorf("%s must be the last step: %s", name, expr)
+			}
+			var values []string
+			for _, n := range nodes {
+				candidates := []*xmlNode{n}
+				if descendant {
+					candidates = descendants(n)
+				}
+				for _, c := range candidates {
+					if name == "text()" {
+						values = append(values, c.text.String())
+					} else if v, ok := c.attrs[name[1:]]; ok {
+						values = append(values, v)
+					}
+				}
+			}
+			return values, nil
+		}
+
+		var next []*xmlNode
+		for _, n := range nodes {
+			var candidates []*xmlNode
+			if descendant {
+				candidates = descendants(n)[1:]
+			} else {
+				candidates = n.children
+			}
+
+			var matched []*xmlNode
+			for _, c := range candidates {
+				if name == "*" || c.name == name {
+					matched = append(matched, c)
+				}
+			}
+
+			filtered, err := filterXPath(matched, predicate)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, filtered...)
+		}
+		nodes = next
+	}
+
+	values := make([]string, len(nodes))
+	for i, n := range nodes {
+		values[i] = n.innerText()
+	}
+	return values, nil
+}
+
+func splitXPathStep(s string) (string, string) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '/':
+			if depth == 0 {
+				return s[:i], s[i:]
+			}
+		}
+	}
+	return s, ""
+}
+
+func descendants(n *xmlNode) []*xmlNode {
+	out := []*xmlNode{n}
+	for _, c := range n.children {
+		out = append(out, descendants(c)...)
+	}
+	return out
+}
+
+// filterXPath applies a positional ([2], [last()]) or attribute
+// ([@id='7'], [@id]) predicate.
+func filterXPath(nodes []*xmlNode, predicate string) ([]*xmlNode, error) {
+	predicate = strings.TrimSpace(predicate)
+	if predicate == "" {
+		return nodes, nil
+	}
+
+	if predicate == "last()" {
+		if len(nodes) == 0 {
+			return nil, nil
+		}
+		return nodes[len(nodes)-1:], nil
+	}
+
+	if idx, err := strconv.Atoi(predicate); err == nil {
+		if idx < 1 || idx > len(nodes) {
+			return nil, nil
+		}
+		return nodes[idx-1 : idx], nil
+	}
+
+	if strings.HasPrefix(predicate, "@") {
+		attr, want, hasValue := strings.Cut(predicate[1:], "=")
+		attr = strings.TrimSpace(attr)
+		want = strings.Trim(strings.TrimSpace(want), `'"`)
+
+		var out []*xmlNode
+		for _, n := range nodes {
+			v, ok := n.attrs[attr]
+			if ok && (!hasValue || v == want) {
+				out = append(out, n)
+			}
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported XPath predicate: [%s]", predicate)
+}
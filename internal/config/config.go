@@ -4,31 +4,244 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+const (
+	CommandTUI      = ""
+	CommandRun      = "run"
+	CommandImport   = "import"
+	CommandExport   = "export"
+	CommandGenerate = "generate"
+	CommandHistory  = "history"
+	CommandDiff     = "diff"
+	CommandSnapshot = "snapshot"
+	CommandGraphQL  = "graphql"
+)
+
+// Formats for import and export.
+const (
+	FormatCurl    = "curl"
+	FormatPostman = "postman"
 )
 
 type Config struct {
+	Command     string
 	FilePath    string
+	EnvFile     string
+	Environment string
 	NoHeaders   bool
+	AllHeaders  bool
 	ShowHelp    bool
 	ShowVersion bool
+	NoHistory   bool
+	// SecretHeaders are masked in the history, besides the defaults.
+	SecretHeaders []string
+
+	// Request settings; zero values use the executor's defaults
+	Timeout           time.Duration
+	ConnectionTimeout time.Duration
+	MaxRedirects      int
+	NoRedirect        bool
+	Insecure          bool
+	Proxy             string
+	NoProxy           string
+
+	// Headless run options
+	Selectors    []string
+	ExpectStatus string
+	NoBody       bool
+	FailFast     bool
+	Report       string
+	ReportFile   string
+
+	// Import and export options
+	Format      string
+	Output      string
+	RequestName string
+	Args        []string
+	EnvImports  []string
+	SpecFile    string
+
+	// Snapshot options
+	UpdateSnapshots bool
+	IgnorePaths     []string
+	IgnoreHeaders   []string
+
+	// History options
+	HistoryShow  int
+	HistoryLimit int
+	HistoryClear bool
+
+	// GraphQL options
+	GraphQLNames bool
 }
 
 var version = "dev"
 
+// stringList is a repeatable flag that also accepts comma-separated values.
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// selectorList is a repeatable request selector flag that also accepts
+// comma-separated selectors. Commas inside a /regex/ do not separate.
+type selectorList []string
+
+func (s *selectorList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *selectorList) Set(value string) error {
+	for value != "" {
+		value = strings.TrimLeft(value, " \t")
+		var sel string
+		if strings.HasPrefix(value, "/") {
+			// A regex runs to a slash followed by a comma or the end.
+			end := len(value) - 1
+			for i := 1; i < len(value); i++ {
+				rest := strings.TrimLeft(value[i+1:], " \t")
+				if value[i] == '/' && (rest == "" || rest[0] == ',') {
+					end = i
+					break
+				}
+			}
+			sel, value = value[:end+1], strings.TrimPrefix(strings.TrimLeft(value[end+1:], " \t"), ",")
+		} else {
+			sel, value, _ = strings.Cut(value, ",")
+		}
+		if sel = strings.TrimSpace(sel); sel != "" {
+			*s = append(*s, sel)
+		}
+	}
+	return nil
+}
+
 func Parse() (*Config, error) {
+	args := os.Args[1:]
 	cfg := &Config{}
 
-	flag.BoolVar(&cfg.NoHeaders, "no-headers", false, "Hide response headers")
-	flag.BoolVar(&cfg.ShowHelp, "help", false, "Show help message")
-	flag.BoolVar(&cfg.ShowHelp, "h", false, "Show help message (shorthand)")
-	flag.BoolVar(&cfg.ShowVersion, "version", false, "Show version")
-	flag.BoolVar(&cfg.ShowVersion, "v", false, "Show version (shorthand)")
+	if len(args) > 0 {
+		switch args[0] {
+		case CommandRun, CommandGenerate, CommandHistory, CommandDiff, CommandSnapshot, CommandGraphQL:
+			cfg.Command = args[0]
+			args = args[1:]
+		case CommandImport, CommandExport:
+			cfg.Command = args[0]
+			if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+				return nil, fmt.Errorf("missing format: httpyum %s curl", args[0])
+			}
+			cfg.Format = args[1]
+			supported := []string{FormatCurl}
+			if cfg.Command == CommandImport {
+				supported = append(supported, FormatPostman)
+			}
+			if !slices.Contains(supported, cfg.Format) {
+				return nil, fmt.Errorf("unknown %s format %q (supported: %s)", args[0], cfg.Format, strings.Join(supported, ", "))
+			}
+			args = args[2:]
+		}
+	}
+
+	fs := flag.NewFlagSet("httpyum", flag.ExitOnError)
+	fs.BoolVar(&cfg.NoHeaders, "no-headers", false, "Hide response headers")
+	fs.StringVar(&cfg.EnvFile, "env-file", "", "Load {{$dotenv}} values from this file")
+	fs.StringVar(&cfg.Environment, "env", "", "Named environment from http-client.env.json")
+	fs.BoolVar(&cfg.ShowHelp, "help", false, "Show help message")
+	fs.BoolVar(&cfg.ShowHelp, "h", false, "Show help message (shorthand)")
+	fs.BoolVar(&cfg.ShowVersion, "version", false, "Show version")
+	fs.BoolVar(&cfg.ShowVersion, "v", false, "Show version (shorthand)")
+	fs.BoolVar(&cfg.NoHistory, "no-history", false, "Do not record requests in the history")
+	fs.Var((*stringList)(&cfg.SecretHeaders), "secret-header", "Header whose value is masked in the history (repeatable)")
+
+	if cfg.Command == CommandTUI || cfg.Command == CommandRun || cfg.Command == CommandSnapshot || cfg.Command == CommandDiff || cfg.Command == CommandGraphQL {
+		fs.DurationVar(&cfg.Timeout, "timeout", 0, "Timeout of each request (default 30s)")
+		fs.DurationVar(&cfg.ConnectionTimeout, "connection-timeout", 0, "Timeout for establishing connections")
+		fs.IntVar(&cfg.MaxRedirects, "max-redirects", 10, "Redirects to follow (0 to follow none)")
+		fs.BoolVar(&cfg.NoRedirect, "no-redirect", false, "Do not follow redirects")
+		fs.BoolVar(&cfg.Insecure, "insecure", false, "Skip TLS certificate verification")
+		fs.BoolVar(&cfg.Insecure, "k", false, "Skip TLS certificate verification (shorthand)")
+		fs.StringVar(&cfg.Proxy, "proxy", "", "Proxy URL (http, https, socks5 or socks5h)")
+		fs.StringVar(&cfg.NoProxy, "no-proxy", "", "Hosts that bypass the proxy, in NO_PROXY syntax")
+	}
+
+	if cfg.Command == CommandRun || cfg.Command == CommandExport || cfg.Command == CommandSnapshot {
+		fs.Var((*selectorList)(&cfg.Selectors), "request", "Request to run (index, description or /regex/)")
+		fs.Var((*selectorList)(&cfg.Selectors), "r", "Request to run (shorthand)")
+	}
 
-	flag.Usage = func() {
+	if cfg.Command == CommandImport {
+		fs.StringVar(&cfg.Output, "output", "", "Append the imported requests to this .http file or directory")
+		fs.StringVar(&cfg.Output, "o", "", "Append the imported requests to this .http file or directory (shorthand)")
+		if cfg.Format == FormatCurl {
+			fs.StringVar(&cfg.RequestName, "name", "", "Name the imported request with # @name")
+		} else {
+			fs.Var((*stringList)(&cfg.EnvImports), "environment", "Postman environment to import (repeatable)")
+		}
+	}
+
+	if cfg.Command == CommandGenerate {
+		fs.StringVar(&cfg.SpecFile, "from", "", "OpenAPI 3 or Swagger 2 spec to generate requests from")
+		fs.StringVar(&cfg.Output, "output", "", "Write the generated .http file here instead of stdout")
+		fs.StringVar(&cfg.Output, "o", "", "Write the generated .http file here (shorthand)")
+	}
+
+	if cfg.Command == CommandDiff {
+		fs.BoolVar(&cfg.AllHeaders, "all-headers", false, "Also compare headers that change on every response")
+	}
+
+	if cfg.Command == CommandRun {
+		fs.BoolVar(&cfg.NoBody, "no-body", false, "Hide response bodies")
+	}
+
+	if cfg.Command == CommandSnapshot {
+		fs.BoolVar(&cfg.UpdateSnapshots, "update", false, "Record new snapshots instead of comparing")
+		fs.BoolVar(&cfg.UpdateSnapshots, "u", false, "Record new snapshots instead of comparing (shorthand)")
+		fs.Var((*stringList)(&cfg.IgnorePaths), "ignore-path", "JSON path to exclude from snapshots (repeatable)")
+		fs.Var((*stringList)(&cfg.IgnoreHeaders), "ignore-header", "Header to exclude from snapshots (repeatable)")
+	}
+
+	if cfg.Command == CommandRun || cfg.Command == CommandSnapshot {
+		fs.StringVar(&cfg.ExpectStatus, "expect-status", "", "Accepted status codes, e.g. 2xx,301")
+		fs.BoolVar(&cfg.FailFast, "fail-fast", false, "Stop at the first failing request")
+		fs.StringVar(&cfg.Report, "report", "", "Write a test report: junit or tap")
+		fs.StringVar(&cfg.ReportFile, "report-file", "", "Write the report to this file instead of stdout")
+	}
+
+	if cfg.Command == CommandGraphQL {
+		fs.BoolVar(&cfg.GraphQLNames, "names", false, "Print only operation names, one per line")
+	}
+
+	if cfg.Command == CommandHistory {
+		fs.IntVar(&cfg.HistoryShow, "show", 0, "Print the entry with this number in full")
+		fs.IntVar(&cfg.HistoryLimit, "limit", 20, "Number of entries to list (0 for all)")
+		fs.BoolVar(&cfg.HistoryClear, "clear", false, "Delete the history of the project")
+	}
+
+	fs.Usage = func() {
 		printUsage()
 	}
 
-	flag.Parse()
+	positional, err := parseInterleaved(fs, args)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.ShowHelp {
 		printUsage()
 		os.Exit(0)
@@ -39,47 +252,247 @@ func Parse() (*Config, error) {
 		os.Exit(0)
 	}
 
-	args := flag.Args()
-	if len(args) < 1 {
+	if cfg.Command == CommandGenerate {
+		if cfg.SpecFile == "" && len(positional) == 1 {
+			cfg.SpecFile = positional[0]
+		}
+		if cfg.SpecFile == "" {
+			return nil, fmt.Errorf("missing required flag: --from <spec>\n\nUsage: httpyum generate --from <openapi.yaml> [-o file.http]")
+		}
+		if _, err := os.Stat(cfg.SpecFile); os.IsNotExist(err) {
+			return nil, fmt.Errorf("spec file not found: %s", cfg.SpecFile)
+		}
+		return cfg, nil
+	}
+
+	if cfg.Command == CommandImport {
+		cfg.Args = positional
+		if cfg.Format == FormatPostman {
+			if len(positional) != 1 {
+				return nil, fmt.Errorf("missing required argument: collection file\n\nUsage: httpyum import postman [OPTIONS] <collection.json>")
+			}
+			if len(cfg.EnvImports) > 0 && cfg.Output == "" {
+				return nil, fmt.Errorf("--environment requires -o to know where to write http-client.env.json")
+			}
+		}
+		return cfg, nil
+	}
+
+	if cfg.Command != CommandExport {
+		if cfg.Timeout < 0 || cfg.ConnectionTimeout < 0 {
+			return nil, fmt.Errorf("timeouts must be positive")
+		}
+		if cfg.MaxRedirects < 0 {
+			return nil, fmt.Errorf("--max-redirects must not be negative")
+		}
+		if cfg.MaxRedirects == 0 {
+			cfg.NoRedirect = true
+		}
+		if cfg.Proxy != "" {
+			if err := parser.ValidateProxy(cfg.Proxy); err != nil {
+				return nil, fmt.Errorf("--proxy: %w", err)
+			}
+		}
+	}
+
+	if len(positional) < 1 {
 		return nil, fmt.Errorf("missing required argument: file path\n\nUsage: httpyum [OPTIONS] <file.http>")
 	}
 
-	cfg.FilePath = args[0]
+	if cfg.Command == CommandDiff {
+		if len(positional) < 2 || len(positional) > 3 {
+			return nil, fmt.Errorf("diff takes one or two requests to compare\n\nUsage: httpyum diff [OPTIONS] <file.http> <request|@n> [request|@n]")
+		}
+		cfg.Args = positional[1:]
+	}
+
+	if cfg.Command == CommandGraphQL {
+		if len(positional) > 2 {
+			return nil, fmt.Errorf("graphql takes one request to introspect\n\nUsage: httpyum graphql [OPTIONS] <file.http> [request]")
+		}
+		cfg.Args = positional[1:]
+	}
+
+	cfg.FilePath = positional[0]
 	if _, err := os.Stat(cfg.FilePath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("file not found: %s", cfg.FilePath)
 	}
 
+	if cfg.ReportFile != "" && cfg.Report == "" {
+		cfg.Report = "junit"
+	}
+
+	if cfg.EnvFile != "" {
+		if _, err := os.Stat(cfg.EnvFile); os.IsNotExist(err) {
+			return nil, fmt.Errorf("env file not found: %s", cfg.EnvFile)
+		}
+	}
+
 	return cfg, nil
 }
 
+// parseInterleaved parses flags that may appear before or after positional
+// arguments, returning the positional arguments in order.
+func parseInterleaved(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `httpyum - Fast HTTP request runner for .http files
 
 Usage:
   httpyum [OPTIONS] <file.http>
+  httpyum run [OPTIONS] <file.http>
+  httpyum import curl [OPTIONS] ['curl ...' | -- curl ...]
+  httpyum import postman [OPTIONS] <collection.json>
+  httpyum export curl [OPTIONS] <file.http>
+  httpyum generate --from <spec> [-o file.http]
+  httpyum history [OPTIONS] <file.http>
+  httpyum diff [OPTIONS] <file.http> <request|@n> [request|@n]
+  httpyum snapshot [OPTIONS] <file.http>
+  httpyum graphql [OPTIONS] <file.http> [request]
 
 Arguments:
   <file.http>    Path to .http file containing HTTP requests
 
+Commands:
+  run            Execute requests without the TUI and print the results
+  import curl    Convert a curl command (argument or stdin) into a request
+  import postman Convert a Postman v2.1 collection into .http files
+  export curl    Print requests as curl commands with variables substituted
+  generate       Generate a .http file from an OpenAPI 3 or Swagger 2 spec
+  history        List or print past responses of the file's project
+  diff           Compare two responses: requests to run, or @n history entries
+  snapshot       Compare responses with recorded snapshots and report drift
+  graphql        List the queries and mutations of a GraphQL endpoint
+
 Options:
-  --no-headers   Hide response headers in output
-  -h, --help     Show this help message
-  -v, --version  Show version information
+  --no-headers        Hide response headers in output
+  --env-file <path>   Load {{$dotenv}} values from this file (overrides .env)
+  --env <name>        Use a named environment from http-client.env.json,
+                      http-client.private.env.json or .vscode/settings.json
+  --no-history        Do not record requests in the history
+  --secret-header <name>
+                      Mask this header's value in the history (repeatable);
+                      X-Api-Key and Api-Key are always masked
+  -h, --help          Show this help message
+  -v, --version       Show version information
+
+Request Options (TUI, run, snapshot, diff and graphql):
+  --timeout <duration>     Timeout of each request, e.g. 2m (default: 30s)
+  --connection-timeout <duration>
+                           Timeout for establishing connections
+  --max-redirects <n>      Redirects to follow (default: 10, 0 for none)
+  --no-redirect            Return redirect responses instead of following them
+  -k, --insecure           Skip TLS certificate verification
+  --proxy <url>            Send requests through this proxy: http://, https://,
+                           socks5:// or socks5h://, with user:pass@ if needed
+                           (default: HTTP_PROXY and HTTPS_PROXY)
+  --no-proxy <hosts>       Hosts that bypass the proxy, in NO_PROXY syntax
+                           (default: NO_PROXY)
+  Requests override these with # @timeout, # @connection-timeout,
+  # @max-redirects, # @no-redirect, # @insecure, # @proxy and # @no-proxy.
+
+Run Options:
+  -r, --request <sel>      Run only matching requests (repeatable); a selector is
+                           a 1-based index, a description or a /regex/
+  --expect-status <codes>  Accepted status codes, e.g. 2xx,301 (default: < 400)
+  --no-body                Hide response bodies
+  --fail-fast              Stop at the first failing request
+  --report <format>        Print a junit or tap report instead of plain output
+  --report-file <path>     Write the report to a file and keep plain output
+
+Snapshot Options:
+  -u, --update             Record snapshots again instead of comparing
+  --ignore-path <path>     JSON path to exclude, e.g. $.data[*].id (repeatable)
+  --ignore-header <name>   Header to exclude (repeatable); Date, ETag and other
+                           volatile headers are always excluded
+  Also accepts -r, --expect-status, --fail-fast, --report and --report-file.
+  Snapshots are stored in __snapshots__/<file>.snap.json next to the file.
+
+Import Options:
+  -o, --output <path>      Append requests to this .http file (default: stdout);
+                           for postman, a directory gets one file per folder
+  --name <name>            Name the curl request with # @name
+  --environment <file>     Postman environment to add to http-client.env.json
+
+Export Options:
+  -r, --request <sel>      Export only matching requests (repeatable)
+
+Generate Options:
+  --from <spec>            OpenAPI 3 or Swagger 2 spec (YAML or JSON)
+  -o, --output <path>      Write the .http file here (default: stdout)
+
+History Options:
+  --limit <n>              Number of entries to list (default: 20, 0 for all)
+  --show <n>               Print entry n of the list with headers and body
+  --clear                  Delete the history of the project
+
+Diff:
+  Each side is a request selector, which is run, or @n for entry n of
+  'httpyum history'. With one request, its response is compared with the
+  previous response in the history. Headers that change on every response,
+  such as Date and Set-Cookie, are skipped unless --all-headers is given;
+  --no-headers ignores header changes. Exits with 1 when the responses
+  differ.
+
+GraphQL:
+  Runs an introspection query against the endpoint of the request, with its
+  headers and authentication, and lists the queries, mutations and
+  subscriptions with their arguments. Without a request, the first GraphQL
+  request of the file is used.
+  --names                  Print only "query name" lines, for completion
 
 Examples:
   httpyum requests.http
   httpyum --no-headers api.http
+  httpyum --env-file secrets.env api.http
+  httpyum --env staging api.http
+  httpyum run api.http
+  httpyum run -r 2 -r "/users/" --expect-status 2xx api.http
+  httpyum run --report junit --report-file results.xml api.http
+  httpyum import curl -o api.http 'curl -X POST https://api.example.com/users -d @user.json'
+  pbpaste | httpyum import curl -o api.http
+  httpyum import postman -o requests/ --environment staging.json api.postman_collection.json
+  httpyum export curl --env staging -r 2 api.http
+  httpyum generate --from openapi.yaml -o api.http
+  httpyum snapshot --ignore-path '$..updatedAt' api.http
+  httpyum history --show 1 api.http
+  httpyum diff api.http "Get user"
+  httpyum diff --env staging api.http 2 @1
+  httpyum graphql --env staging api.http "List users"
 
 Keyboard Controls:
   List View:
     ↑/↓          Navigate requests
     /            Filter requests
     Enter        Execute selected request
+    e            Switch environment
+    c            Show request as curl command
+    H            Browse the request history
+    C            Browse, delete or clear stored cookies
+    x            Mark a request, then x on another to compare them
     q            Quit
 
   Response View:
     f            Open JSON in interactive viewer (jless/fx)
     h            Toggle headers visibility
+    D            Diff with the previous response to the request
     esc/b        Back to list
     q            Quit
 
@@ -95,6 +508,11 @@ Keyboard Controls:
     "body": "json"
   }
 
+  > assert status == 200
+  > assert body.$.id exists
+  > assert header Content-Type contains json
+  > assert duration < 500ms
+
 For more information, visit: https://github.com/aritra1999/httpyum
 `)
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectorListSet(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{[]string{"2"}, []string{"2"}},
+		{[]string{"1,3", "Get users"}, []string{"1", "3", "Get users"}},
+		{[]string{"/v{1,2}/"}, []string{"/v{1,2}/"}},
+		{[]string{"/a,b/,2"}, []string{"/a,b/", "2"}},
+		{[]string{"1, /users/ , /x,y/"}, []string{"1", "/users/", "/x,y/"}},
+		{[]string{"/unclosed,2"}, []string{"/unclosed,2"}},
+		{[]string{"a,,b,"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		var s selectorList
+		for _, v := range tt.values {
+			if err := s.Set(v); err != nil {
+				t.Fatalf("Set(%q): %v", v, err)
+			}
+		}
+		if !reflect.DeepEqual([]string(s), tt.want) {
+			t.Errorf("Set(%q) = %q, want %q", tt.values, s, tt.want)
+		}
+	}
+}
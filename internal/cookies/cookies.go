@@ -0,0 +1,337 @@
+// Package cookies provides a cookie jar that persists the cookies of each
+// project and environment, so sessions survive between requests and runs.
+package cookies
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultEnvironment names the jar used when no environment is selected.
+const defaultEnvironment = "default"
+
+// Store keeps the cookie jars of one project, one per environment.
+type Store struct {
+	dir  string
+	mu   sync.Mutex
+	jars map[string]*Jar
+}
+
+// Open returns the store for the project rooted at projectDir, under
+// stateDir/cookies.
+func Open(stateDir, projectDir string) (*Store, error) {
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := filepath.Base(abs) + "-" + hex.EncodeToString(sum[:])[:12]
+
+	dir := filepath.Join(stateDir, "cookies", name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, jars: make(map[string]*Jar)}, nil
+}
+
+// Jar returns the jar of the named environment; "" selects the jar used
+// without an environment. Cookies that cannot be read start the jar empty.
+// Session cookies saved by earlier versions are not loaded.
+func (s *Store) Jar(environment string) *Jar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if environment == "" {
+		environment = defaultEnvironment
+	}
+	if jar, ok := s.jars[environment]; ok {
+		return jar
+	}
+
+	jar := &Jar{path: filepath.Join(s.dir, fileName(environment))}
+	if data, err := os.ReadFile(jar.path); err == nil {
+		json.Unmarshal(data, &jar.cookies)
+		jar.cookies = slices.DeleteFunc(jar.cookies, (*Cookie).session)
+	}
+	s.jars[environment] = jar
+	return jar
+}
+
+// fileName maps an environment name to a file name that is safe on every
+// platform.
+func fileName(environment string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, environment) + ".json"
+}
+
+// Cookie is a stored cookie. Domain is the host it was set for, or the
+// domain it applies to along with its subdomains unless HostOnly is set.
+// Cookies without Expires are session cookies, which are kept only while
+// httpyum runs.
+type Cookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitzero"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"httpOnly,omitempty"`
+	HostOnly bool      `json:"hostOnly,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+func (c *Cookie) session() bool {
+	return c.Expires.IsZero()
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+func (c *Cookie) matches(host, path string, secure bool) bool {
+	if c.Secure && !secure {
+		return false
+	}
+	if c.HostOnly {
+		if host != c.Domain {
+			return false
+		}
+	} else if host != c.Domain && !strings.HasSuffix(host, "."+c.Domain) {
+		return false
+	}
+	return pathMatch(path, c.Path)
+}
+
+// Jar is an http.CookieJar that saves its persistent cookies after every
+// change.
+type Jar struct {
+	mu      sync.Mutex
+	path    string
+	cookies []*Cookie
+	// saveErr is why saving cookies received in a response failed.
+	saveErr error
+}
+
+// SetCookies stores the cookies received in a response from u, following
+// the RFC 6265 domain, path and expiry rules.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host, ok := canonicalHost(u)
+	if !ok {
+		return
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	changed := false
+	for _, hc := range cookies {
+		c := &Cookie{
+			Name:     hc.Name,
+			Value:    hc.Value,
+			Domain:   host,
+			Path:     hc.Path,
+			Secure:   hc.Secure,
+			HttpOnly: hc.HttpOnly,
+			HostOnly: true,
+			Created:  now,
+		}
+
+		if hc.Domain != "" {
+			domain := strings.ToLower(strings.TrimPrefix(hc.Domain, "."))
+			if host != domain && (net.ParseIP(host) != nil || !strings.HasSuffix(host, "."+domain)) {
+				continue
+			}
+			c.Domain, c.HostOnly = domain, false
+		}
+		if !strings.HasPrefix(c.Path, "/") {
+			c.Path = defaultPath(u.Path)
+		}
+
+		switch {
+		case hc.MaxAge < 0:
+			c.Expires = now
+		case hc.MaxAge > 0:
+			c.Expires = now.Add(time.Duration(hc.MaxAge) * time.Second)
+		case !hc.Expires.IsZero():
+			c.Expires = hc.Expires
+		}
+
+		j.remove(c.Domain, c.Path, c.Name)
+		if !c.expired(now) {
+			j.cookies = append(j.cookies, c)
+		}
+		changed = true
+	}
+
+	if changed {
+		if err := j.save(); err != nil {
+			j.saveErr = err
+		}
+	}
+}
+
+// SaveError returns why saving the cookies received in responses failed
+// since it was last called, or nil. SetCookies cannot return it itself.
+func (j *Jar) SaveError() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	err := j.saveErr
+	j.saveErr = nil
+	return err
+}
+
+// Cookies returns the cookies to send in a request to u, longest path first.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	host, ok := canonicalHost(u)
+	if !ok {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var selected []*Cookie
+	for _, c := range j.cookies {
+		if !c.expired(now) && c.matches(host, path, u.Scheme == "https" || u.Scheme == "wss") {
+			selected = append(selected, c)
+		}
+	}
+	sort.SliceStable(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].Created.Before(selected[b].Created)
+	})
+
+	cookies := make([]*http.Cookie, len(selected))
+	for i, c := range selected {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+	return cookies
+}
+
+// List returns the unexpired cookies sorted by domain, path and name.
+func (j *Jar) List() []Cookie {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var list []Cookie
+	for _, c := range j.cookies {
+		if !c.expired(now) {
+			list = append(list, *c)
+		}
+	}
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Domain != list[b].Domain {
+			return list[a].Domain < list[b].Domain
+		}
+		if list[a].Path != list[b].Path {
+			return list[a].Path < list[b].Path
+		}
+		return list[a].Name < list[b].Name
+	})
+	return list
+}
+
+// Delete removes the cookie with c's domain, path and name.
+func (j *Jar) Delete(c Cookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.remove(c.Domain, c.Path, c.Name)
+	return j.save()
+}
+
+// Clear removes every cookie.
+func (j *Jar) Clear() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.cookies = nil
+	return j.save()
+}
+
+func (j *Jar) remove(domain, path, name string) {
+	kept := j.cookies[:0]
+	for _, c := range j.cookies {
+		if c.Domain != domain || c.Path != path || c.Name != name {
+			kept = append(kept, c)
+		}
+	}
+	j.cookies = kept
+}
+
+// save drops expired cookies and writes those that are not session
+// cookies. Callers hold j.mu.
+func (j *Jar) save() error {
+	now := time.Now()
+	live := make([]*Cookie, 0, len(j.cookies))
+	persistent := make([]*Cookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if c.expired(now) {
+			continue
+		}
+		live = append(live, c)
+		if !c.session() {
+			persistent = append(persistent, c)
+		}
+	}
+	j.cookies = live
+
+	data, err := json.MarshalIndent(persistent, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+func canonicalHost(u *url.URL) (string, bool) {
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	return host, host != ""
+}
+
+// defaultPath is the directory of the request path (RFC 6265 section 5.1.4).
+func defaultPath(path string) string {
+	i := strings.LastIndex(path, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return path[:i]
+}
+
+// pathMatch reports whether a request path is within a cookie path (RFC
+// 6265 section 5.1.4).
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
@@ -0,0 +1,57 @@
+package cookies
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestJarKeepsSessionCookiesInMemory(t *testing.T) {
+	store, err := Open(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("http://example.com/")
+	store.Jar("dev").SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "s"},
+		{Name: "remember", Value: "r", Expires: time.Now().Add(time.Hour)},
+	})
+
+	if got := len(store.Jar("dev").Cookies(u)); got != 2 {
+		t.Fatalf("jar sends %d cookies, want 2", got)
+	}
+
+	reopened := &Store{dir: store.dir, jars: make(map[string]*Jar)}
+	got := reopened.Jar("dev").Cookies(u)
+	if len(got) != 1 || got[0].Name != "remember" {
+		t.Fatalf("reloaded cookies = %v, want only remember", got)
+	}
+}
+
+func TestJarSaveError(t *testing.T) {
+	store, err := Open(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	jar := store.Jar("")
+	if err := os.RemoveAll(store.dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("http://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "id", Value: "1", Expires: time.Now().Add(time.Hour)}})
+	if err := jar.SaveError(); err == nil {
+		t.Fatal("SaveError() = nil, want the failed save")
+	}
+	if err := jar.SaveError(); err != nil {
+		t.Fatalf("second SaveError() = %v, want nil", err)
+	}
+	if got := len(jar.Cookies(u)); got != 1 {
+		t.Fatalf("jar sends %d cookies, want 1", got)
+	}
+}
@@ -0,0 +1,718 @@
+// Package curl converts between curl command lines and .http requests.
+package curl
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+// formBoundary separates the parts of multipart bodies built from -F.
+const formBoundary = "----HttpyumFormBoundary"
+
+// Import is a request parsed from a curl command, along with the options that
+// had no equivalent in the .http file.
+type Import struct {
+	Request  parser.Request
+	Warnings []string
+}
+
+var (
+	// valueOptions lists the options that take a value. Those not handled in
+	// ParseArgs have no .http equivalent and are skipped with a warning.
+	valueOptions = map[string]bool{
+		"-X": true, "--request": true,
+		"-H": true, "--header": true,
+		"-d": true, "--data": true, "--data-ascii": true, "--data-binary": true,
+		"--data-raw": true, "--data-urlencode": true, "--json": true,
+		"-u": true, "--user": true, "--aws-sigv4": true,
+		"-F": true, "--form": true, "--form-string": true,
+		"-A": true, "--user-agent": true,
+		"-e": true, "--referer": true,
+		"-b": true, "--cookie": true,
+		"-m": true, "--max-time": true, "--connect-timeout": true, "--max-redirs": true,
+		"-x": true, "--proxy": true, "-U": true, "--proxy-user": true, "--noproxy": true,
+		"--socks5": true, "--socks5-hostname": true, "-T": true, "--upload-file": true,
+		"--url": true,
+
+		"-o": true, "--output": true, "--cacert": true, "-E": true, "--cert": true,
+		"--key": true, "-w": true, "--write-out": true, "--retry": true,
+		"--resolve": true, "-c": true, "--cookie-jar": true,
+	}
+
+	// ignoredFlags only affect curl's own output or match what the executor
+	// already does (following redirects, decompressing responses).
+	ignoredFlags = map[string]bool{
+		"-s": true, "--silent": true, "-S": true, "--show-error": true,
+		"-L": true, "--location": true, "-v": true, "--verbose": true,
+		"-i": true, "--include": true, "-f": true, "--fail": true,
+		"-N": true, "--no-buffer": true, "-g": true, "--globoff": true,
+		"-#": true, "--progress-bar": true, "--compressed": true, "--basic": true,
+		"--http1.1": true, "--http2": true, "-O": true, "--remote-name": true,
+	}
+)
+
+// Parse converts a curl command line, as copied from documentation or
+// browser developer tools, into a request.
+func Parse(command string) (*Import, error) {
+	args, err := Split(command)
+	if err != nil {
+		return nil, err
+	}
+	return ParseArgs(args)
+}
+
+// ParseArgs converts already split curl arguments into a request. A leading
+// "curl" is optional.
+func ParseArgs(args []string) (*Import, error) {
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	imp := &Import{}
+	req := &imp.Request
+
+	var (
+		method    string
+		rawURL    string
+		data      []string
+		forms     []string
+		upload    string
+		user      string
+		proxyUser string
+		sigv4     string
+		getData   bool
+		head      bool
+		digest    bool
+	)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if rawURL != "" {
+				imp.Warnings = append(imp.Warnings, fmt.Sprintf("extra URL %s ignored", arg))
+				continue
+			}
+			rawURL = arg
+			continue
+		}
+
+		// Expand grouped short options such as -sSL or -XPOST.
+		name, value, hasValue := arg, "", false
+		if !strings.HasPrefix(arg, "--") && len(arg) > 2 {
+			rest := arg[1:]
+			for j := 0; j < len(rest); j++ {
+				short := "-" + rest[j:j+1]
+				if valueOptions[short] {
+					name = short
+					if j+1 < len(rest) {
+						value, hasValue = rest[j+1:], true
+					}
+					break
+				}
+				if j == len(rest)-1 {
+					name = short
+					break
+				}
+				applyFlag(imp, short, &getData, &head, &digest)
+			}
+		}
+
+		if !valueOptions[name] {
+			applyFlag(imp, name, &getData, &head, &digest)
+			continue
+		}
+
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("option %s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch name {
+		case "-X", "--request":
+			method = strings.ToUpper(value)
+		case "-H", "--header":
+			key, val, ok := strings.Cut(value, ":")
+			if !ok {
+				imp.Warnings = append(imp.Warnings, fmt.Sprintf("malformed header %q ignored", value))
+				continue
+			}
+			val = strings.TrimSpace(val)
+			if val == "" {
+				// "-H 'X-Foo:'" removes a header in curl; there is nothing to send.
+				continue
+			}
+			req.Headers = append(req.Headers, parser.Header{Key: strings.TrimSpace(key), Value: val})
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(value, "@") {
+				data = append(data, "< "+strings.TrimPrefix(value, "@"))
+				continue
+			}
+			data = append(data, value)
+		case "--data-raw":
+			data = append(data, value)
+		case "--data-urlencode":
+			data = append(data, urlencodeData(value))
+		case "--json":
+			data = append(data, value)
+			setDefaultHeader(req, "Content-Type", "application/json")
+			setDefaultHeader(req, "Accept", "application/json")
+		case "-u", "--user":
+			user = value
+		case "--aws-sigv4":
+			sigv4 = value
+		case "-F", "--form", "--form-string":
+			forms = append(forms, formPart(name, value))
+		case "-A", "--user-agent":
+			req.Headers = append(req.Headers, parser.Header{Key: "User-Agent", Value: value})
+		case "-e", "--referer":
+			req.Headers = append(req.Headers, parser.Header{Key: "Referer", Value: value})
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				imp.Warnings = append(imp.Warnings, fmt.Sprintf("cookie file %s ignored", value))
+				continue
+			}
+			req.Headers = append(req.Headers, parser.Header{Key: "Cookie", Value: value})
+		case "--url":
+			rawURL = value
+		case "-m", "--max-time":
+			req.Timeout = seconds(imp, name, value)
+		case "--connect-timeout":
+			req.ConnectionTimeout = seconds(imp, name, value)
+		case "-x", "--proxy":
+			req.Proxy = value
+		case "--socks5":
+			req.Proxy = "socks5://" + value
+		case "--socks5-hostname":
+			req.Proxy = "socks5h://" + value
+		case "-U", "--proxy-user":
+			proxyUser = value
+		case "--noproxy":
+			if value != "*" {
+				imp.Warnings = append(imp.Warnings, fmt.Sprintf("option %s %s ignored; only * has an equivalent (# @no-proxy)", name, value))
+				continue
+			}
+			req.NoProxy = true
+		case "-T", "--upload-file":
+			upload = value
+		case "--max-redirs":
+			n, err := strconv.Atoi(value)
+			switch {
+			case err != nil:
+				imp.Warnings = append(imp.Warnings, fmt.Sprintf("option %s %s ignored", name, value))
+			case n == 0:
+				req.NoRedirect = true
+			case n > 0:
+				req.MaxRedirects = n
+			}
+		default:
+			imp.Warnings = append(imp.Warnings, fmt.Sprintf("option %s %s ignored", name, value))
+		}
+	}
+
+	if rawURL == "" {
+		return nil, fmt.Errorf("no URL found in curl command")
+	}
+
+	if req.Proxy != "" {
+		req.Proxy = proxyURL(imp, req.Proxy, proxyUser)
+	}
+
+	if user != "" {
+		req.Headers = append(req.Headers, parser.Header{
+			Key:   "Authorization",
+			Value: authorization(imp, user, sigv4, digest),
+		})
+	}
+
+	switch {
+	case len(forms) > 0:
+		req.Headers = append(req.Headers, parser.Header{
+			Key:   "Content-Type",
+			Value: "multipart/form-data; boundary=" + formBoundary,
+		})
+		req.Body = strings.Join(forms, "\n") + "\n--" + formBoundary + "--"
+	case len(data) > 0 && getData:
+		sep := "?"
+		if strings.Contains(rawURL, "?") {
+			sep = "&"
+		}
+		rawURL += sep + strings.Join(data, "&")
+	case len(data) > 0:
+		setDefaultHeader(req, "Content-Type", "application/x-www-form-urlencoded")
+		req.Body = strings.Join(data, "&")
+	case upload != "":
+		req.Body = "< " + upload
+	}
+
+	if method == "" {
+		switch {
+		case upload != "" && req.Body == "< "+upload:
+			method = "PUT"
+		case head:
+			method = "HEAD"
+		case req.Body != "":
+			method = "POST"
+		default:
+			method = "GET"
+		}
+	}
+
+	req.Method = method
+	req.URL = rawURL
+	return imp, nil
+}
+
+func applyFlag(imp *Import, name string, getData, head, digest *bool) {
+	switch {
+	case name == "--digest":
+		*digest = true
+	case name == "-G" || name == "--get":
+		*getData = true
+	case name == "-I" || name == "--head":
+		*head = true
+	case name == "-k" || name == "--insecure":
+		imp.Request.Insecure = true
+	case ignoredFlags[name]:
+	default:
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("unknown option %s ignored", name))
+	}
+}
+
+// proxyURL returns the -x proxy with the -U credentials, if any, added to
+// it. Invalid proxies are kept as given with a warning.
+func proxyURL(imp *Import, proxy, user string) string {
+	if err := parser.ValidateProxy(proxy); err != nil {
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("proxy %s: %v", proxy, err))
+	}
+	if user == "" {
+		return proxy
+	}
+	raw := proxy
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return proxy
+	}
+	name, password, _ := strings.Cut(user, ":")
+	u.User = url.UserPassword(name, password)
+	return u.String()
+}
+
+// seconds converts the value of a curl timeout option, which may be
+// fractional, into a duration. Invalid values are ignored with a warning.
+func seconds(imp *Import, name, value string) time.Duration {
+	secs, err := strconv.ParseFloat(value, 64)
+	if err != nil || secs <= 0 {
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("option %s %s ignored", name, value))
+		return 0
+	}
+	return time.Duration(secs * float64(time.Second))
+}
+
+// authorization converts -u credentials into an Authorization header using
+// the executor's helpers: "Basic user:pass", "Digest user:pass" or, with
+// --aws-sigv4 "aws:amz:<region>:<service>", "AWS <accessKey> <secretKey>".
+func authorization(imp *Import, user, sigv4 string, digest bool) string {
+	if !strings.Contains(user, ":") {
+		imp.Warnings = append(imp.Warnings, fmt.Sprintf("no password given for user %s", user))
+		user += ":"
+	}
+
+	switch {
+	case sigv4 != "":
+		accessKey, secretKey, _ := strings.Cut(user, ":")
+		value := "AWS " + accessKey + " " + secretKey
+		provider := strings.Split(sigv4, ":")
+		if len(provider) > 2 && provider[2] != "" {
+			value += " region:" + provider[2]
+		}
+		if len(provider) > 3 && provider[3] != "" {
+			value += " service:" + provider[3]
+		}
+		return value
+	case digest:
+		return "Digest " + user
+	default:
+		return "Basic " + user
+	}
+}
+
+// urlencodeData applies curl's --data-urlencode rules: "content",
+// "=content" and "name=content" encode the content part.
+func urlencodeData(value string) string {
+	name, content, ok := strings.Cut(value, "=")
+	if !ok {
+		return url.QueryEscape(value)
+	}
+	if name == "" {
+		return url.QueryEscape(content)
+	}
+	return name + "=" + url.QueryEscape(content)
+}
+
+// formPart renders one -F field as a multipart part. "name=@path" uploads a
+// file and "name=<path" reads the value from a file; both become "< path"
+// body lines. Options after ";" set the part's type and filename.
+func formPart(option, value string) string {
+	name, content, _ := strings.Cut(value, "=")
+
+	var sb strings.Builder
+	sb.WriteString("--" + formBoundary + "\n")
+
+	if option == "--form-string" || (!strings.HasPrefix(content, "@") && !strings.HasPrefix(content, "<")) {
+		fmt.Fprintf(&sb, "Content-Disposition: form-data; name=%q\n\n%s", name, content)
+		return sb.String()
+	}
+
+	upload := strings.HasPrefix(content, "@")
+	fields := strings.Split(content[1:], ";")
+	path := fields[0]
+	filename := path[strings.LastIndex(path, "/")+1:]
+	contentType := ""
+	for _, f := range fields[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(f), "=")
+		switch k {
+		case "type":
+			contentType = v
+		case "filename":
+			filename = strings.Trim(v, `"`)
+		}
+	}
+
+	if upload {
+		fmt.Fprintf(&sb, "Content-Disposition: form-data; name=%q; filename=%q\n", name, filename)
+	} else {
+		fmt.Fprintf(&sb, "Content-Disposition: form-data; name=%q\n", name)
+	}
+	if contentType != "" {
+		fmt.Fprintf(&sb, "Content-Type: %s\n", contentType)
+	}
+	sb.WriteString("\n< " + path)
+	return sb.String()
+}
+
+func setDefaultHeader(req *parser.Request, key, value string) {
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return
+		}
+	}
+	req.Headers = append(req.Headers, parser.Header{Key: key, Value: value})
+}
+
+// Command renders req as a curl command line. Variables are substituted with
+// parser.SubstituteVariables first so the command can be pasted into a shell
+// as is. GraphQL requests are rendered as the JSON request they are sent as,
+// unless their query is read from a file, gRPC calls as grpcurl commands
+// and WebSocket requests as websocat commands.
+func Command(req *parser.Request, variables map[string]string) string {
+	return CommandFunc(req, func(s string) string {
+		return parser.SubstituteVariables(s, variables)
+	})
+}
+
+// CommandFunc renders req as a curl command line like Command, with its
+// variables substituted by subst.
+func CommandFunc(req *parser.Request, subst func(string) string) string {
+	if req.IsGRPC() {
+		return grpcurlCommand(req, subst)
+	}
+	if req.IsWebSocket() {
+		return websocatCommand(req, subst)
+	}
+
+	if req.IsGraphQL() && !hasFiles(req.Body) {
+		graphQL := *req
+		graphQL.Body = subst(req.Body)
+		if converted, err := parser.GraphQLHTTP(graphQL); err == nil {
+			req = &converted
+		}
+	}
+
+	method := strings.ToUpper(req.Method)
+	if method == "GRAPHQL" {
+		method = "POST"
+	}
+	body := strings.TrimRight(subst(req.Body), "\n")
+
+	first := "curl"
+	switch {
+	case method == "HEAD":
+		first += " --head"
+	case method == "GET" && body == "", method == "POST" && body != "":
+	default:
+		first += " -X " + method
+	}
+	for _, option := range settingOptions(req, subst) {
+		first += " " + option
+	}
+	parts := []string{first + " " + quote(subst(req.URL))}
+
+	var form []string
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, "Content-Type") {
+			form = formOptions(subst(h.Value), body)
+		}
+	}
+
+	for _, h := range req.Headers {
+		key, value := subst(h.Key), subst(h.Value)
+		if form != nil && strings.EqualFold(key, "Content-Type") {
+			// curl sets it, with its own boundary.
+			continue
+		}
+		if strings.EqualFold(key, "Authorization") {
+			if auth, _ := parser.ParseAuth(value); auth != nil {
+				parts = append(parts, authOptions(auth)...)
+				continue
+			}
+		}
+		parts = append(parts, "-H "+quote(key+": "+value))
+	}
+	switch {
+	case form != nil:
+		parts = append(parts, form...)
+	case body != "":
+		if bodyParts := parser.SplitBody(body); len(bodyParts) == 1 && bodyParts[0].Path != "" {
+			parts = append(parts, "--data-binary "+quote("@"+bodyParts[0].Path))
+		} else {
+			parts = append(parts, "--data-raw "+quote(body))
+		}
+	}
+
+	return strings.Join(parts, " \\\n  ")
+}
+
+// grpcurlCommand renders a GRPC request as a grpcurl command line.
+func grpcurlCommand(req *parser.Request, subst func(string) string) string {
+	target := subst(req.URL)
+	plaintext := true
+	if scheme, rest, ok := strings.Cut(target, "://"); ok {
+		plaintext = scheme != "grpcs" && scheme != "https"
+		target = rest
+	}
+	address, method, _ := strings.Cut(target, "/")
+
+	first := "grpcurl"
+	switch {
+	case plaintext:
+		first += " -plaintext"
+	case req.Insecure:
+		first += " -insecure"
+	}
+	if req.Timeout > 0 {
+		first += " -max-time " + formatSeconds(req.Timeout)
+	}
+	parts := []string{first}
+	for _, proto := range req.Protos {
+		parts = append(parts, "-proto "+quote(subst(proto)))
+	}
+	for _, h := range req.Headers {
+		key, value := subst(h.Key), subst(h.Value)
+		if strings.EqualFold(key, "Authorization") {
+			if auth, _ := parser.ParseAuth(value); auth != nil && auth.Scheme == parser.AuthBasic {
+				value = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.User+":"+auth.Password))
+			}
+		}
+		parts = append(parts, "-H "+quote(key+": "+value))
+	}
+	if body := strings.TrimSpace(subst(req.Body)); body != "" {
+		parts = append(parts, "-d "+quote(body))
+	}
+	parts = append(parts, quote(address)+" "+quote(method))
+	return strings.Join(parts, " \\\n  ")
+}
+
+// websocatCommand renders a WEBSOCKET request as a websocat command line.
+// websocat sends each line of its input as a message, so the messages of
+// the body are piped to it when they fit on one line each; waiting for the
+// server cannot be expressed.
+func websocatCommand(req *parser.Request, subst func(string) string) string {
+	var messages []string
+	for _, step := range parser.WebSocketSteps(subst(req.Body)) {
+		if !step.Send {
+			continue
+		}
+		if strings.Contains(step.Message, "\n") {
+			messages = nil
+			break
+		}
+		messages = append(messages, quote(step.Message))
+	}
+
+	first := "websocat"
+	if len(messages) > 0 {
+		first = "printf '%s\\n' " + strings.Join(messages, " ") + " | websocat"
+	}
+	if req.Insecure {
+		first += " -k"
+	}
+	parts := []string{first}
+	for _, h := range req.Headers {
+		key, value := subst(h.Key), subst(h.Value)
+		if strings.EqualFold(key, "Authorization") {
+			if auth, _ := parser.ParseAuth(value); auth != nil && auth.Scheme == parser.AuthBasic {
+				value = "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.User+":"+auth.Password))
+			}
+		}
+		parts = append(parts, "-H "+quote(key+": "+value))
+	}
+	parts = append(parts, quote(subst(req.URL)))
+	return strings.Join(parts, " \\\n  ")
+}
+
+// hasFiles reports whether body includes files with "< path" lines.
+func hasFiles(body string) bool {
+	for _, part := range parser.SplitBody(body) {
+		if part.Path != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formOptions renders a multipart/form-data body as -F options, the reverse
+// of formPart. It returns nil for other bodies and for parts it cannot
+// express, which are then sent as raw data.
+func formOptions(contentType, body string) []string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		return nil
+	}
+	boundary := params["boundary"]
+	if boundary == "" {
+		// The executor takes it from the first delimiter line.
+		first, _, _ := strings.Cut(strings.TrimLeft(body, "\r\n"), "\n")
+		boundary = strings.TrimPrefix(strings.TrimSpace(first), "--")
+	}
+	if boundary == "" {
+		return nil
+	}
+	delimiter := "--" + boundary
+
+	var options []string
+	sections := strings.Split(body, delimiter)
+	if len(sections) < 3 || strings.TrimSpace(sections[0]) != "" || !strings.HasPrefix(sections[len(sections)-1], "--") {
+		return nil
+	}
+	for _, section := range sections[1 : len(sections)-1] {
+		head, content, ok := strings.Cut(strings.TrimPrefix(strings.ReplaceAll(section, "\r\n", "\n"), "\n"), "\n\n")
+		if !ok {
+			return nil
+		}
+		content = strings.TrimSuffix(content, "\n")
+
+		var name, filename, partType string
+		for _, line := range strings.Split(head, "\n") {
+			key, value, _ := strings.Cut(line, ":")
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "content-disposition":
+				_, disposition, err := mime.ParseMediaType(strings.TrimSpace(value))
+				if err != nil {
+					return nil
+				}
+				name, filename = disposition["name"], disposition["filename"]
+			case "content-type":
+				partType = strings.TrimSpace(value)
+			}
+		}
+		if name == "" {
+			return nil
+		}
+
+		files := parser.SplitBody(content)
+		if len(files) != 1 || files[0].Path == "" {
+			if strings.HasPrefix(content, "@") || strings.HasPrefix(content, "<") {
+				options = append(options, "--form-string "+quote(name+"="+content))
+			} else {
+				options = append(options, "-F "+quote(name+"="+content))
+			}
+			continue
+		}
+
+		path := files[0].Path
+		value := name + "=<" + path
+		if filename != "" {
+			value = name + "=@" + path
+			if filename != path[strings.LastIndex(path, "/")+1:] {
+				value += ";filename=" + filename
+			}
+		}
+		if partType != "" {
+			value += ";type=" + partType
+		}
+		options = append(options, "-F "+quote(value))
+	}
+	return options
+}
+
+// settingOptions renders the request's settings annotations as curl
+// options. The executor follows redirects unless told not to, while curl
+// only does with -L, so "# @no-redirect" needs nothing.
+func settingOptions(req *parser.Request, subst func(string) string) []string {
+	var options []string
+	if req.Insecure {
+		options = append(options, "-k")
+	}
+	if req.Timeout > 0 {
+		options = append(options, "-m "+formatSeconds(req.Timeout))
+	}
+	if req.ConnectionTimeout > 0 {
+		options = append(options, "--connect-timeout "+formatSeconds(req.ConnectionTimeout))
+	}
+	if req.NoProxy {
+		options = append(options, "--noproxy '*'")
+	} else if req.Proxy != "" {
+		options = append(options, "-x "+quote(subst(req.Proxy)))
+	}
+	if !req.NoRedirect {
+		option := "-L"
+		if req.MaxRedirects > 0 {
+			option += " --max-redirs " + strconv.Itoa(req.MaxRedirects)
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// authOptions renders credentials written for the executor's auth helpers
+// as the equivalent curl options.
+func authOptions(auth *parser.Auth) []string {
+	user := "-u " + quote(auth.User+":"+auth.Password)
+	switch auth.Scheme {
+	case parser.AuthDigest:
+		return []string{"--digest " + user}
+	case parser.AuthAWS:
+		provider := "aws:amz"
+		if auth.Region != "" {
+			provider += ":" + auth.Region
+			if auth.Service != "" {
+				provider += ":" + auth.Service
+			}
+		}
+		parts := []string{"--aws-sigv4 " + quote(provider) + " " + user}
+		if auth.SessionToken != "" {
+			parts = append(parts, "-H "+quote("X-Amz-Security-Token: "+auth.SessionToken))
+		}
+		return parts
+	default:
+		return []string{user}
+	}
+}
@@ -0,0 +1,292 @@
+package curl
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"httpyum/internal/parser"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`curl https://x`, []string{"curl", "https://x"}},
+		{`curl -H 'A: b c' "d \"e\" \$f"`, []string{"curl", "-H", "A: b c", `d "e" $f`}},
+		{"curl \\\n  -X POST \\\r\n  url", []string{"curl", "-X", "POST", "url"}},
+		{`curl $'a\nb\x41\'' x\ y`, []string{"curl", "a\nbA'", "x y"}},
+		{`a''b ""`, []string{"ab", ""}},
+	}
+	for _, tt := range tests {
+		got, err := Split(tt.in)
+		if err != nil {
+			t.Errorf("Split(%q): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{`'open`, `"open`, `trailing\`} {
+		if _, err := Split(in); err == nil {
+			t.Errorf("Split(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestQuoteRoundTrip(t *testing.T) {
+	for _, s := range []string{"", "plain", "a b", "it's", `"$HOME" \ `, "line\nbreak", "{\"a\": [1, 2]}"} {
+		words, err := Split(quote(s))
+		if err != nil || len(words) != 1 || words[0] != s {
+			t.Errorf("Split(quote(%q)) = %q, %v", s, words, err)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		cmd      string
+		method   string
+		url      string
+		headers  []parser.Header
+		body     string
+		check    func(t *testing.T, req *parser.Request)
+		warnings int
+	}{
+		{
+			name:   "GET",
+			cmd:    `curl https://api.example.com/users`,
+			method: "GET", url: "https://api.example.com/users",
+		},
+		{
+			name:   "grouped short flags with a value",
+			cmd:    `curl -sSLXPOST -kH 'Accept: application/json' https://x`,
+			method: "POST", url: "https://x",
+			headers: []parser.Header{{Key: "Accept", Value: "application/json"}},
+			check: func(t *testing.T, req *parser.Request) {
+				if !req.Insecure {
+					t.Error("-k not applied")
+				}
+			},
+		},
+		{
+			name:   "data implies POST and form type",
+			cmd:    `curl -d 'a=1' --data 'b=2' https://x`,
+			method: "POST", url: "https://x",
+			headers: []parser.Header{{Key: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+			body:    "a=1&b=2",
+		},
+		{
+			name:   "data from a file",
+			cmd:    `curl --data-binary @payload.json -H 'Content-Type: application/json' https://x`,
+			method: "POST", url: "https://x",
+			headers: []parser.Header{{Key: "Content-Type", Value: "application/json"}},
+			body:    "< payload.json",
+		},
+		{
+			name:   "-G moves data to the query",
+			cmd:    `curl -G -d q=go --data-urlencode 'name=a b' 'https://x?p=1'`,
+			method: "GET", url: "https://x?p=1&q=go&name=a+b",
+		},
+		{
+			name:   "json",
+			cmd:    `curl --json '{"a":1}' https://x`,
+			method: "POST", url: "https://x",
+			headers: []parser.Header{
+				{Key: "Content-Type", Value: "application/json"},
+				{Key: "Accept", Value: "application/json"},
+			},
+			body: `{"a":1}`,
+		},
+		{
+			name:   "user and digest",
+			cmd:    `curl --digest -u 'me:p w' https://x`,
+			method: "GET", url: "https://x",
+			headers: []parser.Header{{Key: "Authorization", Value: "Digest me:p w"}},
+		},
+		{
+			name:   "aws sigv4",
+			cmd:    `curl --aws-sigv4 aws:amz:eu-west-1:s3 -u AK:SK https://x`,
+			method: "GET", url: "https://x",
+			headers: []parser.Header{{Key: "Authorization", Value: "AWS AK SK region:eu-west-1 service:s3"}},
+		},
+		{
+			name:   "upload file",
+			cmd:    `curl -T report.csv https://x`,
+			method: "PUT", url: "https://x",
+			body: "< report.csv",
+		},
+		{
+			name:   "HEAD",
+			cmd:    `curl -I https://x`,
+			method: "HEAD", url: "https://x",
+		},
+		{
+			name:   "settings",
+			cmd:    `curl -m 2.5 --connect-timeout 1 --max-redirs 0 -x proxy:8080 -U u:p https://x`,
+			method: "GET", url: "https://x",
+			check: func(t *testing.T, req *parser.Request) {
+				if req.Timeout != 2500*time.Millisecond || req.ConnectionTimeout != time.Second {
+					t.Errorf("timeouts = %v, %v", req.Timeout, req.ConnectionTimeout)
+				}
+				if !req.NoRedirect {
+					t.Error("--max-redirs 0 not applied")
+				}
+				if req.Proxy != "http://u:p@proxy:8080" {
+					t.Errorf("proxy = %q", req.Proxy)
+				}
+			},
+		},
+		{
+			name:   "unknown options warn",
+			cmd:    `curl --frobnicate -o out.txt https://x extra`,
+			method: "GET", url: "https://x",
+			warnings: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp, err := Parse(tt.cmd)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			req := &imp.Request
+			if req.Method != tt.method || req.URL != tt.url {
+				t.Errorf("request line = %s %s, want %s %s", req.Method, req.URL, tt.method, tt.url)
+			}
+			if len(req.Headers) != 0 || len(tt.headers) != 0 {
+				if !reflect.DeepEqual(req.Headers, tt.headers) {
+					t.Errorf("headers = %v, want %v", req.Headers, tt.headers)
+				}
+			}
+			if req.Body != tt.body {
+				t.Errorf("body = %q, want %q", req.Body, tt.body)
+			}
+			if len(imp.Warnings) != tt.warnings {
+				t.Errorf("warnings = %q, want %d", imp.Warnings, tt.warnings)
+			}
+			if tt.check != nil {
+				tt.check(t, req)
+			}
+		})
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	for _, cmd := range []string{`curl -s`, `curl https://x -H`} {
+		if _, err := Parse(cmd); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", cmd)
+		}
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	imp, err := Parse(`curl -F 'title=Q1 report' -F 'file=@data/q1.csv;type=text/csv' -F 'notes=<notes.txt' --form-string 'raw=@literal' https://x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := imp.Request
+	if req.Method != "POST" {
+		t.Errorf("method = %s", req.Method)
+	}
+	want := "--" + formBoundary + "\n" +
+		"Content-Disposition: form-data; name=\"title\"\n\nQ1 report\n" +
+		"--" + formBoundary + "\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"q1.csv\"\nContent-Type: text/csv\n\n< data/q1.csv\n" +
+		"--" + formBoundary + "\n" +
+		"Content-Disposition: form-data; name=\"notes\"\n\n< notes.txt\n" +
+		"--" + formBoundary + "\n" +
+		"Content-Disposition: form-data; name=\"raw\"\n\n@literal\n" +
+		"--" + formBoundary + "--"
+	if req.Body != want {
+		t.Errorf("body =\n%s\nwant\n%s", req.Body, want)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "GET follows redirects",
+			src:  "GET https://x/{{id}}\nAccept: */*\n",
+			want: "curl -L https://x/42 \\\n  -H 'Accept: */*'",
+		},
+		{
+			name: "no redirect",
+			src:  "# @no-redirect\nDELETE https://x\n",
+			want: "curl -X DELETE https://x",
+		},
+		{
+			name: "max redirects and settings",
+			src:  "# @max-redirects 3\n# @timeout 1500ms\n# @insecure\nGET https://x\n",
+			want: "curl -k -m 1.5 -L --max-redirs 3 https://x",
+		},
+		{
+			name: "body quoting",
+			src:  "POST https://x\nContent-Type: application/json\n\n{\"name\": \"it's\"}\n",
+			want: "curl -L https://x \\\n  -H 'Content-Type: application/json' \\\n  --data-raw '{\"name\": \"it'\\''s\"}'",
+		},
+		{
+			name: "body from a file",
+			src:  "POST https://x\n\n< ./payload.json\n",
+			want: "curl -L https://x \\\n  --data-binary @./payload.json",
+		},
+		{
+			name: "basic auth",
+			src:  "GET https://x\nAuthorization: Basic me secret\n",
+			want: "curl -L https://x \\\n  -u me:secret",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pf, err := parser.Parse(strings.NewReader(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Command(&pf.Requests[0], map[string]string{"id": "42"})
+			if got != tt.want {
+				t.Errorf("Command =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRoundTrip checks that rendering an imported curl command and
+// importing it again gives the same request.
+func TestRoundTrip(t *testing.T) {
+	commands := []string{
+		`curl https://x/users`,
+		`curl -X PUT -H 'A: it'\''s' -H 'B: "q" $x' --data-raw '{"a": "b c"}' https://x`,
+		`curl -sSLk -m 3 https://x`,
+		`curl --data-binary @body.json https://x`,
+		`curl -F 'title=a b' -F 'file=@dir/a.png;type=image/png' -F 'named=@a.txt;filename=b.txt' -F 'notes=<n.txt' https://x`,
+		`curl --digest -u 'u:p' https://x`,
+		`curl -x socks5://proxy:1080 --connect-timeout 2 https://x`,
+		`curl -I https://x`,
+	}
+	for _, cmd := range commands {
+		first, err := Parse(cmd)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", cmd, err)
+		}
+		rendered := Command(&first.Request, nil)
+		second, err := Parse(rendered)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", rendered, err)
+		}
+		if len(second.Warnings) > 0 {
+			t.Errorf("%s: warnings %q", rendered, second.Warnings)
+		}
+		if !reflect.DeepEqual(first.Request, second.Request) {
+			t.Errorf("round trip of %s through\n%s\ngave %+v, want %+v", cmd, rendered, second.Request, first.Request)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Package diff compares two HTTP responses: their status, headers and a
+// structural diff of JSON bodies, or a line diff of other bodies.
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"httpyum/internal/client"
+)
+
+// Kind describes how a value differs between the two responses.
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change is one difference. Path is "status", "header <Name>", a JSONPath
+// into the body such as $.items[0].id, or "line <n>" for text bodies. Old
+// and New hold the values from the first and second response.
+type Change struct {
+	Path string
+	Kind Kind
+	Old  string
+	New  string
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s → %s", c.Path, c.Old, c.New)
+	}
+}
+
+// Result holds the differences between two responses.
+type Result struct {
+	Status  *Change
+	Headers []Change
+	Body    []Change
+
+	// JSON reports whether the bodies were compared structurally.
+	JSON bool
+}
+
+// Equal reports whether no differences were found.
+func (r *Result) Equal() bool {
+	return r.Status == nil && len(r.Headers) == 0 && len(r.Body) == 0
+}
+
+// Changes returns every difference: status first, then headers, then body.
+func (r *Result) Changes() []Change {
+	var changes []Change
+	if r.Status != nil {
+		changes = append(changes, *r.Status)
+	}
+	changes = append(changes, r.Headers...)
+	return append(changes, r.Body...)
+}
+
+// VolatileHeaders change on every response, so Responses skips them unless
+// Options.AllHeaders is set.
+var VolatileHeaders = []string{
+	"Age",
+	"Content-Length",
+	"Date",
+	"Etag",
+	"Expires",
+	"Last-Modified",
+	"Server-Timing",
+	"Set-Cookie",
+	"X-Request-Id",
+}
+
+// IsVolatile reports whether the header name is one of VolatileHeaders.
+func IsVolatile(name string) bool {
+	for _, h := range VolatileHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures Responses.
+type Options struct {
+	// AllHeaders compares VolatileHeaders too.
+	AllHeaders bool
+}
+
+// Responses compares a (the older or left response) with b.
+func Responses(a, b *client.Response, opts Options) *Result {
+	r := &Result{}
+
+	if a.StatusCode != b.StatusCode {
+		r.Status = &Change{Path: "status", Kind: Changed, Old: a.Status, New: b.Status}
+	}
+
+	if opts.AllHeaders {
+		r.Headers = Headers(a.Headers, b.Headers)
+	} else {
+		r.Headers = Headers(stable(a.Headers), stable(b.Headers))
+	}
+
+	var av, bv any
+	switch {
+	case len(a.Body) == 0 && len(b.Body) > 0:
+		r.Body = []Change{{Path: "body", Kind: Added, New: client.FormatSize(int64(len(b.Body)))}}
+	case len(a.Body) > 0 && len(b.Body) == 0:
+		r.Body = []Change{{Path: "body", Kind: Removed, Old: client.FormatSize(int64(len(a.Body)))}}
+	case decodeJSON(a.Body, &av) && decodeJSON(b.Body, &bv):
+		r.JSON = true
+		r.Body = JSON(av, bv)
+	default:
+		r.Body = Lines(string(a.Body), string(b.Body))
+	}
+	return r
+}
+
+// stable returns headers without VolatileHeaders.
+func stable(headers http.Header) http.Header {
+	out := make(http.Header, len(headers))
+	for k, v := range headers {
+		if !IsVolatile(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Headers compares two header sets by canonical name. Multiple values are
+// joined with ", ".
+func Headers(a, b http.Header) []Change {
+	names := make(map[string]bool)
+	for k := range a {
+		names[http.CanonicalHeaderKey(k)] = true
+	}
+	for k := range b {
+		names[http.CanonicalHeaderKey(k)] = true
+	}
+
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+	for _, k := range keys {
+		av, aok := a[k]
+		bv, bok := b[k]
+		path := "header " + k
+		switch {
+		case !aok:
+			changes = append(changes, Change{Path: path, Kind: Added, New: strings.Join(bv, ", ")})
+		case !bok:
+			changes = append(changes, Change{Path: path, Kind: Removed, Old: strings.Join(av, ", ")})
+		case strings.Join(av, ", ") != strings.Join(bv, ", "):
+			changes = append(changes, Change{Path: path, Kind: Changed, Old: strings.Join(av, ", "), New: strings.Join(bv, ", ")})
+		}
+	}
+	return changes
+}
+
+// JSON compares two decoded JSON documents. Objects are compared key by key
+// and arrays index by index.
+func JSON(a, b any) []Change {
+	var changes []Change
+	compare("$", a, b, &changes)
+	return changes
+}
+
+func compare(path string, a, b any, changes *[]Change) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(av)+len(bv))
+		for k := range av {
+			keys = append(keys, k)
+		}
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := path + keyPath(k)
+			ac, aok := av[k]
+			bc, bok := bv[k]
+			switch {
+			case !aok:
+				*changes = append(*changes, Change{Path: child, Kind: Added, New: render(bc)})
+			case !bok:
+				*changes = append(*changes, Change{Path: child, Kind: Removed, Old: render(ac)})
+			default:
+				compare(child, ac, bc, changes)
+			}
+		}
+		return
+
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			child := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(av):
+				*changes = append(*changes, Change{Path: child, Kind: Added, New: render(bv[i])})
+			case i >= len(bv):
+				*changes = append(*changes, Change{Path: child, Kind: Removed, Old: render(av[i])})
+			default:
+				compare(child, av[i], bv[i], changes)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Kind: Changed, Old: render(a), New: render(b)})
+	}
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// keyPath formats an object key as a JSONPath step.
+func keyPath(key string) string {
+	if identifier.MatchString(key) {
+		return "." + key
+	}
+	return "['" + strings.ReplaceAll(key, "'", `\'`) + "']"
+}
+
+// render formats a JSON value compactly, shortening long values.
+func render(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	s := string(data)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+func decodeJSON(data []byte, v *any) bool {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v) == nil && !dec.More()
+}
+
+// maxLineCells bounds the work of the line diff; larger bodies are reported
+// as a single change.
+const maxLineCells = 4_000_000
+
+// Lines compares two texts line by line, reporting lines present in only
+// one of them. Line numbers refer to the text the line came from.
+func Lines(a, b string) []Change {
+	if a == b {
+		return nil
+	}
+	al := strings.Split(a, "\n")
+	bl := strings.Split(b, "\n")
+	if len(al)*len(bl) > maxLineCells {
+		return []Change{{
+			Path: "body",
+			Kind: Changed,
+			Old:  client.FormatSize(int64(len(a))),
+			New:  client.FormatSize(int64(len(b))),
+		}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of al[i:]
+	// and bl[j:].
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []Change
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			i++
+			j++
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, Change{Path: "line " + strconv.Itoa(i+1), Kind: Removed, Old: shorten(al[i])})
+			i++
+		default:
+			changes = append(changes, Change{Path: "line " + strconv.Itoa(j+1), Kind: Added, New: shorten(bl[j])})
+			j++
+		}
+	}
+	return changes
+}
+
+func shorten(s string) string {
+	if len(s) > 80 {
+		return s[:77] + "..."
+	}
+	return s
+}
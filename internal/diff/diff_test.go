@@ -0,0 +1,150 @@
+package diff
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"httpyum/internal/client"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return v
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Change
+	}{
+		{"equal", `{"a": [1, {"b": null}]}`, `{"a":[1,{"b":null}]}`, nil},
+		{
+			name: "changed, added and removed keys",
+			a:    `{"price": 10, "old": true, "name": "x"}`,
+			b:    `{"price": 12, "name": "x", "discount": 5}`,
+			want: []Change{
+				{Path: "$.discount", Kind: Added, New: "5"},
+				{Path: "$.old", Kind: Removed, Old: "true"},
+				{Path: "$.price", Kind: Changed, Old: "10", New: "12"},
+			},
+		},
+		{
+			name: "arrays by index",
+			a:    `{"items": [{"id": 1}, {"id": 2}]}`,
+			b:    `{"items": [{"id": 1}, {"id": 3}, {"id": 4}]}`,
+			want: []Change{
+				{Path: "$.items[1].id", Kind: Changed, Old: "2", New: "3"},
+				{Path: "$.items[2]", Kind: Added, New: `{"id":4}`},
+			},
+		},
+		{
+			name: "keys that are not identifiers",
+			a:    `{"a-b": 1, "it's": 1}`,
+			b:    `{"a-b": 2, "it's": 2}`,
+			want: []Change{
+				{Path: "$['a-b']", Kind: Changed, Old: "1", New: "2"},
+				{Path: `$['it\'s']`, Kind: Changed, Old: "1", New: "2"},
+			},
+		},
+		{
+			name: "type change",
+			a:    `{"a": {"b": 1}}`,
+			b:    `{"a": [1]}`,
+			want: []Change{{Path: "$.a", Kind: Changed, Old: `{"b":1}`, New: "[1]"}},
+		},
+		{
+			name: "numbers compare as written",
+			a:    `[1.0]`,
+			b:    `[1]`,
+			want: []Change{{Path: "$[0]", Kind: Changed, Old: "1.0", New: "1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := JSON(decode(t, tt.a), decode(t, tt.b))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("JSON =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	a := http.Header{
+		"Content-Type": {"application/json"},
+		"X-Old":        {"1"},
+		"Vary":         {"Accept", "Origin"},
+	}
+	b := http.Header{
+		"Content-Type": {"application/json"},
+		"X-New":        {"2"},
+		"Vary":         {"Accept"},
+	}
+	want := []Change{
+		{Path: "header Vary", Kind: Changed, Old: "Accept, Origin", New: "Accept"},
+		{Path: "header X-New", Kind: Added, New: "2"},
+		{Path: "header X-Old", Kind: Removed, Old: "1"},
+	}
+	if got := Headers(a, b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Headers =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestResponsesSkipsVolatileHeaders(t *testing.T) {
+	a := &client.Response{StatusCode: 200, Status: "200 OK", Body: []byte(`{"a":1}`), Headers: http.Header{
+		"Date":          {"Mon, 01 Jan 2024 00:00:00 GMT"},
+		"Set-Cookie":    {"s=1"},
+		"X-Request-Id":  {"a"},
+		"Server-Timing": {"db;dur=5"},
+		"Cache-Control": {"no-cache"},
+	}}
+	b := &client.Response{StatusCode: 200, Status: "200 OK", Body: []byte(`{"a":1}`), Headers: http.Header{
+		"Date":          {"Tue, 02 Jan 2024 00:00:00 GMT"},
+		"Set-Cookie":    {"s=2"},
+		"X-Request-Id":  {"b"},
+		"Server-Timing": {"db;dur=7"},
+		"Cache-Control": {"no-cache"},
+	}}
+
+	if r := Responses(a, b, Options{}); !r.Equal() {
+		t.Errorf("Responses reported %v, want no changes", r.Changes())
+	}
+	if r := Responses(a, b, Options{AllHeaders: true}); len(r.Headers) != 4 {
+		t.Errorf("Responses with AllHeaders reported %v, want 4 header changes", r.Headers)
+	}
+}
+
+func TestResponsesBodies(t *testing.T) {
+	resp := func(status int, body string) *client.Response {
+		return &client.Response{StatusCode: status, Status: http.StatusText(status), Body: []byte(body)}
+	}
+
+	r := Responses(resp(200, `{"a":1}`), resp(404, `{"a":2}`), Options{})
+	if r.Status == nil || !r.JSON || len(r.Body) != 1 {
+		t.Errorf("JSON bodies: status %v, JSON %v, body %v", r.Status, r.JSON, r.Body)
+	}
+
+	r = Responses(resp(200, "one\ntwo\nthree"), resp(200, "one\n2\nthree"), Options{})
+	want := []Change{
+		{Path: "line 2", Kind: Removed, Old: "two"},
+		{Path: "line 2", Kind: Added, New: "2"},
+	}
+	if r.JSON || !reflect.DeepEqual(r.Body, want) {
+		t.Errorf("text bodies: JSON %v, body %v, want %v", r.JSON, r.Body, want)
+	}
+
+	r = Responses(resp(200, ""), resp(200, "x"), Options{})
+	if len(r.Body) != 1 || r.Body[0].Kind != Added {
+		t.Errorf("empty body: %v", r.Body)
+	}
+}
@@ -0,0 +1,422 @@
+// Package history persists executed requests and their responses per
+// project so they can be browsed and compared later.
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"httpyum/internal/client"
+	"httpyum/internal/parser"
+)
+
+const (
+	// maxEntries is how many entries a project keeps; older ones are pruned.
+	maxEntries = 200
+	// maxBodySize caps the stored response body.
+	maxBodySize = 1 << 20
+)
+
+// Entry is one executed request as it was sent and the response received.
+type Entry struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	File        string    `json:"file"`
+	Environment string    `json:"environment,omitempty"`
+
+	RequestID   string `json:"requestId"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	RequestHeaders []parser.Header `json:"requestHeaders,omitempty"`
+	RequestBody    string          `json:"requestBody,omitempty"`
+
+	Status          string        `json:"status,omitempty"`
+	StatusCode      int           `json:"statusCode,omitempty"`
+	ResponseHeaders http.Header   `json:"responseHeaders,omitempty"`
+	Trailers        http.Header   `json:"trailers,omitempty"`
+	ResponseBody    []byte        `json:"responseBody,omitempty"`
+	ContentType     string        `json:"contentType,omitempty"`
+	Duration        time.Duration `json:"duration,omitempty"`
+	Size            int64         `json:"size,omitempty"`
+	Truncated       bool          `json:"truncated,omitempty"`
+
+	Error      string                   `json:"error,omitempty"`
+	Assertions []client.AssertionResult `json:"assertions,omitempty"`
+	Logs       []string                 `json:"logs,omitempty"`
+}
+
+// NewEntry records result, taking the substituted request when available.
+func NewEntry(result *client.ExecutionResult, file, environment string) *Entry {
+	req := result.Request
+	e := &Entry{
+		Time:        time.Now(),
+		File:        absPath(file),
+		Environment: environment,
+		RequestID:   req.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Method:      req.Method,
+		URL:         req.URL,
+		Assertions:  result.Assertions,
+		Logs:        result.Logs,
+	}
+
+	if r := result.Resolved; r != nil {
+		e.Method = r.Method
+		e.URL = r.URL
+		e.RequestHeaders = redactHeaders(r.Headers, nil)
+		e.RequestBody = r.Body
+	} else {
+		e.RequestHeaders = redactHeaders(req.Headers, nil)
+		e.RequestBody = req.Body
+	}
+	e.redactTokens(result.Tokens)
+
+	if result.Error != nil {
+		e.Error = result.Error.Error()
+	}
+
+	if resp := result.Response; resp != nil {
+		e.Time = resp.RequestTime
+		e.Status = resp.Status
+		e.StatusCode = resp.StatusCode
+		e.ResponseHeaders = redactResponseHeaders(resp.Headers, nil)
+		e.Trailers = resp.Trailers
+		e.ContentType = resp.ContentType
+		e.Duration = resp.Duration
+		e.Size = resp.Size
+		e.ResponseBody = resp.Body
+		if len(e.ResponseBody) > maxBodySize {
+			e.ResponseBody = e.ResponseBody[:maxBodySize]
+			e.Truncated = true
+		}
+	}
+	return e
+}
+
+// redacted replaces secrets in stored headers.
+const redacted = "<redacted>"
+
+// DefaultSecretHeaders are the API key headers whose values are masked in
+// every entry, besides credentials, cookies and session tokens. A store
+// masks more with SetSecretHeaders.
+var DefaultSecretHeaders = []string{"X-Api-Key", "Api-Key"}
+
+// redactHeaders returns headers with the values of those carrying
+// credentials, and of the secret headers, masked, as the history is stored
+// in plain text.
+func redactHeaders(headers []parser.Header, secret map[string]bool) []parser.Header {
+	if headers == nil {
+		return nil
+	}
+	out := make([]parser.Header, len(headers))
+	for i, h := range headers {
+		out[i] = parser.Header{Key: h.Key, Value: redactHeader(h.Key, h.Value, secret)}
+	}
+	return out
+}
+
+func redactResponseHeaders(headers http.Header, secret map[string]bool) http.Header {
+	if headers == nil {
+		return nil
+	}
+	out := make(http.Header, len(headers))
+	for key, values := range headers {
+		masked := make([]string, len(values))
+		for i, v := range values {
+			masked[i] = redactHeader(key, v, secret)
+		}
+		out[key] = masked
+	}
+	return out
+}
+
+// redactHeader masks the value of an Authorization, Proxy-Authorization,
+// Cookie or Set-Cookie header, keeping the auth scheme and cookie names so
+// entries can still be told apart. The values of X-Amz-Security-Token, of
+// DefaultSecretHeaders and of the secret headers are masked whole.
+func redactHeader(key, value string, secret map[string]bool) string {
+	key = http.CanonicalHeaderKey(key)
+	if key == "X-Amz-Security-Token" || secret[key] || slices.ContainsFunc(DefaultSecretHeaders, func(name string) bool {
+		return strings.EqualFold(name, key)
+	}) {
+		return redacted
+	}
+	switch key {
+	case "Authorization", "Proxy-Authorization":
+		if scheme, _, ok := strings.Cut(strings.TrimSpace(value), " "); ok {
+			return scheme + " " + redacted
+		}
+		return redacted
+	case "Cookie":
+		var cookies []string
+		for _, c := range strings.Split(value, ";") {
+			if name, _, ok := strings.Cut(strings.TrimSpace(c), "="); ok {
+				cookies = append(cookies, name+"="+redacted)
+			}
+		}
+		if len(cookies) == 0 {
+			return redacted
+		}
+		return strings.Join(cookies, "; ")
+	case "Set-Cookie":
+		cookie, _, _ := strings.Cut(value, ";")
+		if name, _, ok := strings.Cut(strings.TrimSpace(cookie), "="); ok {
+			return name + "=" + redacted
+		}
+		return redacted
+	}
+	return value
+}
+
+// redactTokens masks the OAuth2 access tokens the request was sent with
+// wherever they were substituted into its URL or headers.
+func (e *Entry) redactTokens(tokens []client.TokenStatus) {
+	for _, t := range tokens {
+		if t.Token == "" {
+			continue
+		}
+		e.URL = strings.ReplaceAll(e.URL, t.Token, redacted)
+		e.URL = strings.ReplaceAll(e.URL, url.QueryEscape(t.Token), redacted)
+		for i, h := range e.RequestHeaders {
+			e.RequestHeaders[i].Value = strings.ReplaceAll(h.Value, t.Token, redacted)
+		}
+	}
+}
+
+// FromFile reports whether e was recorded from the .http file at path,
+// however either path was written.
+func (e *Entry) FromFile(path string) bool {
+	return absPath(e.File) == absPath(path)
+}
+
+// absPath returns path cleaned and made absolute, so that the same file is
+// recorded the same way whatever directory httpyum ran from.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// Title names the entry: its description, name or request line.
+func (e *Entry) Title() string {
+	if e.Description != "" {
+		return e.Description
+	}
+	if e.Name != "" {
+		return e.Name
+	}
+	return e.Method + " " + e.URL
+}
+
+// Result rebuilds an execution result for display. The request holds the
+// substituted values that were sent.
+func (e *Entry) Result() *client.ExecutionResult {
+	req := &parser.Request{
+		ID:          e.RequestID,
+		Name:        e.Name,
+		Description: e.Description,
+		Method:      e.Method,
+		URL:         e.URL,
+		Headers:     e.RequestHeaders,
+		Body:        e.RequestBody,
+	}
+
+	result := &client.ExecutionResult{
+		Request: req,
+		Resolved: &client.ResolvedRequest{
+			Method:  e.Method,
+			URL:     e.URL,
+			Headers: e.RequestHeaders,
+			Body:    e.RequestBody,
+		},
+		Assertions: e.Assertions,
+		Logs:       e.Logs,
+	}
+
+	if e.Error != "" {
+		result.Error = errors.New(e.Error)
+		return result
+	}
+
+	result.Response = &client.Response{
+		StatusCode:  e.StatusCode,
+		Status:      e.Status,
+		Headers:     e.ResponseHeaders,
+		Trailers:    e.Trailers,
+		Body:        e.ResponseBody,
+		ContentType: e.ContentType,
+		Duration:    e.Duration,
+		RequestTime: e.Time,
+		Size:        e.Size,
+	}
+	result.Success = true
+	return result
+}
+
+// Store keeps the history of one project in its own directory.
+type Store struct {
+	dir string
+	// secret are the canonical names of the headers masked in the entries
+	// added, besides those NewEntry masks.
+	secret map[string]bool
+}
+
+// Open returns the store for the project rooted at projectDir, under
+// stateDir/history.
+func Open(stateDir, projectDir string) (*Store, error) {
+	abs, err := filepath.Abs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	name := filepath.Base(abs) + "-" + hex.EncodeToString(sum[:])[:12]
+
+	dir := filepath.Join(stateDir, "history", name)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// SetSecretHeaders masks the values of the named headers, in requests and
+// responses, in the entries added from now on.
+func (s *Store) SetSecretHeaders(names []string) {
+	s.secret = make(map[string]bool, len(names))
+	for _, name := range names {
+		s.secret[http.CanonicalHeaderKey(name)] = true
+	}
+}
+
+// Dir returns the directory the store writes to.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Add saves e, assigning its ID and masking the secret headers, and prunes
+// the oldest entries.
+func (s *Store) Add(e *Entry) error {
+	if len(s.secret) > 0 {
+		e.RequestHeaders = redactHeaders(e.RequestHeaders, s.secret)
+		e.ResponseHeaders = redactResponseHeaders(e.ResponseHeaders, s.secret)
+	}
+	base := e.Time.UTC().Format("20060102T150405.000000000")
+	for i := 0; ; i++ {
+		e.ID = base
+		if i > 0 {
+			e.ID = fmt.Sprintf("%s_%d", base, i)
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(filepath.Join(s.dir, e.ID+".json"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		break
+	}
+
+	return s.prune()
+}
+
+// List returns the stored entries, newest first.
+func (s *Store) List() ([]*Entry, error) {
+	names, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		data, err := os.ReadFile(filepath.Join(s.dir, names[i]))
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			continue
+		}
+		entries = append(entries, &e)
+	}
+	return entries, nil
+}
+
+// ListFile returns the stored entries recorded from the .http file at path,
+// newest first.
+func (s *Store) ListFile(path string) ([]*Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(entries, func(e *Entry) bool { return !e.FromFile(path) }), nil
+}
+
+// Clear removes every entry.
+func (s *Store) Clear() error {
+	names, err := s.files()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) prune() error {
+	names, err := s.files()
+	if err != nil {
+		return err
+	}
+	for len(names) > maxEntries {
+		if err := os.Remove(filepath.Join(s.dir, names[0])); err != nil {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+// files returns the entry file names, oldest first.
+func (s *Store) files() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, d := range dirEntries {
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".json") {
+			names = append(names, d.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
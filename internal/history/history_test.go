@@ -0,0 +1,176 @@
+package history
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"httpyum/internal/client"
+	"httpyum/internal/parser"
+)
+
+func TestNewEntryRedactsCredentials(t *testing.T) {
+	resolved := &client.ResolvedRequest{
+		Method: "GET",
+		URL:    "https://x",
+		Headers: []parser.Header{
+			{Key: "Authorization", Value: "Bearer abc.def"},
+			{Key: "proxy-authorization", Value: "Basic dXNlcjpwYXNz"},
+			{Key: "Cookie", Value: "session=s3cret; theme=dark"},
+			{Key: "Accept", Value: "application/json"},
+		},
+	}
+	result := &client.ExecutionResult{
+		Request:  &parser.Request{Method: "GET", URL: "https://x"},
+		Resolved: resolved,
+		Response: &client.Response{
+			Status:     "200 OK",
+			StatusCode: 200,
+			Headers: http.Header{
+				"Set-Cookie":   {"session=new; Path=/; HttpOnly", "opaque"},
+				"Content-Type": {"application/json"},
+			},
+		},
+	}
+
+	e := NewEntry(result, "api.http", "")
+
+	wantRequest := []parser.Header{
+		{Key: "Authorization", Value: "Bearer <redacted>"},
+		{Key: "proxy-authorization", Value: "Basic <redacted>"},
+		{Key: "Cookie", Value: "session=<redacted>; theme=<redacted>"},
+		{Key: "Accept", Value: "application/json"},
+	}
+	if !reflect.DeepEqual(e.RequestHeaders, wantRequest) {
+		t.Errorf("request headers = %v, want %v", e.RequestHeaders, wantRequest)
+	}
+	wantResponse := http.Header{
+		"Set-Cookie":   {"session=<redacted>", "<redacted>"},
+		"Content-Type": {"application/json"},
+	}
+	if !reflect.DeepEqual(e.ResponseHeaders, wantResponse) {
+		t.Errorf("response headers = %v, want %v", e.ResponseHeaders, wantResponse)
+	}
+
+	// The result itself, still shown in the TUI, is left as it was.
+	if resolved.Headers[0].Value != "Bearer abc.def" || result.Response.Headers.Get("Set-Cookie") != "session=new; Path=/; HttpOnly" {
+		t.Error("NewEntry modified the result")
+	}
+}
+
+func TestEntryFromFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	result := &client.ExecutionResult{Request: &parser.Request{Method: "GET", URL: "https://x"}}
+	e := NewEntry(result, "api.http", "")
+	if !filepath.IsAbs(e.File) {
+		t.Errorf("File = %q, want an absolute path", e.File)
+	}
+	for _, path := range []string{"api.http", "./api.http", filepath.Join(dir, "api.http"), "sub/../api.http"} {
+		if !e.FromFile(path) {
+			t.Errorf("FromFile(%q) = false", path)
+		}
+	}
+	if e.FromFile("other.http") {
+		t.Error(`FromFile("other.http") = true`)
+	}
+}
+
+func TestNewEntryRedactsSecrets(t *testing.T) {
+	result := &client.ExecutionResult{
+		Request: &parser.Request{Method: "GET", URL: "https://x"},
+		Resolved: &client.ResolvedRequest{
+			Method: "GET",
+			URL:    "https://x/items?access_token=tok%2Fen&page=2",
+			Headers: []parser.Header{
+				{Key: "X-Amz-Security-Token", Value: "session"},
+				{Key: "x-api-key", Value: "key1"},
+				{Key: "Api-Key", Value: "key2"},
+				{Key: "X-Custom-Token", Value: "tok/en"},
+				{Key: "X-Trace", Value: "keep"},
+			},
+		},
+		Tokens: []client.TokenStatus{{Provider: "api", Source: "cached", Token: "tok/en"}},
+		Response: &client.Response{
+			Status:     "200 OK",
+			StatusCode: 200,
+			Headers:    http.Header{"X-Api-Key": {"key3"}},
+		},
+	}
+
+	e := NewEntry(result, "api.http", "")
+
+	if want := "https://x/items?access_token=<redacted>&page=2"; e.URL != want {
+		t.Errorf("URL = %q, want %q", e.URL, want)
+	}
+	wantRequest := []parser.Header{
+		{Key: "X-Amz-Security-Token", Value: "<redacted>"},
+		{Key: "x-api-key", Value: "<redacted>"},
+		{Key: "Api-Key", Value: "<redacted>"},
+		{Key: "X-Custom-Token", Value: "<redacted>"},
+		{Key: "X-Trace", Value: "keep"},
+	}
+	if !reflect.DeepEqual(e.RequestHeaders, wantRequest) {
+		t.Errorf("request headers = %v, want %v", e.RequestHeaders, wantRequest)
+	}
+	if got := e.ResponseHeaders.Get("X-Api-Key"); got != "<redacted>" {
+		t.Errorf("response X-Api-Key = %q", got)
+	}
+	if result.Resolved.Headers[3].Value != "tok/en" {
+		t.Error("NewEntry modified the result")
+	}
+}
+
+func TestStoreSecretHeaders(t *testing.T) {
+	store, err := Open(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.SetSecretHeaders([]string{"x-tenant-secret"})
+
+	result := &client.ExecutionResult{
+		Request: &parser.Request{Method: "GET", URL: "https://x"},
+		Resolved: &client.ResolvedRequest{
+			Method:  "GET",
+			URL:     "https://x",
+			Headers: []parser.Header{{Key: "X-Tenant-Secret", Value: "s"}, {Key: "Accept", Value: "*/*"}},
+		},
+	}
+	if err := store.Add(NewEntry(result, "api.http", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := store.List()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("List() = %v, %v", entries, err)
+	}
+	want := []parser.Header{{Key: "X-Tenant-Secret", Value: "<redacted>"}, {Key: "Accept", Value: "*/*"}}
+	if !reflect.DeepEqual(entries[0].RequestHeaders, want) {
+		t.Errorf("request headers = %v, want %v", entries[0].RequestHeaders, want)
+	}
+}
+
+func TestStoreListFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	store, err := Open(t.TempDir(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"a.http", "b.http", "./a.http"} {
+		result := &client.ExecutionResult{Request: &parser.Request{Method: "GET", URL: "https://x/" + file}}
+		if err := store.Add(NewEntry(result, file, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.ListFile(filepath.Join(dir, "a.http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].URL != "https://x/./a.http" || entries[1].URL != "https://x/a.http" {
+		t.Errorf("ListFile() = %v, want the two a.http entries newest first", entries)
+	}
+}
@@ -0,0 +1,270 @@
+// Package jsonpath implements the subset of JSONPath used to address values
+// in JSON documents: $, .key, ['key'], [index], [*], .* and ..key.
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepWildcard
+	stepRecursive
+)
+
+type step struct {
+	kind  stepKind
+	key   string
+	index int
+}
+
+// Path is a compiled JSONPath expression.
+type Path struct {
+	raw   string
+	steps []step
+}
+
+func (p Path) String() string {
+	return p.raw
+}
+
+// Parse compiles a JSONPath expression. The leading "$" is optional.
+func Parse(expr string) (Path, error) {
+	raw := strings.TrimSpace(expr)
+	s := strings.TrimPrefix(raw, "$")
+	var steps []step
+
+	for len(s) > 0 {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			s = s[2:]
+			name, rest := readName(s)
+			if name == "" {
+				return Path{}, fmt.Errorf("invalid JSONPath %q: expected name after ..", raw)
+			}
+			steps = append(steps, step{kind: stepRecursive, key: name})
+			s = rest
+
+		case s[0] == '.':
+			s = s[1:]
+			if strings.HasPrefix(s, "*") {
+				steps = append(steps, step{kind: stepWildcard})
+				s = s[1:]
+				continue
+			}
+			name, rest := readName(s)
+			if name == "" {
+				return Path{}, fmt.Errorf("invalid JSONPath %q: expected name after .", raw)
+			}
+			steps = append(steps, step{kind: stepKey, key: name})
+			s = rest
+
+		case s[0] == '[':
+			end := closingBracket(s)
+			if end < 0 {
+				return Path{}, fmt.Errorf("invalid JSONPath %q: unterminated [", raw)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+
+			switch {
+			case inner == "*":
+				steps = append(steps, step{kind: stepWildcard})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, step{kind: stepKey, key: inner[1 : len(inner)-1]})
+			default:
+				idx, err := strconv.Atoi(inner)
+				if err != nil {
+					return Path{}, fmt.Errorf("invalid JSONPath %q: bad index %q", raw, inner)
+				}
+				steps = append(steps, step{kind: stepIndex, index: idx})
+			}
+
+		default:
+			// Allow a bare leading name, e.g. "data.id".
+			if len(steps) > 0 || strings.HasPrefix(raw, "$") {
+				return Path{}, fmt.Errorf("invalid JSONPath %q: unexpected %q", raw, s[:1])
+			}
+			name, rest := readName(s)
+			steps = append(steps, step{kind: stepKey, key: name})
+			s = rest
+		}
+	}
+
+	return Path{raw: raw, steps: steps}, nil
+}
+
+func readName(s string) (string, string) {
+	i := 0
+	for i < len(s) && s[i] != '.' && s[i] != '[' {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '\'' || s[i] == '"':
+			quote = s[i]
+		case s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// Query returns every value in doc matched by the path. doc is a value
+// decoded by encoding/json into interface{}.
+func (p Path) Query(doc any) []any {
+	current := []any{doc}
+	for _, st := range p.steps {
+		var next []any
+		for _, node := range current {
+			next = append(next, apply(st, node)...)
+		}
+		current = next
+		if len(current) == 0 {
+			break
+		}
+	}
+	return current
+}
+
+func apply(st step, node any) []any {
+	switch st.kind {
+	case stepKey:
+		if obj, ok := node.(map[string]any); ok {
+			if v, ok := obj[st.key]; ok {
+				return []any{v}
+			}
+		}
+	case stepIndex:
+		if arr, ok := node.([]any); ok {
+			idx := st.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				return []any{arr[idx]}
+			}
+		}
+	case stepWildcard:
+		return children(node)
+	case stepRecursive:
+		var out []any
+		var walk func(any)
+		walk = func(n any) {
+			if obj, ok := n.(map[string]any); ok {
+				if v, ok := obj[st.key]; ok {
+					out = append(out, v)
+				}
+			}
+			for _, child := range children(n) {
+				walk(child)
+			}
+		}
+		walk(node)
+		return out
+	}
+	return nil
+}
+
+func children(node any) []any {
+	switch n := node.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make([]any, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, n[k])
+		}
+		return out
+	case []any:
+		return n
+	}
+	return nil
+}
+
+// Query compiles expr and evaluates it against doc.
+func Query(doc any, expr string) ([]any, error) {
+	p, err := Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return p.Query(doc), nil
+}
+
+// Replace sets every value matched by the path to value and returns the
+// updated document. Objects and arrays are modified in place; the root is
+// only replaced by the path "$".
+func (p Path) Replace(doc any, value any) any {
+	return replace(p.steps, doc, value)
+}
+
+func replace(steps []step, node any, value any) any {
+	if len(steps) == 0 {
+		return value
+	}
+	st, rest := steps[0], steps[1:]
+
+	switch st.kind {
+	case stepKey:
+		if obj, ok := node.(map[string]any); ok {
+			if v, ok := obj[st.key]; ok {
+				obj[st.key] = replace(rest, v, value)
+			}
+		}
+	case stepIndex:
+		if arr, ok := node.([]any); ok {
+			idx := st.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				arr[idx] = replace(rest, arr[idx], value)
+			}
+		}
+	case stepWildcard:
+		switch n := node.(type) {
+		case map[string]any:
+			for k, v := range n {
+				n[k] = replace(rest, v, value)
+			}
+		case []any:
+			for i, v := range n {
+				n[i] = replace(rest, v, value)
+			}
+		}
+	case stepRecursive:
+		switch n := node.(type) {
+		case map[string]any:
+			for k, v := range n {
+				v = replace(steps, v, value)
+				if k == st.key {
+					v = replace(rest, v, value)
+				}
+				n[k] = v
+			}
+		case []any:
+			for i, v := range n {
+				n[i] = replace(steps, v, value)
+			}
+		}
+	}
+	return node
+}
@@ -0,0 +1,80 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) any {
+	t.Helper()
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestQuery(t *testing.T) {
+	doc := decode(t, `{"a": {"b": [1, 2, {"c": 3}]}, "c": 0, "d": {"c": 4}, "k.e.y": 5}`)
+
+	tests := []struct {
+		expr string
+		want []any
+	}{
+		{"$", []any{doc}},
+		{"$.a.b[0]", []any{1.0}},
+		{"a.b[1]", []any{2.0}},
+		{"$.a.b[-1].c", []any{3.0}},
+		{"$['a']['b'][2]['c']", []any{3.0}},
+		{`$["k.e.y"]`, []any{5.0}},
+		{"$.a.b[*]", []any{1.0, 2.0, map[string]any{"c": 3.0}}},
+		{"$.d.*", []any{4.0}},
+		{"$..c", []any{0.0, 3.0, 4.0}},
+		{"$.x", nil},
+		{"$.a.b[3]", nil},
+		{"$.a.b.c", nil},
+		{"$.c.d", nil},
+		{"$..x", nil},
+	}
+	for _, tt := range tests {
+		got, err := Query(doc, tt.expr)
+		if err != nil {
+			t.Errorf("Query(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{"$..", "$.", "$[1", "$[x]", "$a"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		expr, doc, want string
+	}{
+		{"$.a", `{"a": 1, "b": 2}`, `{"a": 0, "b": 2}`},
+		{"$.items[*].id", `{"items": [{"id": 1}, {"id": 2}]}`, `{"items": [{"id": 0}, {"id": 0}]}`},
+		{"$..id", `{"id": 1, "x": [{"id": 2}]}`, `{"id": 0, "x": [{"id": 0}]}`},
+		{"$.missing", `{"a": 1}`, `{"a": 1}`},
+		{"$", `{"a": 1}`, `0`},
+	}
+	for _, tt := range tests {
+		p, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := p.Replace(decode(t, tt.doc), 0.0)
+		if want := decode(t, tt.want); !reflect.DeepEqual(got, want) {
+			t.Errorf("Replace(%q) on %s = %v, want %v", tt.expr, tt.doc, got, want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// maxExampleDepth stops example generation for deeply nested or recursive
+// schemas.
+const maxExampleDepth = 8
+
+// orderedMap is a JSON object that keeps the property order of the schema.
+type orderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+func (m *orderedMap) set(key string, v any) {
+	if m.values == nil {
+		m.values = make(map[string]any)
+	}
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = v
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.values[k])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonValue converts a value from the spec, such as an example, into one
+// that marshals to JSON in document order.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case *object:
+		m := &orderedMap{}
+		for _, k := range v.keys {
+			m.set(k, jsonValue(v.values[k]))
+		}
+		return m
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = jsonValue(item)
+		}
+		return list
+	}
+	return v
+}
+
+// example builds a sample value for schema, preferring the examples and
+// defaults given in the spec.
+func (s *spec) example(schema *object, depth int, seen map[string]bool) any {
+	if schema == nil || depth > maxExampleDepth {
+		return nil
+	}
+	if ref := schema.str("$ref"); ref != "" {
+		if seen[ref] {
+			return nil
+		}
+		seen[ref] = true
+		defer delete(seen, ref)
+		return s.example(s.lookup(ref), depth, seen)
+	}
+
+	for _, key := range []string{"example", "default", "x-example"} {
+		if v := schema.get(key); v != nil {
+			return jsonValue(v)
+		}
+	}
+	if examples := schema.list("examples"); len(examples) > 0 {
+		return jsonValue(examples[0])
+	}
+	if enum := schema.list("enum"); len(enum) > 0 {
+		return jsonValue(enum[0])
+	}
+
+	if all := schema.list("allOf"); len(all) > 0 {
+		merged := &orderedMap{}
+		for _, part := range all {
+			sub, _ := part.(*object)
+			if m, ok := s.example(sub, depth+1, seen).(*orderedMap); ok {
+				for _, k := range m.keys {
+					merged.set(k, m.values[k])
+				}
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if choices := schema.list(key); len(choices) > 0 {
+			sub, _ := choices[0].(*object)
+			return s.example(sub, depth+1, seen)
+		}
+	}
+
+	typ := schema.str("type")
+	if list := schema.list("type"); len(list) > 0 {
+		// OpenAPI 3.1 allows a list of types such as [string, "null"].
+		typ, _ = list[0].(string)
+	}
+	if typ == "" && schema.obj("properties") != nil {
+		typ = "object"
+	}
+
+	switch typ {
+	case "object":
+		m := &orderedMap{}
+		props := schema.obj("properties")
+		for _, name := range props.keysOrNil() {
+			prop := s.resolve(props.obj(name))
+			if prop.boolean("readOnly") {
+				continue
+			}
+			m.set(name, s.example(props.obj(name), depth+1, seen))
+		}
+		if extra := schema.obj("additionalProperties"); extra != nil && len(m.keys) == 0 {
+			m.set("key", s.example(extra, depth+1, seen))
+		}
+		return m
+	case "array":
+		item := s.example(schema.obj("items"), depth+1, seen)
+		if item == nil {
+			return []any{}
+		}
+		return []any{item}
+	case "integer":
+		return 0
+	case "number":
+		return 0.0
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(schema.str("format"))
+	}
+	return nil
+}
+
+func stringExample(format string) string {
+	switch format {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "time":
+		return "00:00:00"
+	case "email":
+		return "user@example.com"
+	case "uuid":
+		return "00000000-0000-0000-0000-000000000000"
+	case "uri", "url":
+		return "https://example.com"
+	case "hostname":
+		return "example.com"
+	case "ipv4":
+		return "127.0.0.1"
+	case "ipv6":
+		return "::1"
+	case "byte":
+		return "c3RyaW5n"
+	case "password":
+		return "password"
+	}
+	return "string"
+}
@@ -0,0 +1,467 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"httpyum/internal/parser"
+)
+
+// formBoundary separates the parts of generated multipart bodies.
+const formBoundary = "----HttpyumFormBoundary"
+
+// placeholder is the value of generated credential variables.
+const placeholder = "changeme"
+
+var (
+	methods       = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "options": true, "head": true, "patch": true, "trace": true}
+	pathTemplate  = regexp.MustCompile(`\{([^}]+)\}`)
+	nonWordChars  = regexp.MustCompile(`\W+`)
+	jsonMediaType = regexp.MustCompile(`^application/(.+\+)?json`)
+)
+
+// Result is a generated .http file. Warnings list parts of the spec that
+// could not be represented.
+type Result struct {
+	Variables []parser.Variable
+	Requests  []parser.Request
+	Warnings  []string
+}
+
+// Generate reads an OpenAPI 3 or Swagger 2 document (YAML or JSON) and
+// returns one request per operation.
+func Generate(path string) (*Result, error) {
+	s, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	if s.root.str("openapi") == "" && !s.isSwagger2() {
+		return nil, fmt.Errorf("%s is not an OpenAPI 3 or Swagger 2 document", path)
+	}
+
+	g := &generator{spec: s, result: &Result{}}
+	g.addVariable("baseUrl", s.baseURL())
+
+	paths := s.root.obj("paths")
+	for _, p := range paths.keysOrNil() {
+		item := s.resolve(paths.obj(p))
+		for _, method := range item.keysOrNil() {
+			if !methods[method] {
+				continue
+			}
+			g.result.Requests = append(g.result.Requests, g.operation(p, method, item, item.obj(method)))
+		}
+	}
+	return g.result, nil
+}
+
+// baseURL returns the first server URL with its variables set to their
+// defaults, or the Swagger 2 scheme, host and basePath.
+func (s *spec) baseURL() string {
+	var base string
+	if s.isSwagger2() {
+		scheme := "https"
+		if schemes := s.root.list("schemes"); len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+		}
+		host := s.root.str("host")
+		if host == "" {
+			host = "localhost"
+		}
+		base = scheme + "://" + host + s.root.str("basePath")
+	} else if servers := s.root.list("servers"); len(servers) > 0 {
+		server, _ := servers[0].(*object)
+		base = server.str("url")
+		vars := server.obj("variables")
+		base = pathTemplate.ReplaceAllStringFunc(base, func(match string) string {
+			name := match[1 : len(match)-1]
+			if v := vars.obj(name); v != nil {
+				return v.str("default")
+			}
+			return match
+		})
+	}
+
+	if base == "" || strings.HasPrefix(base, "/") {
+		base = "http://localhost" + base
+	}
+	return strings.TrimSuffix(base, "/")
+}
+
+type generator struct {
+	spec   *spec
+	result *Result
+}
+
+func (g *generator) warn(format string, args ...any) {
+	g.result.Warnings = append(g.result.Warnings, fmt.Sprintf(format, args...))
+}
+
+// addVariable defines a file variable unless one with the same name exists,
+// and returns the name to reference it by.
+func (g *generator) addVariable(name, value string) string {
+	name = variableName(name)
+	for _, v := range g.result.Variables {
+		if v.Name == name {
+			return name
+		}
+	}
+	if value == "" {
+		value = name
+	}
+	g.result.Variables = append(g.result.Variables, parser.Variable{Name: name, Value: value})
+	return name
+}
+
+func (g *generator) operation(path, method string, item, op *object) parser.Request {
+	s := g.spec
+	req := parser.Request{
+		Method:      strings.ToUpper(method),
+		Description: op.str("summary"),
+	}
+	if id := op.str("operationId"); id != "" {
+		req.Name = variableName(id)
+		if req.Description == "" {
+			req.Description = id
+		}
+	}
+	if req.Description == "" {
+		req.Description = req.Method + " " + path
+	}
+	if op.boolean("deprecated") {
+		req.Description += " (deprecated)"
+	}
+
+	params := g.parameters(item, op)
+
+	url := "{{baseUrl}}" + pathTemplate.ReplaceAllStringFunc(path, func(match string) string {
+		name := match[1 : len(match)-1]
+		value := ""
+		if p := params.get(paramKey("path", name)); p != nil {
+			value = g.paramValue(p)
+		}
+		return "{{" + g.addVariable(name, value) + "}}"
+	})
+
+	var query []string
+	var cookies []string
+	for _, key := range params.order {
+		p := params.values[key]
+		in, name := p.str("in"), p.str("name")
+		if in == "path" || in == "body" || in == "formData" {
+			continue
+		}
+		if !p.boolean("required") && p.get("example") == nil && p.get("default") == nil && p.obj("schema").get("example") == nil {
+			continue
+		}
+		ref := "{{" + g.addVariable(name, g.paramValue(p)) + "}}"
+		switch in {
+		case "query":
+			query = append(query, name+"="+ref)
+		case "header":
+			req.Headers = append(req.Headers, parser.Header{Key: name, Value: ref})
+		case "cookie":
+			cookies = append(cookies, name+"="+ref)
+		}
+	}
+
+	g.applySecurity(&req, op, &query, &cookies)
+
+	if len(query) > 0 {
+		url += "?" + strings.Join(query, "&")
+	}
+	if len(cookies) > 0 {
+		req.Headers = append(req.Headers, parser.Header{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+	req.URL = url
+
+	if s.isSwagger2() {
+		g.swaggerBody(&req, op, params)
+	} else if body := s.resolve(op.obj("requestBody")); body != nil {
+		g.requestBody(&req, body)
+	}
+	return req
+}
+
+// parameterSet holds the parameters of an operation in declaration order,
+// keyed by location and name.
+type parameterSet struct {
+	order  []string
+	values map[string]*object
+}
+
+func paramKey(in, name string) string {
+	return in + ":" + name
+}
+
+// parameters merges path-level and operation-level parameters; the
+// operation overrides parameters with the same name and location.
+func (g *generator) parameters(item, op *object) parameterSet {
+	set := parameterSet{values: make(map[string]*object)}
+	for _, list := range [][]any{item.list("parameters"), op.list("parameters")} {
+		for _, raw := range list {
+			p, _ := raw.(*object)
+			p = g.spec.resolve(p)
+			if p == nil {
+				continue
+			}
+			key := paramKey(p.str("in"), p.str("name"))
+			if _, ok := set.values[key]; !ok {
+				set.order = append(set.order, key)
+			}
+			set.values[key] = p
+		}
+	}
+	return set
+}
+
+func (ps parameterSet) get(key string) *object {
+	return ps.values[key]
+}
+
+// paramValue returns an example value for a parameter as a string.
+func (g *generator) paramValue(p *object) string {
+	if v := p.get("example"); v != nil {
+		return scalarString(jsonValue(v))
+	}
+	if examples := p.obj("examples"); examples != nil && len(examples.keys) > 0 {
+		if ex := g.spec.resolve(examples.obj(examples.keys[0])); ex.get("value") != nil {
+			return scalarString(jsonValue(ex.get("value")))
+		}
+	}
+	schema := p.obj("schema")
+	if schema == nil {
+		// Swagger 2 puts the type on the parameter itself.
+		schema = p
+	}
+	return scalarString(g.spec.example(schema, 0, map[string]bool{}))
+}
+
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = scalarString(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// applySecurity adds credentials for the first security requirement of the
+// operation, or the document's default when the operation has none.
+func (g *generator) applySecurity(req *parser.Request, op *object, query, cookies *[]string) {
+	s := g.spec
+	requirements := s.root.list("security")
+	if op.get("security") != nil {
+		requirements = op.list("security")
+	}
+	if len(requirements) == 0 {
+		return
+	}
+	requirement, _ := requirements[0].(*object)
+
+	schemes := s.root.obj("components").obj("securitySchemes")
+	if s.isSwagger2() {
+		schemes = s.root.obj("securityDefinitions")
+	}
+
+	for _, name := range requirement.keysOrNil() {
+		scheme := s.resolve(schemes.obj(name))
+		if scheme == nil {
+			g.warn("%s %s: unknown security scheme %q", req.Method, req.URL, name)
+			continue
+		}
+
+		typ := scheme.str("type")
+		switch {
+		case typ == "http" && strings.EqualFold(scheme.str("scheme"), "bearer"),
+			typ == "oauth2", typ == "openIdConnect":
+			ref := "{{" + g.addVariable(name, placeholder) + "}}"
+			req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Bearer " + ref})
+		case typ == "basic", typ == "http" && strings.EqualFold(scheme.str("scheme"), "basic"):
+			// The variable holds "username:password", encoded when sent.
+			ref := "{{" + g.addVariable(name, "username:password") + "}}"
+			req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Basic " + ref})
+		case typ == "http" && strings.EqualFold(scheme.str("scheme"), "digest"):
+			ref := "{{" + g.addVariable(name, "username:password") + "}}"
+			req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Digest " + ref})
+		case typ == "apiKey":
+			ref := "{{" + g.addVariable(name, placeholder) + "}}"
+			switch scheme.str("in") {
+			case "query":
+				*query = append(*query, scheme.str("name")+"="+ref)
+			case "cookie":
+				*cookies = append(*cookies, scheme.str("name")+"="+ref)
+			default:
+				req.Headers = append(req.Headers, parser.Header{Key: scheme.str("name"), Value: ref})
+			}
+		default:
+			g.warn("security scheme %q (%s %s) is not supported", name, typ, scheme.str("scheme"))
+		}
+	}
+}
+
+// requestBody renders an OpenAPI 3 requestBody, preferring JSON content.
+func (g *generator) requestBody(req *parser.Request, body *object) {
+	content := body.obj("content")
+	mediaTypes := content.keysOrNil()
+	if len(mediaTypes) == 0 {
+		return
+	}
+
+	mediaType := mediaTypes[0]
+	for _, preferred := range []func(string) bool{
+		jsonMediaType.MatchString,
+		func(mt string) bool { return mt == "application/x-www-form-urlencoded" },
+		func(mt string) bool { return mt == "multipart/form-data" },
+	} {
+		if mt := firstMatch(mediaTypes, preferred); mt != "" {
+			mediaType = mt
+			break
+		}
+	}
+
+	media := content.obj(mediaType)
+	var example any
+	if v := media.get("example"); v != nil {
+		example = jsonValue(v)
+	} else if examples := media.obj("examples"); examples != nil && len(examples.keys) > 0 {
+		example = jsonValue(g.spec.resolve(examples.obj(examples.keys[0])).get("value"))
+	} else {
+		example = g.spec.example(media.obj("schema"), 0, map[string]bool{})
+	}
+
+	g.setBody(req, mediaType, example, g.spec.resolve(media.obj("schema")))
+}
+
+// swaggerBody renders a Swagger 2 "body" parameter or "formData" parameters.
+func (g *generator) swaggerBody(req *parser.Request, op *object, params parameterSet) {
+	consumes := op.list("consumes")
+	if len(consumes) == 0 {
+		consumes = g.spec.root.list("consumes")
+	}
+	mediaTypes := make([]string, 0, len(consumes))
+	for _, c := range consumes {
+		mediaTypes = append(mediaTypes, fmt.Sprint(c))
+	}
+
+	form := &orderedMap{}
+	formSchema := &object{values: map[string]any{}}
+	props := &object{values: map[string]any{}}
+	formSchema.values["properties"] = props
+	for _, key := range params.order {
+		p := params.get(key)
+		switch p.str("in") {
+		case "body":
+			mediaType := firstMatch(mediaTypes, jsonMediaType.MatchString)
+			if mediaType == "" {
+				mediaType = "application/json"
+			}
+			g.setBody(req, mediaType, g.spec.example(p.obj("schema"), 0, map[string]bool{}), g.spec.resolve(p.obj("schema")))
+			return
+		case "formData":
+			name := p.str("name")
+			props.keys = append(props.keys, name)
+			props.values[name] = p
+			if p.str("type") == "file" {
+				form.set(name, "")
+				continue
+			}
+			form.set(name, g.paramValue(p))
+		}
+	}
+
+	if len(form.keys) == 0 {
+		return
+	}
+	mediaType := "application/x-www-form-urlencoded"
+	if mt := firstMatch(mediaTypes, func(mt string) bool { return mt == "multipart/form-data" }); mt != "" {
+		mediaType = mt
+	}
+	g.setBody(req, mediaType, form, formSchema)
+}
+
+// setBody renders example as a body of the given media type and sets the
+// Content-Type header.
+func (g *generator) setBody(req *parser.Request, mediaType string, example any, schema *object) {
+	switch {
+	case jsonMediaType.MatchString(mediaType):
+		data, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			g.warn("%s: could not render example body: %v", req.Description, err)
+			return
+		}
+		req.Body = string(data)
+
+	case mediaType == "application/x-www-form-urlencoded":
+		m, ok := example.(*orderedMap)
+		if !ok {
+			return
+		}
+		pairs := make([]string, len(m.keys))
+		for i, k := range m.keys {
+			pairs[i] = k + "=" + scalarString(m.values[k])
+		}
+		req.Body = strings.Join(pairs, "&")
+
+	case mediaType == "multipart/form-data":
+		m, ok := example.(*orderedMap)
+		if !ok {
+			return
+		}
+		props := schema.obj("properties")
+		var parts []string
+		for _, k := range m.keys {
+			prop := g.spec.resolve(props.obj(k))
+			part := "--" + formBoundary + "\n"
+			if prop.str("format") == "binary" || prop.str("type") == "file" {
+				part += fmt.Sprintf("Content-Disposition: form-data; name=%q; filename=%q\n\n< ./%s", k, k, k)
+			} else {
+				part += fmt.Sprintf("Content-Disposition: form-data; name=%q\n\n%s", k, scalarString(m.values[k]))
+			}
+			parts = append(parts, part)
+		}
+		req.Body = strings.Join(parts, "\n") + "\n--" + formBoundary + "--"
+		mediaType += "; boundary=" + formBoundary
+
+	default:
+		if str, ok := example.(string); ok {
+			req.Body = str
+		} else {
+			g.warn("%s: no example body generated for %s", req.Description, mediaType)
+		}
+	}
+
+	req.Headers = append(req.Headers, parser.Header{Key: "Content-Type", Value: mediaType})
+}
+
+func firstMatch(list []string, match func(string) bool) string {
+	for _, s := range list {
+		if match(s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// variableName turns a parameter or scheme name into a valid .http variable
+// name.
+func variableName(name string) string {
+	name = strings.Trim(nonWordChars.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		return "param"
+	}
+	return name
+}
@@ -0,0 +1,158 @@
+// Package openapi generates .http requests from OpenAPI 3 and Swagger 2
+// specifications.
+package openapi
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// object is a mapping from the spec that remembers its key order, so
+// generated requests follow the order of the document.
+type object struct {
+	keys   []string
+	values map[string]any
+}
+
+func (o *object) keysOrNil() []string {
+	if o == nil {
+		return nil
+	}
+	return o.keys
+}
+
+func (o *object) get(key string) any {
+	if o == nil {
+		return nil
+	}
+	return o.values[key]
+}
+
+func (o *object) obj(key string) *object {
+	v, _ := o.get(key).(*object)
+	return v
+}
+
+func (o *object) list(key string) []any {
+	v, _ := o.get(key).([]any)
+	return v
+}
+
+func (o *object) str(key string) string {
+	switch v := o.get(key).(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (o *object) boolean(key string) bool {
+	v, _ := o.get(key).(bool)
+	return v
+}
+
+// spec is a loaded document with $ref resolution.
+type spec struct {
+	root *object
+}
+
+func load(path string) (*spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	if len(node.Content) == 0 {
+		return nil, fmt.Errorf("invalid spec: empty document")
+	}
+
+	v, err := convertNode(node.Content[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec: %w", err)
+	}
+	root, ok := v.(*object)
+	if !ok {
+		return nil, fmt.Errorf("invalid spec: top level is not a mapping")
+	}
+	return &spec{root: root}, nil
+}
+
+func convertNode(n *yaml.Node) (any, error) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		o := &object{values: make(map[string]any, len(n.Content)/2)}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i].Value
+			v, err := convertNode(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if _, seen := o.values[key]; !seen {
+				o.keys = append(o.keys, key)
+			}
+			o.values[key] = v
+		}
+		return o, nil
+	case yaml.SequenceNode:
+		list := make([]any, 0, len(n.Content))
+		for _, c := range n.Content {
+			v, err := convertNode(c)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case yaml.AliasNode:
+		return convertNode(n.Alias)
+	case yaml.ScalarNode:
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	return nil, nil
+}
+
+// resolve follows a local "$ref" such as "#/components/schemas/User". Other
+// values are returned as they are.
+func (s *spec) resolve(o *object) *object {
+	for depth := 0; o != nil && depth < 32; depth++ {
+		ref := o.str("$ref")
+		if ref == "" {
+			return o
+		}
+		o = s.lookup(ref)
+	}
+	return o
+}
+
+func (s *spec) lookup(ref string) *object {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	cur := s.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		cur = cur.obj(part)
+		if cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+func (s *spec) isSwagger2() bool {
+	return s.root.str("swagger") != ""
+}
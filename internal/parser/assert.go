@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+var assertOperators = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"contains": true, "!contains": true, "matches": true,
+	"startsWith": true, "endsWith": true, "exists": true, "!exists": true,
+}
+
+// parseAssertion parses the text after "> assert". Subjects are status,
+// duration, size, body, body.<path> (JSONPath or XPath) and header <name>.
+func parseAssertion(text string) (Assertion, error) {
+	a := Assertion{Raw: strings.TrimSpace(text)}
+	fields := strings.Fields(text)
+
+	subject := fields[0]
+	rest := fields[1:]
+	switch {
+	case subject == "status" || subject == "duration" || subject == "size" || subject == "body":
+		a.Subject = subject
+	case strings.HasPrefix(subject, "body."):
+		a.Subject = "body"
+		a.Path = strings.TrimPrefix(subject, "body.")
+	case subject == "header":
+		if len(rest) == 0 {
+			return a, fmt.Errorf("assert header: missing header name")
+		}
+		a.Subject = "header"
+		a.Path = rest[0]
+		rest = rest[1:]
+	case strings.HasPrefix(subject, "header."), strings.HasPrefix(subject, "headers."):
+		a.Subject = "header"
+		a.Path = subject[strings.Index(subject, ".")+1:]
+	default:
+		return a, fmt.Errorf("assert: unknown subject %q (use status, duration, size, body or header)", subject)
+	}
+
+	if len(rest) == 0 {
+		return a, fmt.Errorf("assert %s: missing operator", subject)
+	}
+
+	op := rest[0]
+	if op == "not" && len(rest) > 1 && (rest[1] == "exists" || rest[1] == "contains") {
+		op = "!" + rest[1]
+		rest = rest[1:]
+	}
+	if !assertOperators[op] {
+		return a, fmt.Errorf("assert %s: unknown operator %q", subject, op)
+	}
+	a.Operator = op
+
+	if op == "exists" || op == "!exists" {
+		return a, nil
+	}
+
+	// Take the expected value from the original text to keep its spacing.
+	expected := skipFields(text, len(fields)-len(rest)+1)
+	if expected == "" {
+		return a, fmt.Errorf("assert %s %s: missing expected value", subject, op)
+	}
+	if len(expected) >= 2 && (expected[0] == '"' || expected[0] == '\'') && expected[len(expected)-1] == expected[0] {
+		expected = expected[1 : len(expected)-1]
+	}
+	a.Expected = expected
+
+	return a, nil
+}
+
+// skipFields returns text without its first n whitespace-separated fields.
+func skipFields(text string, n int) string {
+	s := strings.TrimSpace(text)
+	for i := 0; i < n; i++ {
+		j := strings.IndexAny(s, " \t")
+		if j < 0 {
+			return ""
+		}
+		s = strings.TrimLeft(s[j:], " \t")
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Authorization schemes with built-in helpers.
+const (
+	AuthBasic  = "basic"
+	AuthDigest = "digest"
+	AuthAWS    = "aws"
+)
+
+// Auth is an Authorization header value that the executor completes before
+// sending: "Basic user:pass" or "Basic user pass" (encoded), "Digest user
+// pass" (answered after the server's challenge) and "AWS <accessKey>
+// <secretKey> [token:<token>] [region:<region>] [service:<service>]"
+// (signed with Signature Version 4).
+type Auth struct {
+	Scheme   string
+	User     string
+	Password string
+
+	// AWS only
+	SessionToken string
+	Region       string
+	Service      string
+}
+
+// authParams matches the start of auth-param lists such as
+// `username="u", realm="r"`.
+var authParams = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*\s*=`)
+
+// ParseAuth parses an Authorization header value. It returns nil for
+// values that are sent as they are, such as bearer tokens, already encoded
+// Basic credentials or complete Digest responses.
+func ParseAuth(value string) (*Auth, error) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(value), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(scheme) {
+	case AuthBasic:
+		user, password, ok := splitUserPassword(rest)
+		if !ok {
+			return nil, nil
+		}
+		return &Auth{Scheme: AuthBasic, User: user, Password: password}, nil
+
+	case AuthDigest:
+		if authParams.MatchString(rest) {
+			// A complete Digest response, sent as it is.
+			return nil, nil
+		}
+		user, password, ok := splitUserPassword(rest)
+		if !ok {
+			return nil, fmt.Errorf("digest auth expects \"Digest <user> <password>\"")
+		}
+		return &Auth{Scheme: AuthDigest, User: user, Password: password}, nil
+
+	case AuthAWS:
+		fields := strings.Fields(rest)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("AWS auth expects \"AWS <accessKey> <secretKey> [token:<token>] [region:<region>] [service:<service>]\"")
+		}
+		auth := &Auth{Scheme: AuthAWS, User: fields[0], Password: fields[1]}
+		for _, f := range fields[2:] {
+			key, value, ok := strings.Cut(f, ":")
+			if !ok {
+				return nil, fmt.Errorf("AWS auth: unexpected %q", f)
+			}
+			switch strings.ToLower(key) {
+			case "token":
+				auth.SessionToken = value
+			case "region":
+				auth.Region = value
+			case "service":
+				auth.Service = value
+			default:
+				return nil, fmt.Errorf("AWS auth: unknown option %q", key)
+			}
+		}
+		return auth, nil
+	}
+	return nil, nil
+}
+
+// splitUserPassword splits "user:pass" or "user pass". An encoded token
+// contains neither separator and is not split.
+func splitUserPassword(s string) (user, password string, ok bool) {
+	if user, password, ok = strings.Cut(s, ":"); ok && !strings.Contains(user, " ") {
+		return user, password, true
+	}
+	if user, password, ok = strings.Cut(s, " "); ok {
+		return user, strings.TrimSpace(password), true
+	}
+	return "", "", false
+}
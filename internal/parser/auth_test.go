@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAuth(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    *Auth
+		wantErr bool
+	}{
+		{value: "Bearer abc", want: nil},
+		{value: "Basic dXNlcjpwYXNz", want: nil},
+		{value: "Basic user:pass", want: &Auth{Scheme: AuthBasic, User: "user", Password: "pass"}},
+		{value: "basic user  pa ss", want: &Auth{Scheme: AuthBasic, User: "user", Password: "pa ss"}},
+		{value: "Digest user pass", want: &Auth{Scheme: AuthDigest, User: "user", Password: "pass"}},
+		{value: "Digest user:pa:ss", want: &Auth{Scheme: AuthDigest, User: "user", Password: "pa:ss"}},
+		{value: `Digest username="u", realm="r", nonce="n", uri="/", response="abc"`, want: nil},
+		{value: `Digest username = "u", realm="r"`, want: nil},
+		{value: "Digest user", wantErr: true},
+		{
+			value: "AWS AK SK token:T region:eu-west-1 service:s3",
+			want:  &Auth{Scheme: AuthAWS, User: "AK", Password: "SK", SessionToken: "T", Region: "eu-west-1", Service: "s3"},
+		},
+		{value: "AWS AK", wantErr: true},
+		{value: "AWS AK SK bogus", wantErr: true},
+		{value: "AWS AK SK color:red", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAuth(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAuth(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseAuth(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package parser
+
+import "strings"
+
+// BodyPart is a piece of a request body: literal Text, or the file at Path
+// included with a "< path" line. Substitute is set for "<@ path" lines,
+// whose file content has its variables substituted.
+type BodyPart struct {
+	Text       string
+	Path       string
+	Substitute bool
+}
+
+// SplitBody splits a body into its text and the files included by its
+// "< path" and "<@ path" lines. The line break after a file line is kept
+// at the start of the following text, so a multipart boundary after the
+// file still starts on its own line.
+func SplitBody(body string) []BodyPart {
+	var parts []BodyPart
+	var text strings.Builder
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		path, substitute, ok := fileReference(line)
+		if !ok {
+			text.WriteString(line)
+			if i < len(lines)-1 {
+				text.WriteString("\n")
+			}
+			continue
+		}
+
+		if text.Len() > 0 {
+			parts = append(parts, BodyPart{Text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, BodyPart{Path: path, Substitute: substitute})
+		if i < len(lines)-1 {
+			text.WriteString("\n")
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, BodyPart{Text: text.String()})
+	}
+	return parts
+}
+
+// fileReference parses a "< path" or "<@ path" body line.
+func fileReference(line string) (path string, substitute, ok bool) {
+	line = strings.TrimRight(line, " \t\r")
+	rest, found := strings.CutPrefix(line, "<")
+	if !found {
+		return "", false, false
+	}
+	if rest, found = strings.CutPrefix(rest, "@"); found {
+		substitute = true
+	} else if rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+		// "<tag>" is XML, not a file reference.
+		return "", false, false
+	}
+	path = strings.TrimSpace(rest)
+	return path, substitute, path != ""
+}
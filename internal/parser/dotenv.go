@@ -1,10 +1,21 @@
 package parser
 
 import (
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+var (
+	dotenvKeyRegex     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	dotenvExpandRegex  = regexp.MustCompile(`\\?\$(\{[A-Za-z_][A-Za-z0-9_]*(?::?-[^}]*)?\}|[A-Za-z_][A-Za-z0-9_]*)`)
+	dotenvVarRegex     = regexp.MustCompile(`^\$(\{[A-Za-z_][A-Za-z0-9_]*(?::?-[^}]*)?\}|[A-Za-z_][A-Za-z0-9_]*)`)
+	dotenvDefaultRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(:?-)(.*)$`)
+)
+
 func LoadSystemEnv() map[string]string {
 	envVars := make(map[string]string)
 	for _, env := range os.Environ() {
@@ -15,3 +26,207 @@ func LoadSystemEnv() map[string]string {
 	}
 	return envVars
 }
+
+// LoadEnv builds the values available to {{$dotenv NAME}}, from:
+//
+//  1. a .env file in the same directory as the .http file, if present, for
+//     variables not set in the process environment
+//  2. the process environment
+//  3. envFile, if given (it must exist), which overrides both
+func LoadEnv(httpFilePath, envFile string) (map[string]string, error) {
+	envVars := LoadSystemEnv()
+
+	dotenvPath := filepath.Join(filepath.Dir(httpFilePath), ".env")
+	if _, err := os.Stat(dotenvPath); err == nil {
+		if err := loadDotEnvInto(dotenvPath, envVars, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if envFile != "" {
+		if err := loadDotEnvInto(envFile, envVars, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return envVars, nil
+}
+
+// loadDotEnvInto adds the variables of the env file at path to envVars,
+// replacing those already set only when override is true.
+func loadDotEnvInto(path string, envVars map[string]string, override bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening env file: %w", err)
+	}
+	defer f.Close()
+
+	values, err := ParseDotEnv(f, envVars)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for key, value := range values {
+		if _, ok := envVars[key]; ok && !override {
+			continue
+		}
+		envVars[key] = value
+	}
+	return nil
+}
+
+// ParseDotEnv parses a .env file. It supports comments, an optional "export"
+// prefix, single-quoted (literal), double-quoted (escapes and expansion) and
+// unquoted values, quoted values spanning multiple lines, and ${VAR}, $VAR and
+// ${VAR:-default} expansion. Expansion looks at keys defined earlier in the
+// file first and then at base.
+func ParseDotEnv(r io.Reader, base map[string]string) (map[string]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file: %w", err)
+	}
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	values := make(map[string]string)
+	lookup := func(name string) (string, bool) {
+		if v, ok := values[name]; ok {
+			return v, true
+		}
+		v, ok := base[name]
+		return v, ok
+	}
+
+	lineNum := 0
+	for len(src) > 0 {
+		lineNum++
+		line, rest, _ := strings.Cut(src, "\n")
+		src = rest
+
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		trimmed = strings.TrimPrefix(trimmed, "export ")
+		key, value, found := strings.Cut(trimmed, "=")
+		if !found {
+			return nil, NewParseError(lineNum, fmt.Sprintf("expected KEY=VALUE, got %q", trimmed))
+		}
+		key = strings.TrimSpace(key)
+		if !dotenvKeyRegex.MatchString(key) {
+			return nil, NewParseError(lineNum, fmt.Sprintf("invalid variable name %q", key))
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '\'' || value[0] == '"') {
+			quote := value[0]
+			body := value[1:]
+			startLine := lineNum
+
+			// Quoted values may continue over following lines.
+			end := closingQuote(body, quote)
+			for end < 0 && len(src) > 0 {
+				next, rest, _ := strings.Cut(src, "\n")
+				src = rest
+				lineNum++
+				body += "\n" + next
+				end = closingQuote(body, quote)
+			}
+			if end < 0 {
+				return nil, NewParseError(startLine, fmt.Sprintf("unterminated quoted value for %s", key))
+			}
+
+			body = body[:end]
+			if quote == '"' {
+				body = expandQuotedDotEnv(body, lookup)
+			}
+			values[key] = body
+			continue
+		}
+
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = value[:idx]
+		}
+		values[key] = expandDotEnv(strings.TrimSpace(value), lookup)
+	}
+
+	return values, nil
+}
+
+// closingQuote returns the index of the first unescaped quote in s, or -1.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		if quote == '"' && s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandQuotedDotEnv unescapes and expands a double-quoted value in a single
+// pass, so that an escaped backslash before $ does not escape the $.
+func expandQuotedDotEnv(s string, lookup func(string) (string, bool)) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '"', '\\', '$':
+				sb.WriteByte(s[i])
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(s[i])
+			}
+		case c == '$':
+			if match := dotenvVarRegex.FindString(s[i:]); match != "" {
+				sb.WriteString(dotenvVar(match, lookup))
+				i += len(match) - 1
+				continue
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+func expandDotEnv(s string, lookup func(string) (string, bool)) string {
+	return dotenvExpandRegex.ReplaceAllStringFunc(s, func(match string) string {
+		if strings.HasPrefix(match, `\`) {
+			return match[1:]
+		}
+		return dotenvVar(match, lookup)
+	})
+}
+
+// dotenvVar returns the value of a $VAR, ${VAR} or ${VAR:-default}
+// reference.
+func dotenvVar(match string, lookup func(string) (string, bool)) string {
+	name := strings.TrimPrefix(match, "$")
+	if !strings.HasPrefix(name, "{") {
+		value, _ := lookup(name)
+		return value
+	}
+
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "{"), "}")
+	if m := dotenvDefaultRegex.FindStringSubmatch(name); m != nil {
+		value, ok := lookup(m[1])
+		if !ok || (m[2] == ":-" && value == "") {
+			return m[3]
+		}
+		return value
+	}
+	value, _ := lookup(name)
+	return value
+}
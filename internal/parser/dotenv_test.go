@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	base := map[string]string{"HOME": "/home/me", "EMPTY": ""}
+	tests := []struct {
+		name string
+		src  string
+		want map[string]string
+	}{
+		{"plain", "A=1\nB = two \n", map[string]string{"A": "1", "B": "two"}},
+		{"comments and export", "# comment\n\nexport A=1 # trailing\n", map[string]string{"A": "1"}},
+		{"single quotes are literal", `A='$HOME \n'`, map[string]string{"A": `$HOME \n`}},
+		{"double quote escapes", `A="a\nb\t\"c\" \\"`, map[string]string{"A": "a\nb\t\"c\" \\"}},
+		{"multi-line", "A=\"one\ntwo\"\nB=3", map[string]string{"A": "one\ntwo", "B": "3"}},
+		{"CRLF", "A=1\r\nB=2\r\n", map[string]string{"A": "1", "B": "2"}},
+		{"expansion", "A=x\nB=${A}-$A-$HOME", map[string]string{"A": "x", "B": "x-x-/home/me"}},
+		{"defaults", `A=${MISSING:-d} B=${EMPTY:-e}`, map[string]string{"A": "d B=e"}},
+		{"default only when unset", "A=${EMPTY-d}|${MISSING-d}", map[string]string{"A": "|d"}},
+		{"escaped dollar", `A="\$HOME"`, map[string]string{"A": "$HOME"}},
+		{"escaped backslash before dollar", `A="\\$HOME"`, map[string]string{"A": `\/home/me`}},
+		{"unknown escape kept", `A="\d"`, map[string]string{"A": `\d`}},
+		{"unquoted escaped dollar", `A=\$HOME`, map[string]string{"A": "$HOME"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotEnv(strings.NewReader(tt.src), base)
+			if err != nil {
+				t.Fatalf("ParseDotEnv: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotEnv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDotEnvErrors(t *testing.T) {
+	for _, src := range []string{"NOEQUALS", "1A=x", `A="unterminated`} {
+		if _, err := ParseDotEnv(strings.NewReader(src), nil); err == nil {
+			t.Errorf("ParseDotEnv(%q) succeeded, want an error", src)
+		}
+	}
+}
+
+func TestLoadEnvPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write(".env", "HTTPYUM_TEST_SHELL=dotenv\nHTTPYUM_TEST_DOTENV=dotenv\nHTTPYUM_TEST_FILE=dotenv\n")
+	envFile := write("override.env", "HTTPYUM_TEST_FILE=file\n")
+	t.Setenv("HTTPYUM_TEST_SHELL", "shell")
+	t.Setenv("HTTPYUM_TEST_FILE", "shell")
+
+	env, err := LoadEnv(filepath.Join(dir, "api.http"), envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{
+		"HTTPYUM_TEST_SHELL":  "shell",
+		"HTTPYUM_TEST_DOTENV": "dotenv",
+		"HTTPYUM_TEST_FILE":   "file",
+	} {
+		if env[key] != want {
+			t.Errorf("%s = %q, want %q", key, env[key], want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	systemVariableRegex = regexp.MustCompile(`\{\{\s*\$(guid|uuid|timestamp|datetime|localDatetime|randomInt|processEnv)((?:\s+[^}]*?)?)\s*\}\}`)
+	dateFormatArgRegex  = regexp.MustCompile(`^(rfc1123|iso8601|"[^"]*"|'[^']*')\s*(.*)$`)
+	dayJSTokenRegex     = regexp.MustCompile(`\[[^\]]*\]|YYYY|YY|MMMM|MMM|MM|M|DD|D|dddd|ddd|HH|H|hh|h|mm|m|ss|s|SSS|A|a|ZZ|Z`)
+)
+
+// resolveSystemVariables evaluates the built-in dynamic variables. They are
+// computed on every call, so each execution gets fresh values:
+//
+//	{{$guid}}, {{$uuid}}                   random UUID v4
+//	{{$timestamp [offset unit]}}           Unix timestamp in seconds
+//	{{$datetime fmt [offset unit]}}        UTC time; fmt is rfc1123, iso8601 or "custom"
+//	{{$localDatetime fmt [offset unit]}}   like $datetime in the local time zone
+//	{{$randomInt min max}}                 random integer in [min, max)
+//	{{$processEnv [%]NAME}}                process environment variable
+//
+// Offset units are y, M, w, d, h, m, s and ms. Expressions with invalid
+// arguments are left untouched.
+func resolveSystemVariables(text string) string {
+	return systemVariableRegex.ReplaceAllStringFunc(text, func(match string) string {
+		m := systemVariableRegex.FindStringSubmatch(match)
+		name, args := m[1], strings.TrimSpace(m[2])
+
+		switch name {
+		case "guid", "uuid":
+			return newUUID()
+
+		case "timestamp":
+			t, ok := applyOffset(time.Now(), args)
+			if !ok {
+				return match
+			}
+			return strconv.FormatInt(t.Unix(), 10)
+
+		case "datetime", "localDatetime":
+			parts := dateFormatArgRegex.FindStringSubmatch(args)
+			if parts == nil {
+				return match
+			}
+			t, ok := applyOffset(time.Now(), parts[2])
+			if !ok {
+				return match
+			}
+			if name == "datetime" {
+				t = t.UTC()
+			} else {
+				t = t.Local()
+			}
+			return formatDatetime(t, parts[1])
+
+		case "randomInt":
+			fields := strings.Fields(args)
+			if len(fields) != 2 {
+				return match
+			}
+			lo, err1 := strconv.ParseInt(fields[0], 10, 64)
+			hi, err2 := strconv.ParseInt(fields[1], 10, 64)
+			if err1 != nil || err2 != nil || hi <= lo {
+				return match
+			}
+			n, err := rand.Int(rand.Reader, big.NewInt(hi-lo))
+			if err != nil {
+				return match
+			}
+			return strconv.FormatInt(lo+n.Int64(), 10)
+
+		case "processEnv":
+			envName := args
+			if strings.HasPrefix(envName, "%") {
+				envName = os.Getenv(strings.TrimPrefix(envName, "%"))
+			}
+			if envName == "" {
+				return match
+			}
+			return os.Getenv(envName)
+		}
+
+		return match
+	})
+}
+
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// applyOffset shifts t by an "<amount> <unit>" offset such as "-3 d". An
+// empty offset returns t unchanged.
+func applyOffset(t time.Time, offset string) (time.Time, bool) {
+	fields := strings.Fields(offset)
+	if len(fields) == 0 {
+		return t, true
+	}
+	if len(fields) != 2 {
+		return t, false
+	}
+
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return t, false
+	}
+
+	switch fields[1] {
+	case "y":
+		return t.AddDate(n, 0, 0), true
+	case "M":
+		return t.AddDate(0, n, 0), true
+	case "w":
+		return t.AddDate(0, 0, 7*n), true
+	case "d":
+		return t.AddDate(0, 0, n), true
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour), true
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute), true
+	case "s":
+		return t.Add(time.Duration(n) * time.Second), true
+	case "ms":
+		return t.Add(time.Duration(n) * time.Millisecond), true
+	}
+	return t, false
+}
+
+func formatDatetime(t time.Time, format string) string {
+	switch format {
+	case "rfc1123":
+		if t.Location() == time.UTC {
+			return t.Format(http1123)
+		}
+		return t.Format(time.RFC1123Z)
+	case "iso8601":
+		if t.Location() == time.UTC {
+			return t.Format("2006-01-02T15:04:05.000Z")
+		}
+		return t.Format("2006-01-02T15:04:05.000-07:00")
+	}
+	return formatDayJS(t, format[1:len(format)-1])
+}
+
+const http1123 = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// formatDayJS formats t using Day.js style tokens (YYYY-MM-DD HH:mm:ss),
+// the syntax used by other .http clients. Text in [brackets] is literal.
+func formatDayJS(t time.Time, layout string) string {
+	return dayJSTokenRegex.ReplaceAllStringFunc(layout, func(token string) string {
+		switch token {
+		case "YYYY":
+			return fmt.Sprintf("%04d", t.Year())
+		case "YY":
+			return fmt.Sprintf("%02d", t.Year()%100)
+		case "MMMM":
+			return t.Month().String()
+		case "MMM":
+			return t.Month().String()[:3]
+		case "MM":
+			return fmt.Sprintf("%02d", int(t.Month()))
+		case "M":
+			return strconv.Itoa(int(t.Month()))
+		case "DD":
+			return fmt.Sprintf("%02d", t.Day())
+		case "D":
+			return strconv.Itoa(t.Day())
+		case "dddd":
+			return t.Weekday().String()
+		case "ddd":
+			return t.Weekday().String()[:3]
+		case "HH":
+			return fmt.Sprintf("%02d", t.Hour())
+		case "H":
+			return strconv.Itoa(t.Hour())
+		case "hh":
+			return fmt.Sprintf("%02d", hour12(t))
+		case "h":
+			return strconv.Itoa(hour12(t))
+		case "mm":
+			return fmt.Sprintf("%02d", t.Minute())
+		case "m":
+			return strconv.Itoa(t.Minute())
+		case "ss":
+			return fmt.Sprintf("%02d", t.Second())
+		case "s":
+			return strconv.Itoa(t.Second())
+		case "SSS":
+			return fmt.Sprintf("%03d", t.Nanosecond()/int(time.Millisecond))
+		case "A":
+			return t.Format("PM")
+		case "a":
+			return t.Format("pm")
+		case "ZZ":
+			return t.Format("-0700")
+		case "Z":
+			return t.Format("-07:00")
+		}
+		return strings.Trim(token, "[]")
+	})
+}
+
+func hour12(t time.Time) int {
+	h := t.Hour() % 12
+	if h == 0 {
+		h = 12
+	}
+	return h
+}
@@ -0,0 +1,290 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	envFileName        = "http-client.env.json"
+	privateEnvFileName = "http-client.private.env.json"
+	vscodeSettingsPath = ".vscode/settings.json"
+	vscodeEnvKey       = "rest-client.environmentVariables"
+	sharedEnvName      = "$shared"
+)
+
+var sharedRefRegex = regexp.MustCompile(`\{\{\s*\$shared\s+(\w+)\s*\}\}`)
+
+// Environment is a named set of variables loaded from environment files,
+// along with the OAuth2 providers declared in its "Security" object and the
+// TLS configurations declared in its "SSLConfiguration" object.
+type Environment struct {
+	Name      string
+	Variables map[string]string
+	OAuth2    map[string]*OAuth2Config
+	TLS       []*TLSConfig
+}
+
+// Environments maps environment names to their definitions. The "$shared"
+// entry, if present, holds variables common to every environment.
+type Environments map[string]*Environment
+
+// Names returns the selectable environment names in sorted order.
+func (e Environments) Names() []string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		if name != sharedEnvName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Variables returns the variables of the named environment layered over the
+// shared ones. An empty name yields only the shared variables.
+func (e Environments) Variables(name string) map[string]string {
+	vars := make(map[string]string)
+	shared := e[sharedEnvName]
+	if shared != nil {
+		for k, v := range shared.Variables {
+			vars[k] = v
+		}
+	}
+
+	if env, ok := e[name]; ok && name != sharedEnvName {
+		for k, v := range env.Variables {
+			vars[k] = sharedRefRegex.ReplaceAllStringFunc(v, func(match string) string {
+				ref := sharedRefRegex.FindStringSubmatch(match)[1]
+				if shared != nil {
+					if value, ok := shared.Variables[ref]; ok {
+						return value
+					}
+				}
+				return match
+			})
+		}
+	}
+	return vars
+}
+
+// OAuth2 returns the OAuth2 providers of the named environment layered over
+// the shared ones.
+func (e Environments) OAuth2(name string) map[string]*OAuth2Config {
+	providers := make(map[string]*OAuth2Config)
+	for _, envName := range []string{sharedEnvName, name} {
+		if env, ok := e[envName]; ok {
+			for k, v := range env.OAuth2 {
+				providers[k] = v
+			}
+		}
+	}
+	return providers
+}
+
+// TLS returns the TLS configurations of the named environment followed by
+// the shared ones, so that the environment's take precedence for the same
+// host pattern.
+func (e Environments) TLS(name string) []*TLSConfig {
+	var configs []*TLSConfig
+	for _, envName := range []string{name, sharedEnvName} {
+		if env, ok := e[envName]; ok {
+			configs = append(configs, env.TLS...)
+		}
+	}
+	return configs
+}
+
+// LoadEnvironments reads the JetBrains (http-client.env.json and
+// http-client.private.env.json) and VS Code REST Client
+// (.vscode/settings.json) environment files that apply to dir. Later files
+// override earlier ones: VS Code settings, then the shared env file, then the
+// private env file.
+func LoadEnvironments(dir string) (Environments, error) {
+	envs := make(Environments)
+
+	if settingsPath := findUpwards(dir, vscodeSettingsPath); settingsPath != "" {
+		data, err := os.ReadFile(settingsPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", settingsPath, err)
+		}
+		var settings map[string]json.RawMessage
+		if err := json.Unmarshal(stripJSONComments(data), &settings); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", settingsPath, err)
+		}
+		if raw, ok := settings[vscodeEnvKey]; ok {
+			if err := mergeEnvironments(envs, raw); err != nil {
+				return nil, fmt.Errorf("error parsing %s: %w", settingsPath, err)
+			}
+		}
+	}
+
+	for _, name := range []string{envFileName, privateEnvFileName} {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		if err := mergeEnvironments(envs, stripJSONComments(data)); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	}
+
+	return envs, nil
+}
+
+func mergeEnvironments(envs Environments, data []byte) error {
+	var raw map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for name, values := range raw {
+		env, ok := envs[name]
+		if !ok {
+			env = &Environment{Name: name, Variables: make(map[string]string), OAuth2: make(map[string]*OAuth2Config)}
+			envs[name] = env
+		}
+		for key, value := range values {
+			if key == "Security" {
+				providers, err := parseSecurity(value)
+				if err != nil {
+					return fmt.Errorf("environment %q: %w", name, err)
+				}
+				for k, v := range providers {
+					env.OAuth2[k] = v
+				}
+				continue
+			}
+			if key == "SSLConfiguration" {
+				configs, err := parseSSLConfiguration(value)
+				if err != nil {
+					return fmt.Errorf("environment %q: %w", name, err)
+				}
+				env.TLS = mergeTLS(env.TLS, configs)
+				continue
+			}
+			if s, ok := scalarJSON(value); ok {
+				env.Variables[key] = s
+			}
+		}
+	}
+	return nil
+}
+
+// mergeTLS adds configs to existing ones, replacing those for the same host
+// pattern, as a private env file does for the shared one.
+func mergeTLS(existing, configs []*TLSConfig) []*TLSConfig {
+	for _, cfg := range configs {
+		i := slices.IndexFunc(existing, func(c *TLSConfig) bool { return c.Host == cfg.Host })
+		if i >= 0 {
+			existing[i] = cfg
+		} else {
+			existing = append(existing, cfg)
+		}
+	}
+	return existing
+}
+
+// scalarJSON converts a JSON string, number or boolean to its string form.
+// Objects, arrays and null are reported as not scalar.
+func scalarJSON(raw json.RawMessage) (string, bool) {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	default:
+		return "", false
+	}
+}
+
+// findUpwards looks for rel in dir and each of its parents and returns the
+// first existing path, or "".
+func findUpwards(dir, rel string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(abs, rel)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return ""
+		}
+		abs = parent
+	}
+}
+
+// stripJSONComments removes // and /* */ comments and trailing commas so
+// that JSONC files such as VS Code settings can be decoded.
+func stripJSONComments(data []byte) []byte {
+	var out strings.Builder
+	inString := false
+	escape := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out.WriteByte(c)
+			if escape {
+				escape = false
+			} else if c == '\\' {
+				escape = true
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ',':
+			j := i + 1
+			for j < len(data) && strings.ContainsRune(" \t\r\n", rune(data[j])) {
+				j++
+			}
+			if j < len(data) && (data[j] == '}' || data[j] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+
+	return []byte(out.String())
+}
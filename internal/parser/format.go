@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatRequest renders req in .http syntax. Parsing the output yields an
+// equivalent request, which makes it suitable for importers that append to
+// existing files.
+func FormatRequest(req Request) string {
+	var sb strings.Builder
+
+	sb.WriteString("###")
+	if req.Description != "" {
+		sb.WriteString(" " + req.Description)
+	}
+	sb.WriteString("\n")
+
+	if req.Name != "" {
+		fmt.Fprintf(&sb, "# @name %s\n", req.Name)
+	}
+	if len(req.SnapshotIgnore) > 0 {
+		fmt.Fprintf(&sb, "# @snapshot-ignore %s\n", strings.Join(req.SnapshotIgnore, " "))
+	}
+	if req.NoCookieJar {
+		sb.WriteString("# @no-cookie-jar\n")
+	}
+	if req.Timeout > 0 {
+		fmt.Fprintf(&sb, "# @timeout %s\n", req.Timeout)
+	}
+	if req.ConnectionTimeout > 0 {
+		fmt.Fprintf(&sb, "# @connection-timeout %s\n", req.ConnectionTimeout)
+	}
+	if req.NoRedirect {
+		sb.WriteString("# @no-redirect\n")
+	} else if req.MaxRedirects > 0 {
+		fmt.Fprintf(&sb, "# @max-redirects %d\n", req.MaxRedirects)
+	}
+	if req.Insecure {
+		sb.WriteString("# @insecure\n")
+	}
+	if req.NoProxy {
+		sb.WriteString("# @no-proxy\n")
+	} else if req.Proxy != "" {
+		fmt.Fprintf(&sb, "# @proxy %s\n", req.Proxy)
+	}
+	for _, proto := range req.Protos {
+		fmt.Fprintf(&sb, "# @proto %s\n", proto)
+	}
+	for _, s := range req.PreRequestScripts {
+		writeScript(&sb, "<", s)
+	}
+
+	fmt.Fprintf(&sb, "%s %s\n", req.Method, req.URL)
+	for _, h := range req.Headers {
+		fmt.Fprintf(&sb, "%s: %s\n", h.Key, h.Value)
+	}
+
+	if body := strings.Trim(req.Body, "\n"); body != "" {
+		sb.WriteString("\n" + body + "\n")
+	}
+
+	if len(req.ResponseHandlers) > 0 || len(req.Assertions) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, s := range req.ResponseHandlers {
+		writeScript(&sb, ">", s)
+	}
+	for _, a := range req.Assertions {
+		fmt.Fprintf(&sb, "> assert %s\n", a.Raw)
+	}
+
+	return sb.String()
+}
+
+// FormatVariables renders file variables as "@name = value" lines.
+func FormatVariables(variables []Variable) string {
+	var sb strings.Builder
+	for _, v := range variables {
+		fmt.Fprintf(&sb, "@%s = %s\n", v.Name, v.Value)
+	}
+	return sb.String()
+}
+
+// FormatFile renders a complete .http file: variables followed by requests.
+func FormatFile(variables []Variable, requests []Request) string {
+	var parts []string
+	if len(variables) > 0 {
+		parts = append(parts, FormatVariables(variables))
+	}
+	for _, req := range requests {
+		parts = append(parts, FormatRequest(req))
+	}
+	return strings.Join(parts, "\n")
+}
+
+func writeScript(sb *strings.Builder, phase string, s Script) {
+	if s.Path != "" {
+		fmt.Fprintf(sb, "%s %s\n", phase, s.Path)
+		return
+	}
+	fmt.Fprintf(sb, "%s {%%%s%%}\n", phase, s.Source)
+}
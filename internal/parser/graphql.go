@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// GraphQLHeader marks a request as GraphQL, as in the JetBrains HTTP Client
+// ("X-Request-Type: GraphQL"). It is not sent.
+const GraphQLHeader = "X-Request-Type"
+
+// IsGraphQL reports whether req is a GraphQL request: its method is GRAPHQL
+// or it has an "X-Request-Type: GraphQL" header.
+func (r *Request) IsGraphQL() bool {
+	if r.Method == "GRAPHQL" {
+		return true
+	}
+	for _, h := range r.Headers {
+		if strings.EqualFold(h.Key, GraphQLHeader) && strings.EqualFold(strings.TrimSpace(h.Value), "graphql") {
+			return true
+		}
+	}
+	return false
+}
+
+// GraphQLHTTP returns the HTTP request a GraphQL request is sent as. Its
+// body, a query optionally followed by a blank line and a JSON object of
+// variables, becomes a {"query": ..., "variables": ...} document sent with
+// POST, or the query and variables URL parameters of a GET. The GRAPHQL
+// method is sent as POST, and the X-Request-Type header is dropped.
+func GraphQLHTTP(req Request) (Request, error) {
+	query, variables := SplitGraphQL(req.Body)
+
+	headers := make([]Header, 0, len(req.Headers)+1)
+	hasContentType := false
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, GraphQLHeader) {
+			continue
+		}
+		hasContentType = hasContentType || strings.EqualFold(h.Key, "Content-Type")
+		headers = append(headers, h)
+	}
+
+	if req.Method == "GET" {
+		params := url.Values{"query": {query}}
+		if variables != nil {
+			var compact bytes.Buffer
+			json.Compact(&compact, variables)
+			params.Set("variables", compact.String())
+		}
+		separator := "?"
+		if strings.Contains(req.URL, "?") {
+			separator = "&"
+		}
+		req.URL += separator + params.Encode()
+		req.Headers, req.Body = headers, ""
+		return req, nil
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Keep <, > and & readable; they are common in queries.
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(struct {
+		Query     string          `json:"query"`
+		Variables json.RawMessage `json:"variables,omitempty"`
+	}{query, variables})
+	if err != nil {
+		return req, err
+	}
+	if !hasContentType {
+		headers = append(headers, Header{Key: "Content-Type", Value: "application/json"})
+	}
+	req.Method = "POST"
+	req.Headers = headers
+	req.Body = strings.TrimSuffix(buf.String(), "\n")
+	return req, nil
+}
+
+// SplitGraphQL splits a GraphQL body into its query and its variables, the
+// JSON object after the first blank line that starts one. A query in
+// shorthand form ("{ user { id } }") is not valid JSON, so it is never taken
+// for variables.
+func SplitGraphQL(body string) (query string, variables json.RawMessage) {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i-1]) != "" || !strings.HasPrefix(strings.TrimSpace(lines[i]), "{") {
+			continue
+		}
+		query = strings.TrimSpace(strings.Join(lines[:i], "\n"))
+		rest := strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		var object map[string]json.RawMessage
+		if query == "" || json.Unmarshal([]byte(rest), &object) != nil {
+			continue
+		}
+		return query, json.RawMessage(rest)
+	}
+	return strings.TrimSpace(body), nil
+}
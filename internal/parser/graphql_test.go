@@ -0,0 +1,70 @@
+package parser
+
+import "testing"
+
+func TestSplitGraphQL(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		query     string
+		variables string
+	}{
+		{
+			name:  "query only",
+			body:  "query {\n  user(id: 1) { name }\n}\n",
+			query: "query {\n  user(id: 1) { name }\n}",
+		},
+		{
+			name:      "query and variables",
+			body:      "query User($id: ID!) {\n  user(id: $id) { name }\n}\n\n{\"id\": 1}\n",
+			query:     "query User($id: ID!) {\n  user(id: $id) { name }\n}",
+			variables: `{"id": 1}`,
+		},
+		{
+			name:      "CRLF and multi-line variables",
+			body:      "query User($id: ID!) { user(id: $id) { name } }\r\n\r\n{\r\n  \"id\": 1\r\n}",
+			query:     "query User($id: ID!) { user(id: $id) { name } }",
+			variables: "{\n  \"id\": 1\n}",
+		},
+		{
+			name:  "shorthand query after a blank line",
+			body:  "# users\n\n{ users { id } }",
+			query: "# users\n\n{ users { id } }",
+		},
+		{
+			name:  "shorthand query only",
+			body:  "{\n  users { id }\n}",
+			query: "{\n  users { id }\n}",
+		},
+		{
+			name:  "blank line inside the query",
+			body:  "query {\n  a\n\n  b\n}",
+			query: "query {\n  a\n\n  b\n}",
+		},
+		{
+			name:      "variables after a blank line inside the query",
+			body:      "query {\n\n  { a }\n}\n\n{\"x\": true}",
+			query:     "query {\n\n  { a }\n}",
+			variables: `{"x": true}`,
+		},
+		{
+			name:  "variables that are not an object",
+			body:  "query { a }\n\n[1, 2]",
+			query: "query { a }\n\n[1, 2]",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, variables := SplitGraphQL(tt.body)
+			if query != tt.query {
+				t.Errorf("query = %q, want %q", query, tt.query)
+			}
+			if string(variables) != tt.variables {
+				t.Errorf("variables = %q, want %q", variables, tt.variables)
+			}
+		})
+	}
+}
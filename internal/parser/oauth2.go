@@ -0,0 +1,157 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// OAuth2 grant types.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+	GrantAuthorizationCode = "authorization_code"
+	GrantDeviceCode        = "device_code"
+)
+
+// OAuth2Config is an OAuth2 provider declared in an environment file under
+// "Security": {"Auth": {"<name>": {...}}}. Values may contain {{variables}},
+// which are substituted when a token is requested.
+type OAuth2Config struct {
+	Name          string
+	GrantType     string
+	AuthURL       string
+	TokenURL      string
+	DeviceAuthURL string
+	RedirectURL   string
+	ClientID      string
+	ClientSecret  string
+	Scope         string
+	Audience      string
+	Username      string
+	Password      string
+
+	// ClientAuthInBody sends the client credentials as form parameters
+	// instead of with Basic authentication.
+	ClientAuthInBody bool
+	// PKCE adds a code challenge to the authorization code flow. It is on
+	// unless disabled with "PKCE": false.
+	PKCE bool
+}
+
+// grantTypes maps normalized "Grant Type" values, in the JetBrains HTTP
+// Client spelling or the OAuth2 one, to grant types.
+var grantTypes = map[string]string{
+	"clientcredentials":   GrantClientCredentials,
+	"password":            GrantPassword,
+	"authorizationcode":   GrantAuthorizationCode,
+	"devicecode":          GrantDeviceCode,
+	"deviceauthorization": GrantDeviceCode,
+}
+
+// parseSecurity reads the OAuth2 providers of an environment's "Security"
+// object. Auth entries of other types are skipped.
+func parseSecurity(raw json.RawMessage) (map[string]*OAuth2Config, error) {
+	var security map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &security); err != nil {
+		return nil, fmt.Errorf("Security: %w", err)
+	}
+	var auth map[string]map[string]json.RawMessage
+	for key, value := range security {
+		if normalizeKey(key) == "auth" {
+			if err := json.Unmarshal(value, &auth); err != nil {
+				return nil, fmt.Errorf("Security.Auth: %w", err)
+			}
+		}
+	}
+
+	configs := make(map[string]*OAuth2Config)
+	for name, fields := range auth {
+		cfg := &OAuth2Config{Name: name, PKCE: true}
+		typ := ""
+		for key, value := range fields {
+			if normalizeKey(key) == "pkce" {
+				// true, false or an object of PKCE settings.
+				var enabled bool
+				cfg.PKCE = json.Unmarshal(value, &enabled) != nil || enabled
+				continue
+			}
+			s, ok := scalarJSON(value)
+			if !ok {
+				continue
+			}
+			switch normalizeKey(key) {
+			case "type":
+				typ = s
+			case "granttype":
+				grant, ok := grantTypes[normalizeKey(s)]
+				if !ok {
+					return nil, fmt.Errorf("auth %q: unsupported grant type %q", name, s)
+				}
+				cfg.GrantType = grant
+			case "authurl":
+				cfg.AuthURL = s
+			case "tokenurl":
+				cfg.TokenURL = s
+			case "deviceauthurl":
+				cfg.DeviceAuthURL = s
+			case "redirecturl":
+				cfg.RedirectURL = s
+			case "clientid":
+				cfg.ClientID = s
+			case "clientsecret":
+				cfg.ClientSecret = s
+			case "clientcredentials":
+				cfg.ClientAuthInBody = normalizeKey(s) == "inbody"
+			case "scope":
+				cfg.Scope = s
+			case "audience":
+				cfg.Audience = s
+			case "username":
+				cfg.Username = s
+			case "password":
+				cfg.Password = s
+			}
+		}
+		if !strings.EqualFold(typ, "OAuth2") {
+			continue
+		}
+		if err := cfg.validate(); err != nil {
+			return nil, fmt.Errorf("auth %q: %w", name, err)
+		}
+		configs[name] = cfg
+	}
+	return configs, nil
+}
+
+func (c *OAuth2Config) validate() error {
+	if c.GrantType == "" {
+		return fmt.Errorf("missing Grant Type")
+	}
+	if c.TokenURL == "" {
+		return fmt.Errorf("missing Token URL")
+	}
+	switch c.GrantType {
+	case GrantAuthorizationCode:
+		if c.AuthURL == "" {
+			return fmt.Errorf("missing Auth URL")
+		}
+	case GrantDeviceCode:
+		if c.DeviceAuthURL == "" {
+			return fmt.Errorf("missing Device Auth URL")
+		}
+	}
+	return nil
+}
+
+// normalizeKey lowercases s and drops spaces, dashes and underscores, so
+// "Grant Type", "grant_type" and "grantType" compare equal.
+func normalizeKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_':
+			return -1
+		}
+		return r
+	}, strings.ToLower(s))
+}
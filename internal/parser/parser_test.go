@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, req Request)
+	}{
+		{
+			name:  "known annotations",
+			input: "# @name login\n# @timeout 5s\n# @no-redirect\nPOST https://x/login\n# @insecure\n",
+			check: func(t *testing.T, req Request) {
+				if req.Name != "login" || req.Timeout != 5*time.Second || !req.NoRedirect || !req.Insecure {
+					t.Errorf("request = %+v", req)
+				}
+			},
+		},
+		{
+			name:  "name with equals",
+			input: "# @name=login\nGET https://x\n",
+			check: func(t *testing.T, req Request) {
+				if req.Name != "login" {
+					t.Errorf("Name = %q, want login", req.Name)
+				}
+			},
+		},
+		{
+			name:  "unknown annotation is the description",
+			input: "# @deprecated use v2\nGET https://x/v1\n",
+			check: func(t *testing.T, req Request) {
+				if req.Description != "@deprecated use v2" {
+					t.Errorf("Description = %q", req.Description)
+				}
+			},
+		},
+		{
+			name:  "unknown annotation after the name",
+			input: "### Users\n# @name users\n// @owner team-api\nGET https://x/users\n",
+			check: func(t *testing.T, req Request) {
+				if req.Name != "users" || req.Description != "@owner team-api" {
+					t.Errorf("Name = %q, Description = %q", req.Name, req.Description)
+				}
+			},
+		},
+		{
+			name:  "mention in a comment",
+			input: "# ask @alice about this\nGET https://x\n",
+			check: func(t *testing.T, req Request) {
+				if req.Description != "ask @alice about this" {
+					t.Errorf("Description = %q", req.Description)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parsed.Requests) != 1 {
+				t.Fatalf("parsed %d requests, want 1", len(parsed.Requests))
+			}
+			tt.check(t, parsed.Requests[0])
+		})
+	}
+}
+
+func TestParseAnnotationErrors(t *testing.T) {
+	for _, input := range []string{
+		"# @timeout soon\nGET https://x\n",
+		"# @max-redirects -1\nGET https://x\n",
+		"# @proxy ftp://proxy\nGET https://x\n",
+		"# @proto\nGRPC localhost:50051/a.B/C\n",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", input)
+		}
+	}
+}
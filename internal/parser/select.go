@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SelectRequests returns the requests matching any of the given selectors, in
+// file order. A selector is a 1-based index ("2"), a regular expression
+// wrapped in slashes ("/users/") matched against the name, description, method
+// and URL, or a name or description matched case-insensitively. No selectors
+// selects all requests.
+func SelectRequests(requests []Request, selectors []string) ([]Request, error) {
+	if len(selectors) == 0 {
+		return requests, nil
+	}
+
+	selected := make([]bool, len(requests))
+	for _, sel := range selectors {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			continue
+		}
+
+		matched := false
+
+		if idx, err := strconv.Atoi(sel); err == nil {
+			if idx < 1 || idx > len(requests) {
+				return nil, fmt.Errorf("request index %d out of range (1-%d)", idx, len(requests))
+			}
+			selected[idx-1] = true
+			continue
+		}
+
+		if len(sel) > 1 && strings.HasPrefix(sel, "/") && strings.HasSuffix(sel, "/") {
+			re, err := regexp.Compile(sel[1 : len(sel)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid request pattern %s: %w", sel, err)
+			}
+			for i, req := range requests {
+				if re.MatchString(req.Name) || re.MatchString(req.Description) || re.MatchString(req.Method+" "+req.URL) {
+					selected[i] = true
+					matched = true
+				}
+			}
+		} else {
+			for i, req := range requests {
+				if strings.EqualFold(req.Name, sel) || strings.EqualFold(req.Description, sel) || req.ID == sel {
+					selected[i] = true
+					matched = true
+				}
+			}
+		}
+
+		if !matched {
+			return nil, fmt.Errorf("no request matches %q", sel)
+		}
+	}
+
+	var result []Request
+	for i, req := range requests {
+		if selected[i] {
+			result = append(result, req)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TLSConfig is a TLS configuration declared in an environment file under
+// "SSLConfiguration". It applies to the hosts matching Host: an exact host
+// name, a "*.example.com" wildcard or "*" for every host, optionally with a
+// ":port". Values may contain {{variables}}, and relative paths are resolved
+// against the directory of the .http file.
+type TLSConfig struct {
+	Host string
+
+	// ClientCertificate is a PEM certificate, with the key in
+	// ClientCertificateKey or in the same file, or a PKCS#12 (.p12, .pfx)
+	// bundle. Passphrase decrypts the bundle.
+	ClientCertificate    string
+	ClientCertificateKey string
+	Passphrase           string
+
+	// CACertificates are PEM files of roots trusted in addition to the
+	// system ones.
+	CACertificates []string
+
+	// MinVersion is the lowest TLS version accepted, such as
+	// tls.VersionTLS12; zero keeps Go's default.
+	MinVersion uint16
+	// ServerName overrides the name sent for SNI and checked against the
+	// server certificate.
+	ServerName string
+	// Insecure skips verification of the server certificate, set with
+	// "verifyHostCertificate": false.
+	Insecure bool
+}
+
+// tlsVersions maps "minVersion" values to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseSSLConfiguration reads an environment's "SSLConfiguration" object,
+// either a single configuration for every host, as in the JetBrains HTTP
+// Client, or an object of configurations keyed by host pattern.
+func parseSSLConfiguration(raw json.RawMessage) ([]*TLSConfig, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("SSLConfiguration: %w", err)
+	}
+
+	perHost := len(fields) > 0
+	for _, value := range fields {
+		if !strings.HasPrefix(strings.TrimSpace(string(value)), "{") {
+			perHost = false
+		}
+	}
+	if !perHost {
+		cfg, err := parseTLSConfig("*", fields)
+		if err != nil {
+			return nil, fmt.Errorf("SSLConfiguration: %w", err)
+		}
+		return []*TLSConfig{cfg}, nil
+	}
+
+	hosts := make([]string, 0, len(fields))
+	for host := range fields {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	configs := make([]*TLSConfig, 0, len(hosts))
+	for _, host := range hosts {
+		var hostFields map[string]json.RawMessage
+		if err := json.Unmarshal(fields[host], &hostFields); err != nil {
+			return nil, fmt.Errorf("SSLConfiguration %q: %w", host, err)
+		}
+		cfg, err := parseTLSConfig(host, hostFields)
+		if err != nil {
+			return nil, fmt.Errorf("SSLConfiguration %q: %w", host, err)
+		}
+		configs = append(configs, cfg)
+	}
+	return configs, nil
+}
+
+func parseTLSConfig(host string, fields map[string]json.RawMessage) (*TLSConfig, error) {
+	cfg := &TLSConfig{Host: strings.ToLower(host)}
+	for key, value := range fields {
+		if k := normalizeKey(key); k == "cacertificates" || k == "cacertificate" {
+			var paths []string
+			if err := json.Unmarshal(value, &paths); err != nil {
+				s, ok := scalarJSON(value)
+				if !ok {
+					return nil, fmt.Errorf("%s: expected a path or a list of paths", key)
+				}
+				paths = []string{s}
+			}
+			cfg.CACertificates = append(cfg.CACertificates, paths...)
+			continue
+		}
+
+		s, ok := scalarJSON(value)
+		if !ok {
+			continue
+		}
+		switch normalizeKey(key) {
+		case "clientcertificate":
+			cfg.ClientCertificate = s
+		case "clientcertificatekey":
+			cfg.ClientCertificateKey = s
+		case "passphrase", "certificatepassphrase":
+			cfg.Passphrase = s
+		case "minversion", "mintlsversion":
+			version, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(s), "tlsv")]
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported TLS version %q (use 1.0, 1.1, 1.2 or 1.3)", key, s)
+			}
+			cfg.MinVersion = version
+		case "servername", "sni":
+			cfg.ServerName = s
+		case "verifyhostcertificate":
+			cfg.Insecure = s == "false"
+		}
+	}
+
+	if cfg.ClientCertificateKey != "" && cfg.ClientCertificate == "" {
+		return nil, fmt.Errorf("clientCertificateKey without clientCertificate")
+	}
+	return cfg, nil
+}
@@ -1,5 +1,7 @@
 package parser
 
+import "time"
+
 type Header struct {
 	Key   string
 	Value string
@@ -7,6 +9,7 @@ type Header struct {
 
 type Request struct {
 	ID          string
+	Name        string
 	LineStart   int
 	LineEnd     int
 	Method      string
@@ -14,6 +17,63 @@ type Request struct {
 	Headers     []Header
 	Body        string
 	Description string
+	Assertions  []Assertion
+
+	PreRequestScripts []Script
+	ResponseHandlers  []Script
+
+	// SnapshotIgnore lists JSON paths ($.id) and header names excluded
+	// from snapshot comparison, from "# @snapshot-ignore" annotations.
+	SnapshotIgnore []string
+
+	// NoCookieJar sends the request without stored cookies and without
+	// storing the cookies it receives ("# @no-cookie-jar").
+	NoCookieJar bool
+
+	// Timeout and ConnectionTimeout ("# @timeout 2m",
+	// "# @connection-timeout 2s"), MaxRedirects ("# @max-redirects 3"),
+	// NoRedirect ("# @no-redirect") and Insecure ("# @insecure") override
+	// the executor's settings when set.
+	Timeout           time.Duration
+	ConnectionTimeout time.Duration
+	MaxRedirects      int
+	NoRedirect        bool
+	Insecure          bool
+
+	// Proxy ("# @proxy http://localhost:8888") sends the request through
+	// the given proxy, ignoring NO_PROXY; NoProxy ("# @no-proxy") sends it
+	// directly.
+	Proxy   string
+	NoProxy bool
+
+	// Protos are the .proto files describing the service of a GRPC request
+	// ("# @proto ./api.proto"). Without them the service is looked up with
+	// server reflection.
+	Protos []string
+}
+
+// IsGRPC reports whether req is a gRPC call ("GRPC host:port/package.Service/Method").
+func (r *Request) IsGRPC() bool {
+	return r.Method == "GRPC"
+}
+
+// Script is a JavaScript handler attached to a request: inline source from a
+// {% ... %} block, or a Path to a .js file relative to the .http file.
+type Script struct {
+	Line   int
+	Source string
+	Path   string
+}
+
+// Assertion is a "> assert <subject> <operator> <expected>" check on the
+// response of a request.
+type Assertion struct {
+	Line     int
+	Raw      string
+	Subject  string
+	Path     string
+	Operator string
+	Expected string
 }
 
 type Variable struct {
@@ -23,6 +83,7 @@ type Variable struct {
 }
 
 type ParsedFile struct {
+	Path      string
 	Variables []Variable
 	Requests  []Request
 	RawLines  []string
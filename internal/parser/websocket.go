@@ -0,0 +1,58 @@
+package parser
+
+import "strings"
+
+// IsWebSocket reports whether req opens a WebSocket connection
+// ("WEBSOCKET ws://host/path").
+func (r *Request) IsWebSocket() bool {
+	return r.Method == "WEBSOCKET"
+}
+
+// WebSocketStep is a message sent on a WebSocket connection once Wait
+// messages have been received from the server since the previous step. A
+// step with Send unset only waits, for "=== wait-for-server" lines after the
+// last message.
+type WebSocketStep struct {
+	Wait    int
+	Message string
+	Send    bool
+}
+
+// WebSocketSteps splits the body of a WEBSOCKET request into the messages to
+// send. Messages are separated by "===" lines, and each
+// "=== wait-for-server" line waits for a message from the server before the
+// next message is sent. A body without separators is a single message.
+func WebSocketSteps(body string) []WebSocketStep {
+	var steps []WebSocketStep
+	var message []string
+	wait := 0
+
+	flush := func() {
+		text := strings.Trim(strings.Join(message, "\n"), "\n")
+		message = nil
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		steps = append(steps, WebSocketStep{Wait: wait, Message: text, Send: true})
+		wait = 0
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		rest, ok := strings.CutPrefix(trimmed, "===")
+		if !ok {
+			message = append(message, line)
+			continue
+		}
+		flush()
+		if strings.TrimSpace(rest) == "wait-for-server" {
+			wait++
+		}
+	}
+	flush()
+
+	if wait > 0 {
+		steps = append(steps, WebSocketStep{Wait: wait})
+	}
+	return steps
+}
@@ -0,0 +1,67 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWebSocketSteps(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []WebSocketStep
+	}{
+		{
+			name: "empty",
+			body: "",
+			want: nil,
+		},
+		{
+			name: "single message",
+			body: "{\n  \"type\": \"hello\"\n}\n",
+			want: []WebSocketStep{{Message: "{\n  \"type\": \"hello\"\n}", Send: true}},
+		},
+		{
+			name: "separated messages",
+			body: "one\n===\ntwo\r\n===\r\nthree",
+			want: []WebSocketStep{
+				{Message: "one", Send: true},
+				{Message: "two", Send: true},
+				{Message: "three", Send: true},
+			},
+		},
+		{
+			name: "waits before messages",
+			body: "=== wait-for-server\nhello\n=== wait-for-server\n=== wait-for-server\nbye",
+			want: []WebSocketStep{
+				{Wait: 1, Message: "hello", Send: true},
+				{Wait: 2, Message: "bye", Send: true},
+			},
+		},
+		{
+			name: "trailing waits",
+			body: "subscribe\n=== wait-for-server\n  ===   wait-for-server  ",
+			want: []WebSocketStep{
+				{Message: "subscribe", Send: true},
+				{Wait: 2},
+			},
+		},
+		{
+			name: "blank messages are skipped",
+			body: "\n===\n   \n===\nping\n===\n",
+			want: []WebSocketStep{{Message: "ping", Send: true}},
+		},
+		{
+			name: "indentation is kept",
+			body: "  {\n    \"a\": 1\n  }",
+			want: []WebSocketStep{{Message: "  {\n    \"a\": 1\n  }", Send: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WebSocketSteps(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WebSocketSteps() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
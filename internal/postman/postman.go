@@ -0,0 +1,505 @@
+// Package postman converts Postman v2.1 collections and environments into
+// .http requests and environment variables.
+package postman
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"httpyum/internal/parser"
+)
+
+// formBoundary separates the parts of multipart bodies built from formdata.
+const formBoundary = "----HttpyumFormBoundary"
+
+type collection struct {
+	Info struct {
+		Name   string `json:"name"`
+		Schema string `json:"schema"`
+	} `json:"info"`
+	Item     []item     `json:"item"`
+	Variable []variable `json:"variable"`
+	Auth     *auth      `json:"auth"`
+	Event    []event    `json:"event"`
+}
+
+type item struct {
+	Name    string   `json:"name"`
+	Item    []item   `json:"item"`
+	Request *request `json:"request"`
+	Auth    *auth    `json:"auth"`
+	Event   []event  `json:"event"`
+}
+
+type request struct {
+	Method string          `json:"method"`
+	URL    json.RawMessage `json:"url"`
+	Header []keyValue      `json:"header"`
+	Body   *body           `json:"body"`
+	Auth   *auth           `json:"auth"`
+}
+
+// UnmarshalJSON accepts the short form of a request, which is just its URL.
+func (r *request) UnmarshalJSON(data []byte) error {
+	var rawURL string
+	if json.Unmarshal(data, &rawURL) == nil {
+		r.Method = "GET"
+		r.URL = data
+		return nil
+	}
+	type plain request
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+type body struct {
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw"`
+	URLEncoded []keyValue `json:"urlencoded"`
+	FormData   []keyValue `json:"formdata"`
+	File       struct {
+		Src string `json:"src"`
+	} `json:"file"`
+	GraphQL struct {
+		Query     string `json:"query"`
+		Variables string `json:"variables"`
+	} `json:"graphql"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type url struct {
+	Raw      string     `json:"raw"`
+	Variable []variable `json:"variable"`
+}
+
+type keyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type"`
+	Src         any    `json:"src"`
+	ContentType string `json:"contentType"`
+	Disabled    bool   `json:"disabled"`
+}
+
+type variable struct {
+	Key      string `json:"key"`
+	Value    any    `json:"value"`
+	Disabled bool   `json:"disabled"`
+	Enabled  *bool  `json:"enabled"`
+}
+
+type auth struct {
+	Type   string     `json:"type"`
+	Basic  []variable `json:"basic"`
+	Digest []variable `json:"digest"`
+	Bearer []variable `json:"bearer"`
+	APIKey []variable `json:"apikey"`
+	AWSv4  []variable `json:"awsv4"`
+}
+
+type event struct {
+	Listen string `json:"listen"`
+}
+
+// File is a group of requests destined for one .http file: the requests at
+// the root of the collection, or those in one top-level folder.
+type File struct {
+	Name      string
+	Variables []parser.Variable
+	Requests  []parser.Request
+}
+
+// Collection is a converted collection. Warnings list what could not be
+// converted, such as scripts and unsupported auth types.
+type Collection struct {
+	Name     string
+	Files    []File
+	Warnings []string
+}
+
+// Merged returns the whole collection as a single file. Requests in folders
+// get the folder name as a description prefix.
+func (c *Collection) Merged() File {
+	merged := File{Name: c.Name}
+	for _, f := range c.Files {
+		merged.Variables = appendVariables(merged.Variables, f.Variables...)
+		for _, req := range f.Requests {
+			if f.Name != c.Name {
+				req.Description = f.Name + " / " + req.Description
+			}
+			merged.Requests = append(merged.Requests, req)
+		}
+	}
+	return merged
+}
+
+// ConvertCollection reads a Postman v2.1 collection.
+func ConvertCollection(r io.Reader) (*Collection, error) {
+	var col collection
+	if err := json.NewDecoder(r).Decode(&col); err != nil {
+		return nil, fmt.Errorf("invalid collection: %w", err)
+	}
+	if col.Info.Schema != "" && !strings.Contains(col.Info.Schema, "v2.") {
+		return nil, fmt.Errorf("unsupported collection schema %s (export as v2.1)", col.Info.Schema)
+	}
+
+	c := &converter{result: &Collection{Name: col.Info.Name}}
+	if c.result.Name == "" {
+		c.result.Name = "collection"
+	}
+	if len(col.Event) > 0 {
+		c.warn("collection scripts were not converted")
+	}
+
+	var variables []parser.Variable
+	for _, v := range col.Variable {
+		if v.Disabled {
+			continue
+		}
+		if !variableName.MatchString(v.Key) {
+			c.warn("variable %q is not a valid .http variable name and was skipped", v.Key)
+			continue
+		}
+		variables = append(variables, parser.Variable{Name: v.Key, Value: c.variables(scalar(v.Value))})
+	}
+
+	root := File{Name: c.result.Name}
+	for _, it := range col.Item {
+		if it.Request != nil {
+			root.Requests = append(root.Requests, c.convertItem(it, "", col.Auth))
+			root.Variables = appendVariables(root.Variables, c.urlVariables(it)...)
+			continue
+		}
+		folder := File{Name: it.Name}
+		c.collect(&folder, it, "", col.Auth)
+		c.result.Files = append(c.result.Files, folder)
+	}
+	if len(root.Requests) > 0 || len(c.result.Files) == 0 {
+		c.result.Files = append([]File{root}, c.result.Files...)
+	}
+
+	for i := range c.result.Files {
+		f := &c.result.Files[i]
+		f.Variables = appendVariables(append([]parser.Variable{}, variables...), f.Variables...)
+	}
+	return c.result, nil
+}
+
+// ConvertEnvironment reads a Postman environment export and returns its name
+// and enabled variables.
+func ConvertEnvironment(r io.Reader) (string, map[string]string, error) {
+	var env struct {
+		Name   string     `json:"name"`
+		Values []variable `json:"values"`
+	}
+	if err := json.NewDecoder(r).Decode(&env); err != nil {
+		return "", nil, fmt.Errorf("invalid environment: %w", err)
+	}
+
+	c := &converter{result: &Collection{}}
+	vars := make(map[string]string)
+	for _, v := range env.Values {
+		if v.Enabled != nil && !*v.Enabled {
+			continue
+		}
+		vars[v.Key] = c.variables(scalar(v.Value))
+	}
+	return env.Name, vars, nil
+}
+
+type converter struct {
+	result *Collection
+}
+
+func (c *converter) warn(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	for _, w := range c.result.Warnings {
+		if w == msg {
+			return
+		}
+	}
+	c.result.Warnings = append(c.result.Warnings, msg)
+}
+
+// collect adds the requests in folder it to f. Nested folders are flattened
+// with their names prefixed to the request descriptions.
+func (c *converter) collect(f *File, it item, prefix string, inherited *auth) {
+	if it.Auth != nil {
+		inherited = it.Auth
+	}
+	if len(it.Event) > 0 {
+		c.warn("folder %q: scripts were not converted", it.Name)
+	}
+	for _, child := range it.Item {
+		if child.Request != nil {
+			f.Requests = append(f.Requests, c.convertItem(child, prefix, inherited))
+			f.Variables = appendVariables(f.Variables, c.urlVariables(child)...)
+			continue
+		}
+		c.collect(f, child, prefix+child.Name+" / ", inherited)
+	}
+}
+
+func (c *converter) convertItem(it item, prefix string, inherited *auth) parser.Request {
+	src := it.Request
+	title := prefix + it.Name
+
+	req := parser.Request{
+		Method:      strings.ToUpper(src.Method),
+		Description: title,
+	}
+	if req.Method == "" {
+		req.Method = "GET"
+	}
+	req.URL = c.variables(pathParams.ReplaceAllString(rawURL(src.URL), "/{{$1}}"))
+
+	for _, ev := range it.Event {
+		c.warn("%s: %s script was not converted", title, ev.Listen)
+	}
+
+	for _, h := range src.Header {
+		if h.Disabled {
+			continue
+		}
+		req.Headers = append(req.Headers, parser.Header{Key: h.Key, Value: c.variables(h.Value)})
+	}
+
+	a := src.Auth
+	if a == nil {
+		a = inherited
+	}
+	c.applyAuth(&req, a, title)
+	c.applyBody(&req, src.Body, title)
+	return req
+}
+
+// urlVariables returns the :param path variables of a request that have
+// values, as file variables.
+func (c *converter) urlVariables(it item) []parser.Variable {
+	var u url
+	if json.Unmarshal(it.Request.URL, &u) != nil {
+		return nil
+	}
+	var vars []parser.Variable
+	for _, v := range u.Variable {
+		if value := scalar(v.Value); v.Key != "" && value != "" {
+			vars = append(vars, parser.Variable{Name: v.Key, Value: c.variables(value)})
+		}
+	}
+	return vars
+}
+
+func (c *converter) applyAuth(req *parser.Request, a *auth, title string) {
+	if a == nil {
+		return
+	}
+	params := func(vars []variable) map[string]string {
+		m := make(map[string]string)
+		for _, v := range vars {
+			m[v.Key] = c.variables(scalar(v.Value))
+		}
+		return m
+	}
+
+	switch a.Type {
+	case "noauth", "":
+	case "bearer":
+		token := params(a.Bearer)["token"]
+		req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Bearer " + token})
+	case "basic":
+		p := params(a.Basic)
+		req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Basic " + p["username"] + ":" + p["password"]})
+	case "digest":
+		p := params(a.Digest)
+		req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: "Digest " + p["username"] + ":" + p["password"]})
+	case "awsv4":
+		p := params(a.AWSv4)
+		value := "AWS " + p["accessKey"] + " " + p["secretKey"]
+		for _, opt := range []struct{ name, key string }{
+			{"token", "sessionToken"}, {"region", "region"}, {"service", "service"},
+		} {
+			if p[opt.key] != "" {
+				value += " " + opt.name + ":" + p[opt.key]
+			}
+		}
+		req.Headers = append(req.Headers, parser.Header{Key: "Authorization", Value: value})
+	case "apikey":
+		p := params(a.APIKey)
+		if p["in"] == "query" {
+			sep := "?"
+			if strings.Contains(req.URL, "?") {
+				sep = "&"
+			}
+			req.URL += sep + p["key"] + "=" + p["value"]
+			return
+		}
+		req.Headers = append(req.Headers, parser.Header{Key: p["key"], Value: p["value"]})
+	default:
+		c.warn("%s: %s auth was not converted", title, a.Type)
+	}
+}
+
+func (c *converter) applyBody(req *parser.Request, b *body, title string) {
+	if b == nil {
+		return
+	}
+
+	switch b.Mode {
+	case "", "none":
+	case "raw":
+		req.Body = c.variables(b.Raw)
+		switch b.Options.Raw.Language {
+		case "json":
+			setDefaultHeader(req, "Content-Type", "application/json")
+		case "xml":
+			setDefaultHeader(req, "Content-Type", "application/xml")
+		}
+	case "urlencoded":
+		var pairs []string
+		for _, kv := range b.URLEncoded {
+			if !kv.Disabled {
+				pairs = append(pairs, c.variables(kv.Key)+"="+c.variables(kv.Value))
+			}
+		}
+		req.Body = strings.Join(pairs, "&")
+		setDefaultHeader(req, "Content-Type", "application/x-www-form-urlencoded")
+	case "formdata":
+		var parts []string
+		for _, kv := range b.FormData {
+			if kv.Disabled {
+				continue
+			}
+			part := "--" + formBoundary + "\n"
+			if kv.Type == "file" {
+				path := scalar(kv.Src)
+				if path == "" {
+					c.warn("%s: form file %q has no source and was skipped", title, kv.Key)
+					continue
+				}
+				filename := path[strings.LastIndex(path, "/")+1:]
+				part += fmt.Sprintf("Content-Disposition: form-data; name=%q; filename=%q\n", kv.Key, filename)
+				if kv.ContentType != "" {
+					part += "Content-Type: " + kv.ContentType + "\n"
+				}
+				part += "\n< " + path
+			} else {
+				part += fmt.Sprintf("Content-Disposition: form-data; name=%q\n\n%s", kv.Key, c.variables(kv.Value))
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) > 0 {
+			req.Body = strings.Join(parts, "\n") + "\n--" + formBoundary + "--"
+			setDefaultHeader(req, "Content-Type", "multipart/form-data; boundary="+formBoundary)
+		}
+	case "file":
+		if b.File.Src == "" {
+			c.warn("%s: file body has no source", title)
+			return
+		}
+		req.Body = "< " + b.File.Src
+	case "graphql":
+		body := strings.TrimSpace(b.GraphQL.Query)
+		if vars := strings.TrimSpace(b.GraphQL.Variables); vars != "" {
+			var object map[string]json.RawMessage
+			if json.Unmarshal([]byte(vars), &object) == nil {
+				body += "\n\n" + vars
+			} else {
+				c.warn("%s: graphql variables are not a JSON object and were dropped", title)
+			}
+		}
+		req.Body = c.variables(body)
+		setDefaultHeader(req, parser.GraphQLHeader, "GraphQL")
+	default:
+		c.warn("%s: %s body was not converted", title, b.Mode)
+	}
+}
+
+var (
+	pathParams       = regexp.MustCompile(`/:([A-Za-z_]\w*)`)
+	variableName     = regexp.MustCompile(`^\w+$`)
+	dynamicVariables = regexp.MustCompile(`\{\{\s*\$(\w+)\s*\}\}`)
+)
+
+// dynamicReplacements maps Postman dynamic variables onto the built-in
+// ones. Others are left as they are and reported.
+var dynamicReplacements = map[string]string{
+	"guid":         "{{$guid}}",
+	"randomUUID":   "{{$uuid}}",
+	"timestamp":    "{{$timestamp}}",
+	"isoTimestamp": "{{$datetime iso8601}}",
+	"randomInt":    "{{$randomInt 0 1001}}",
+}
+
+// variables rewrites Postman dynamic variables in s. Regular {{name}}
+// variables use the same syntax and are kept as they are.
+func (c *converter) variables(s string) string {
+	return dynamicVariables.ReplaceAllStringFunc(s, func(match string) string {
+		name := dynamicVariables.FindStringSubmatch(match)[1]
+		if replacement, ok := dynamicReplacements[name]; ok {
+			return replacement
+		}
+		c.warn("dynamic variable {{$%s}} has no equivalent", name)
+		return match
+	})
+}
+
+func rawURL(data json.RawMessage) string {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		return s
+	}
+	var u url
+	if json.Unmarshal(data, &u) == nil {
+		return u.Raw
+	}
+	return ""
+}
+
+func scalar(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		if len(v) > 0 {
+			return scalar(v[0])
+		}
+		return ""
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+func setDefaultHeader(req *parser.Request, key, value string) {
+	for _, h := range req.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return
+		}
+	}
+	req.Headers = append(req.Headers, parser.Header{Key: key, Value: value})
+}
+
+// appendVariables appends the variables not already defined in vars.
+func appendVariables(vars []parser.Variable, more ...parser.Variable) []parser.Variable {
+	for _, v := range more {
+		defined := false
+		for _, existing := range vars {
+			if existing.Name == v.Name {
+				defined = true
+				break
+			}
+		}
+		if !defined {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
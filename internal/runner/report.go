@@ -0,0 +1,135 @@
+package runner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// writeJUnit writes one test case per request in JUnit XML format.
+// Transport errors are reported as errors and failed checks as failures.
+func writeJUnit(w io.Writer, suiteName string, cases []caseResult) error {
+	suite := junitTestSuite{Name: suiteName}
+	var total float64
+
+	for _, c := range cases {
+		req := c.result.Request
+		tc := junitTestCase{
+			Name:      requestTitle(req),
+			Classname: suiteName,
+			Time:      "0.000",
+		}
+		if c.result.Response != nil {
+			secs := c.result.Response.Duration.Seconds()
+			total += secs
+			tc.Time = fmt.Sprintf("%.3f", secs)
+			tc.SystemOut = fmt.Sprintf("%s %s -> %s", req.Method, resolvedURL(c), c.result.Response.Status)
+		}
+
+		switch {
+		case c.result.Error != nil:
+			tc.Error = &junitMessage{Message: "request failed", Body: c.result.Error.Error()}
+			suite.Errors++
+		case !c.passed:
+			tc.Failure = &junitMessage{Message: c.failures[0], Body: strings.Join(c.failures, "\n")}
+			suite.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+
+	doc := junitTestSuites{
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP writes the results in Test Anything Protocol version 13 format.
+func writeTAP(w io.Writer, suiteName string, cases []caseResult) error {
+	var sb strings.Builder
+	sb.WriteString("TAP version 13\n")
+	if suiteName != "" {
+		fmt.Fprintf(&sb, "# %s\n", suiteName)
+	}
+	fmt.Fprintf(&sb, "1..%d\n", len(cases))
+
+	for i, c := range cases {
+		status := "ok"
+		if !c.passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(&sb, "%s %d - %s\n", status, i+1, requestTitle(c.result.Request))
+
+		if !c.passed {
+			sb.WriteString("  ---\n")
+			sb.WriteString("  failures:\n")
+			for _, f := range c.failures {
+				fmt.Fprintf(&sb, "    - %q\n", f)
+			}
+			if c.result.Response != nil {
+				fmt.Fprintf(&sb, "  status: %d\n", c.result.Response.StatusCode)
+				fmt.Fprintf(&sb, "  duration_ms: %d\n", c.result.Response.Duration.Milliseconds())
+			}
+			sb.WriteString("  ...\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func resolvedURL(c caseResult) string {
+	if c.result.Resolved != nil {
+		return c.result.Resolved.URL
+	}
+	return c.result.Request.URL
+}
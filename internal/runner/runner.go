@@ -0,0 +1,384 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/history"
+	"httpyum/internal/parser"
+	"httpyum/internal/snapshot"
+)
+
+// Options controls which requests are run and how results are printed.
+type Options struct {
+	Environment  map[string]string
+	OAuth2       map[string]*parser.OAuth2Config
+	Tokens       *client.TokenCache
+	Cookies      http.CookieJar
+	Settings     client.Settings
+	TLS          []*parser.TLSConfig
+	Selectors    []string
+	ExpectStatus string
+	ShowHeaders  bool
+	ShowBody     bool
+	FailFast     bool
+
+	// Report selects a machine-readable report format ("junit" or "tap").
+	// It is written to ReportOut, or to out instead of the plain-text
+	// output when ReportOut is nil.
+	Report    string
+	ReportOut io.Writer
+	SuiteName string
+
+	// History records every result when set. EnvironmentName is stored
+	// with each entry.
+	History         *history.Store
+	EnvironmentName string
+
+	// Snapshots compares each response with its recorded snapshot, which
+	// fails the request on drift. Requests without a snapshot, or all
+	// requests when UpdateSnapshots is set, have theirs recorded. The
+	// default status check is skipped in this mode.
+	Snapshots       *snapshot.File
+	SnapshotRules   snapshot.Rules
+	UpdateSnapshots bool
+}
+
+// Summary counts the outcome of a headless run.
+type Summary struct {
+	Total  int
+	Passed int
+	Failed int
+
+	// SnapshotsWritten counts snapshots recorded or updated.
+	SnapshotsWritten int
+}
+
+// caseResult is the outcome of one request in a run.
+type caseResult struct {
+	result   *client.ExecutionResult
+	passed   bool
+	failures []string
+	notes    []string
+}
+
+// Run executes the selected requests in file order and writes a plain-text
+// report of each result to out. A request fails when it cannot be sent, an
+// assertion fails, or its status code is not accepted by opts.ExpectStatus.
+// Requests with assertions skip the default status check, and with it the
+// check that a GraphQL response reports no errors.
+func Run(out io.Writer, parsedFile *parser.ParsedFile, envVars map[string]string, opts Options) (*Summary, error) {
+	requests, err := parser.SelectRequests(parsedFile.Requests, opts.Selectors)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher, err := parseStatusMatcher(opts.ExpectStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	var writeReport func(io.Writer, string, []caseResult) error
+	switch opts.Report {
+	case "":
+	case "junit":
+		writeReport = writeJUnit
+	case "tap":
+		writeReport = writeTAP
+	default:
+		return nil, fmt.Errorf("unknown report format %q (use junit or tap)", opts.Report)
+	}
+
+	human := out
+	reportOut := opts.ReportOut
+	if writeReport != nil && reportOut == nil {
+		human = io.Discard
+		reportOut = out
+	}
+
+	variables := parser.BuildVariableMap(parsedFile.Variables, envVars, opts.Environment)
+	executor := client.NewExecutor(variables, client.Options{
+		Requests: parsedFile.Requests,
+		BaseDir:  filepath.Dir(parsedFile.Path),
+		OAuth2:   opts.OAuth2,
+		Tokens:   opts.Tokens,
+		Cookies:  opts.Cookies,
+		Settings: opts.Settings,
+		TLS:      opts.TLS,
+	})
+
+	summary := &Summary{}
+	var cases []caseResult
+	for i := range requests {
+		req := &requests[i]
+		result := executor.Execute(context.Background(), req)
+		if opts.History != nil {
+			if err := opts.History.Add(history.NewEntry(result, parsedFile.Path, opts.EnvironmentName)); err != nil {
+				fmt.Fprintf(human, "warning: could not save history: %v\n", err)
+			}
+		}
+
+		c := caseResult{result: result}
+		if result.Error != nil {
+			c.failures = append(c.failures, result.Error.Error())
+		} else {
+			checkStatus := opts.ExpectStatus != "" || len(req.Assertions) == 0 && opts.Snapshots == nil
+			if checkStatus && !matcher(result.Response.StatusCode) {
+				c.failures = append(c.failures, fmt.Sprintf("unexpected status %s", result.Response.Status))
+			}
+			if checkStatus {
+				for _, e := range result.GraphQLErrors {
+					c.failures = append(c.failures, "graphql error: "+e.String())
+				}
+			}
+			for _, a := range result.Assertions {
+				if !a.Passed {
+					c.failures = append(c.failures, fmt.Sprintf("assert %s: %s", a.Name, a.Message))
+				}
+			}
+			if opts.Snapshots != nil {
+				checkSnapshot(&c, req, opts, summary)
+			}
+		}
+		c.passed = len(c.failures) == 0
+		cases = append(cases, c)
+
+		summary.Total++
+		if c.passed {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+
+		printResult(human, result, c.passed, c.notes, opts)
+
+		if !c.passed && opts.FailFast {
+			break
+		}
+	}
+
+	fmt.Fprintf(human, "%d requests, %d passed, %d failed\n", summary.Total, summary.Passed, summary.Failed)
+
+	if opts.Snapshots != nil && summary.SnapshotsWritten > 0 {
+		if err := opts.Snapshots.Save(); err != nil {
+			return nil, fmt.Errorf("error saving snapshots: %w", err)
+		}
+		fmt.Fprintf(human, "%d snapshots written to %s\n", summary.SnapshotsWritten, opts.Snapshots.Path())
+	}
+
+	if writeReport != nil {
+		if err := writeReport(reportOut, opts.SuiteName, cases); err != nil {
+			return nil, fmt.Errorf("error writing report: %w", err)
+		}
+	}
+
+	return summary, nil
+}
+
+// requestTitle names a request in output: its description, name or ID.
+func requestTitle(req *parser.Request) string {
+	if req.Description != "" {
+		return req.Description
+	}
+	if req.Name != "" {
+		return req.Name
+	}
+	return req.ID
+}
+
+// PrintResult writes result in the plain-text format used by Run.
+func PrintResult(out io.Writer, result *client.ExecutionResult, opts Options) {
+	printResult(out, result, result.Error == nil && result.FailedAssertions() == 0, nil, opts)
+}
+
+// checkSnapshot records the snapshot of c's response, or compares the
+// response with the recorded one and fails c on drift.
+func checkSnapshot(c *caseResult, req *parser.Request, opts Options, summary *Summary) {
+	rules := opts.SnapshotRules.ForRequest(req)
+	key := snapshot.Key(req)
+	current := snapshot.New(req, c.result.Response, rules)
+
+	recorded, ok := opts.Snapshots.Snapshots[key]
+	if !ok || opts.UpdateSnapshots {
+		opts.Snapshots.Snapshots[key] = current
+		summary.SnapshotsWritten++
+		if ok {
+			c.notes = append(c.notes, "snapshot updated")
+		} else {
+			c.notes = append(c.notes, "snapshot written")
+		}
+		return
+	}
+
+	changes := snapshot.Compare(recorded, current, rules).Changes()
+	if len(changes) == 0 {
+		c.notes = append(c.notes, "snapshot matches")
+		return
+	}
+	c.failures = append(c.failures, fmt.Sprintf("snapshot drift: %d differences", len(changes)))
+	c.notes = append(c.notes, "snapshot drift:")
+	for _, change := range changes {
+		c.failures = append(c.failures, change.String())
+		c.notes = append(c.notes, "  "+change.String())
+	}
+}
+
+func printResult(out io.Writer, result *client.ExecutionResult, ok bool, notes []string, opts Options) {
+	req := result.Request
+
+	mark := "✓"
+	if !ok {
+		mark = "✗"
+	}
+
+	title := requestTitle(req)
+	url := req.URL
+	if result.Resolved != nil {
+		url = result.Resolved.URL
+	}
+	fmt.Fprintf(out, "%s %s\n", mark, title)
+	fmt.Fprintf(out, "  %s %s\n", req.Method, url)
+	if result.Response != nil && result.Response.Proxy != "" {
+		fmt.Fprintf(out, "  via proxy %s\n", result.Response.Proxy)
+	}
+
+	for _, line := range result.Logs {
+		fmt.Fprintf(out, "  log: %s\n", line)
+	}
+
+	for _, frame := range result.Frames {
+		arrow := "←"
+		if frame.Sent {
+			arrow = "→"
+		}
+		text := strings.ReplaceAll(strings.TrimRight(frame.Text(), "\n"), "\n", "\n    ")
+		fmt.Fprintf(out, "  %s %s\n", arrow, text)
+	}
+
+	if result.Error != nil {
+		fmt.Fprintf(out, "  error: %v\n\n", result.Error)
+		return
+	}
+
+	resp := result.Response
+	fmt.Fprintf(out, "  %s | %s | %s\n", resp.Status, resp.Duration.String(), client.FormatSize(resp.Size))
+	if result.Stopped {
+		fmt.Fprintf(out, "  stream stopped at the timeout after %d events\n", len(result.Events))
+	}
+
+	for _, a := range result.Assertions {
+		if a.Passed {
+			fmt.Fprintf(out, "  ✓ %s\n", a.Name)
+		} else {
+			fmt.Fprintf(out, "  ✗ %s (%s)\n", a.Name, a.Message)
+		}
+	}
+
+	for _, e := range result.GraphQLErrors {
+		fmt.Fprintf(out, "  graphql error: %s\n", e)
+	}
+
+	for _, note := range notes {
+		fmt.Fprintf(out, "  %s\n", note)
+	}
+
+	if opts.ShowHeaders && len(resp.Headers) > 0 {
+		fmt.Fprintln(out)
+		printHeaders(out, resp.Headers)
+	}
+	if opts.ShowHeaders && len(resp.Trailers) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "  Trailers:")
+		printHeaders(out, resp.Trailers)
+	}
+
+	if opts.ShowBody && len(resp.Body) > 0 {
+		body := string(resp.Body)
+		switch {
+		case client.IsStreaming(resp.ContentType) && !client.IsEventStream(resp.ContentType):
+			// JSON lines are pretty-printed one by one.
+			var lines []string
+			for _, e := range client.ParseStream(resp.ContentType, resp.Body) {
+				line, _ := client.PrettyPrintJSON([]byte(e.Data))
+				lines = append(lines, line)
+			}
+			body = strings.Join(lines, "\n")
+		case client.IsJSON(resp.ContentType):
+			if pretty, err := client.PrettyPrintJSON(resp.Body); err == nil {
+				body = pretty
+			}
+		}
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, strings.TrimRight(body, "\n"))
+	}
+
+	fmt.Fprintln(out)
+}
+
+func printHeaders(out io.Writer, headers http.Header) {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(out, "  %s: %s\n", key, strings.Join(headers[key], ", "))
+	}
+}
+
+// parseStatusMatcher builds a predicate from a comma-separated list of status
+// codes ("200"), classes ("2xx") and ranges ("200-299"). An empty spec accepts
+// every status below 400.
+func parseStatusMatcher(spec string) (func(int) bool, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return func(code int) bool { return code > 0 && code < 400 }, nil
+	}
+
+	type statusRange struct{ lo, hi int }
+	var ranges []statusRange
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		switch {
+		case part == "":
+			continue
+		case len(part) == 3 && strings.HasSuffix(part, "xx"):
+			class, err := strconv.Atoi(part[:1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid status class: %s", part)
+			}
+			ranges = append(ranges, statusRange{class * 100, class*100 + 99})
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err1 := strconv.Atoi(strings.TrimSpace(bounds[0]))
+			hi, err2 := strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err1 != nil || err2 != nil || lo > hi {
+				return nil, fmt.Errorf("invalid status range: %s", part)
+			}
+			ranges = append(ranges, statusRange{lo, hi})
+		default:
+			code, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid status code: %s", part)
+			}
+			ranges = append(ranges, statusRange{code, code})
+		}
+	}
+
+	return func(code int) bool {
+		for _, r := range ranges {
+			if code >= r.lo && code <= r.hi {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
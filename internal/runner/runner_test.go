@@ -0,0 +1,46 @@
+package runner
+
+import "testing"
+
+func TestParseStatusMatcher(t *testing.T) {
+	tests := []struct {
+		spec   string
+		accept []int
+		reject []int
+	}{
+		{spec: "", accept: []int{200, 204, 301, 399}, reject: []int{0, 400, 404, 500}},
+		{spec: "  ", accept: []int{200}, reject: []int{500}},
+		{spec: "200", accept: []int{200}, reject: []int{201, 404}},
+		{spec: "2xx", accept: []int{200, 299}, reject: []int{199, 300}},
+		{spec: "4XX", accept: []int{400, 404, 499}, reject: []int{200, 500}},
+		{spec: "200-204", accept: []int{200, 202, 204}, reject: []int{199, 205}},
+		{spec: "200 - 204", accept: []int{204}, reject: []int{205}},
+		{spec: "2xx, 404,500-503", accept: []int{201, 404, 502}, reject: []int{403, 504}},
+		{spec: "201,,", accept: []int{201}, reject: []int{200}},
+	}
+	for _, tt := range tests {
+		match, err := parseStatusMatcher(tt.spec)
+		if err != nil {
+			t.Errorf("parseStatusMatcher(%q) error = %v", tt.spec, err)
+			continue
+		}
+		for _, code := range tt.accept {
+			if !match(code) {
+				t.Errorf("parseStatusMatcher(%q) rejects %d", tt.spec, code)
+			}
+		}
+		for _, code := range tt.reject {
+			if match(code) {
+				t.Errorf("parseStatusMatcher(%q) accepts %d", tt.spec, code)
+			}
+		}
+	}
+}
+
+func TestParseStatusMatcherErrors(t *testing.T) {
+	for _, spec := range []string{"abc", "axx", "300-200", "200-", "-200", "2xx,ok"} {
+		if _, err := parseStatusMatcher(spec); err == nil {
+			t.Errorf("parseStatusMatcher(%q) succeeded, want an error", spec)
+		}
+	}
+}
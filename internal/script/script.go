@@ -0,0 +1,258 @@
+// Package script runs JetBrains-style pre-request and response handler
+// scripts with an embedded JavaScript engine.
+package script
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// timeout bounds how long a single script may run.
+const timeout = 5 * time.Second
+
+// Request is the request as seen by a script.
+type Request struct {
+	Method  string
+	URL     string
+	Headers http.Header
+	Body    string
+
+	// Variables are request-scoped values set with request.variables.set.
+	Variables map[string]string
+	// Environment holds the resolved file and environment variables.
+	Environment map[string]string
+}
+
+// Response is the response passed to response handlers.
+type Response struct {
+	Status      int
+	Headers     http.Header
+	Body        []byte
+	ContentType string
+}
+
+// TestResult is the outcome of a client.test block.
+type TestResult struct {
+	Name    string
+	Passed  bool
+	Message string
+}
+
+// Result collects what a script reported.
+type Result struct {
+	Tests []TestResult
+	Logs  []string
+}
+
+// RunPreRequest runs a pre-request script. It may read and write globals
+// (client.global) and req.Variables (request.variables).
+func RunPreRequest(name, source string, globals map[string]string, req *Request) (*Result, error) {
+	return run(name, source, globals, req, nil)
+}
+
+// RunResponseHandler runs a response handler script with access to resp.
+func RunResponseHandler(name, source string, globals map[string]string, req *Request, resp *Response) (*Result, error) {
+	return run(name, source, globals, req, resp)
+}
+
+func run(name, source string, globals map[string]string, req *Request, resp *Response) (*Result, error) {
+	vm := goja.New()
+	result := &Result{}
+
+	if err := vm.Set("client", newClient(vm, globals, result)); err != nil {
+		return nil, err
+	}
+	if err := vm.Set("request", newRequest(vm, req)); err != nil {
+		return nil, err
+	}
+	if resp != nil {
+		if err := vm.Set("response", newResponse(vm, resp)); err != nil {
+			return nil, err
+		}
+	}
+	console := vm.NewObject()
+	console.Set("log", logFunc(result))
+	vm.Set("console", console)
+
+	timer := time.AfterFunc(timeout, func() {
+		vm.Interrupt(fmt.Sprintf("script timed out after %s", timeout))
+	})
+	defer timer.Stop()
+
+	if _, err := vm.RunScript(name, source); err != nil {
+		return result, scriptError(err)
+	}
+	return result, nil
+}
+
+func newClient(vm *goja.Runtime, globals map[string]string, result *Result) *goja.Object {
+	global := vm.NewObject()
+	global.Set("set", func(name string, value goja.Value) {
+		globals[name] = valueString(value)
+	})
+	global.Set("get", func(name string) goja.Value {
+		if v, ok := globals[name]; ok {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+	global.Set("isEmpty", func() bool {
+		return len(globals) == 0
+	})
+	global.Set("clear", func(name string) {
+		delete(globals, name)
+	})
+	global.Set("clearAll", func() {
+		for k := range globals {
+			delete(globals, k)
+		}
+	})
+
+	c := vm.NewObject()
+	c.Set("global", global)
+	c.Set("log", logFunc(result))
+	c.Set("test", func(name string, fn goja.Callable) {
+		_, err := fn(goja.Undefined())
+		test := TestResult{Name: name, Passed: err == nil}
+		if err != nil {
+			test.Message = scriptError(err).Error()
+		}
+		result.Tests = append(result.Tests, test)
+	})
+	c.Set("assert", func(call goja.FunctionCall) goja.Value {
+		if !call.Argument(0).ToBoolean() {
+			msg := "assertion failed"
+			if len(call.Arguments) > 1 {
+				msg = call.Argument(1).String()
+			}
+			panic(vm.NewGoError(errors.New(msg)))
+		}
+		return goja.Undefined()
+	})
+	return c
+}
+
+func newRequest(vm *goja.Runtime, req *Request) *goja.Object {
+	variables := vm.NewObject()
+	variables.Set("set", func(name string, value goja.Value) {
+		req.Variables[name] = valueString(value)
+	})
+	variables.Set("get", func(name string) goja.Value {
+		if v, ok := req.Variables[name]; ok {
+			return vm.ToValue(v)
+		}
+		if v, ok := req.Environment[name]; ok {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+
+	environment := vm.NewObject()
+	environment.Set("get", func(name string) goja.Value {
+		if v, ok := req.Environment[name]; ok {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+
+	raw := func(s string) *goja.Object {
+		o := vm.NewObject()
+		o.Set("getRaw", func() string { return s })
+		return o
+	}
+
+	r := vm.NewObject()
+	r.Set("method", req.Method)
+	r.Set("url", raw(req.URL))
+	r.Set("body", raw(req.Body))
+	r.Set("headers", newHeaders(vm, req.Headers))
+	r.Set("variables", variables)
+	r.Set("environment", environment)
+	return r
+}
+
+func newResponse(vm *goja.Runtime, resp *Response) *goja.Object {
+	body := vm.ToValue(string(resp.Body))
+	mimeType, params, _ := mime.ParseMediaType(resp.ContentType)
+	if strings.Contains(mimeType, "json") && len(resp.Body) > 0 {
+		parse, ok := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("parse"))
+		if ok {
+			if parsed, err := parse(goja.Undefined(), body); err == nil {
+				body = parsed
+			}
+		}
+	}
+
+	contentType := vm.NewObject()
+	contentType.Set("mimeType", mimeType)
+	contentType.Set("charset", params["charset"])
+
+	r := vm.NewObject()
+	r.Set("status", resp.Status)
+	r.Set("body", body)
+	r.Set("headers", newHeaders(vm, resp.Headers))
+	r.Set("contentType", contentType)
+	return r
+}
+
+func newHeaders(vm *goja.Runtime, headers http.Header) *goja.Object {
+	h := vm.NewObject()
+	h.Set("valueOf", func(name string) goja.Value {
+		if v := headers.Get(name); v != "" {
+			return vm.ToValue(v)
+		}
+		return goja.Null()
+	})
+	h.Set("valuesOf", func(name string) []string {
+		return headers.Values(name)
+	})
+	return h
+}
+
+func logFunc(result *Result) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		parts := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			parts[i] = valueString(arg)
+		}
+		result.Logs = append(result.Logs, strings.Join(parts, " "))
+		return goja.Undefined()
+	}
+}
+
+// valueString converts a JS value to the string stored in a variable:
+// objects and arrays become JSON, everything else its string form.
+func valueString(v goja.Value) string {
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return ""
+	}
+	if obj, ok := v.(*goja.Object); ok && obj.ClassName() != "String" {
+		if data, err := obj.MarshalJSON(); err == nil {
+			return string(data)
+		}
+	}
+	return v.String()
+}
+
+func scriptError(err error) error {
+	var exc *goja.Exception
+	if errors.As(err, &exc) {
+		if obj, ok := exc.Value().(*goja.Object); ok {
+			if msg := obj.Get("message"); msg != nil && !goja.IsUndefined(msg) {
+				return errors.New(msg.String())
+			}
+		}
+		return errors.New(exc.Value().String())
+	}
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return fmt.Errorf("%v", interrupted.Value())
+	}
+	return err
+}
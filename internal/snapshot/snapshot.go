@@ -0,0 +1,255 @@
+// Package snapshot records normalized responses next to a .http file and
+// compares later responses against them to detect drift.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/diff"
+	"httpyum/internal/jsonpath"
+	"httpyum/internal/parser"
+)
+
+// Dir is the directory, next to the .http file, that holds snapshots.
+const Dir = "__snapshots__"
+
+// ignored replaces the values of ignored JSON paths.
+const ignored = "<ignored>"
+
+// DefaultIgnoreHeaders are headers that change on every response.
+var DefaultIgnoreHeaders = diff.VolatileHeaders
+
+// Rules exclude volatile values from snapshots. Paths are JSONPath
+// expressions into the body; headers are matched case-insensitively.
+type Rules struct {
+	IgnorePaths   []string
+	IgnoreHeaders []string
+}
+
+// ForRequest returns the rules combined with the request's
+// "# @snapshot-ignore" entries: those starting with $ are JSON paths,
+// others header names.
+func (r Rules) ForRequest(req *parser.Request) Rules {
+	out := Rules{
+		IgnorePaths:   append([]string(nil), r.IgnorePaths...),
+		IgnoreHeaders: append([]string(nil), r.IgnoreHeaders...),
+	}
+	for _, item := range req.SnapshotIgnore {
+		if strings.HasPrefix(item, "$") {
+			out.IgnorePaths = append(out.IgnorePaths, item)
+		} else {
+			out.IgnoreHeaders = append(out.IgnoreHeaders, item)
+		}
+	}
+	return out
+}
+
+// Validate reports the first invalid JSON path.
+func (r Rules) Validate() error {
+	for _, p := range r.IgnorePaths {
+		if _, err := jsonpath.Parse(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot is the recorded response to one request.
+type Snapshot struct {
+	Request string            `json:"request"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+	Text    string            `json:"text,omitempty"`
+}
+
+// Key identifies the snapshot of req: its @name, or its ID when unnamed.
+func Key(req *parser.Request) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	return req.ID
+}
+
+// New records resp to req, applying rules.
+func New(req *parser.Request, resp *client.Response, rules Rules) *Snapshot {
+	s := &Snapshot{
+		Request: req.Method + " " + req.URL,
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+	}
+	for k, v := range resp.Headers {
+		s.Headers[http.CanonicalHeaderKey(k)] = strings.Join(v, ", ")
+	}
+
+	var doc any
+	if decodeJSON(resp.Body, &doc) {
+		if data, err := marshal(doc); err == nil {
+			s.Body = data
+		}
+	}
+	if s.Body == nil && len(resp.Body) > 0 {
+		s.Text = string(resp.Body)
+	}
+	return s.normalize(rules)
+}
+
+// normalize returns a copy of s without ignored headers and with ignored
+// JSON paths replaced.
+func (s *Snapshot) normalize(rules Rules) *Snapshot {
+	out := *s
+	out.Headers = make(map[string]string, len(s.Headers))
+	for k, v := range s.Headers {
+		if !ignoreHeader(k, rules) {
+			out.Headers[k] = v
+		}
+	}
+
+	if len(s.Body) > 0 && len(rules.IgnorePaths) > 0 {
+		var doc any
+		if decodeJSON(s.Body, &doc) {
+			for _, expr := range rules.IgnorePaths {
+				if p, err := jsonpath.Parse(expr); err == nil {
+					doc = p.Replace(doc, ignored)
+				}
+			}
+			if data, err := marshal(doc); err == nil {
+				out.Body = data
+			}
+		}
+	}
+	return &out
+}
+
+func ignoreHeader(name string, rules Rules) bool {
+	for _, list := range [][]string{DefaultIgnoreHeaders, rules.IgnoreHeaders} {
+		for _, h := range list {
+			if strings.EqualFold(h, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Compare reports how current differs from the recorded snapshot. Both are
+// normalized with rules, so rules added after recording apply too.
+func Compare(recorded, current *Snapshot, rules Rules) *diff.Result {
+	a, b := recorded.normalize(rules), current.normalize(rules)
+
+	r := &diff.Result{}
+	if a.Status != b.Status {
+		r.Status = &diff.Change{
+			Path: "status",
+			Kind: diff.Changed,
+			Old:  fmt.Sprint(a.Status),
+			New:  fmt.Sprint(b.Status),
+		}
+	}
+
+	r.Headers = diff.Headers(toHeader(a.Headers), toHeader(b.Headers))
+
+	var av, bv any
+	switch {
+	case decodeJSON(a.Body, &av) && decodeJSON(b.Body, &bv):
+		r.JSON = true
+		r.Body = diff.JSON(av, bv)
+	default:
+		r.Body = diff.Lines(a.bodyText(), b.bodyText())
+	}
+	return r
+}
+
+func (s *Snapshot) bodyText() string {
+	if len(s.Body) > 0 {
+		return string(s.Body)
+	}
+	return s.Text
+}
+
+func toHeader(m map[string]string) http.Header {
+	h := make(http.Header, len(m))
+	for k, v := range m {
+		h[k] = []string{v}
+	}
+	return h
+}
+
+func decodeJSON(data []byte, v *any) bool {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v) == nil && !dec.More()
+}
+
+// File holds the snapshots of one .http file.
+type File struct {
+	path      string
+	Snapshots map[string]*Snapshot
+}
+
+// PathFor returns where the snapshots of httpFile are stored:
+// __snapshots__/<name>.snap.json next to it.
+func PathFor(httpFile string) string {
+	base := strings.TrimSuffix(filepath.Base(httpFile), filepath.Ext(httpFile))
+	return filepath.Join(filepath.Dir(httpFile), Dir, base+".snap.json")
+}
+
+// Load reads the snapshots of httpFile. A missing file yields no snapshots.
+func Load(httpFile string) (*File, error) {
+	f := &File{path: PathFor(httpFile), Snapshots: make(map[string]*Snapshot)}
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.Snapshots); err != nil {
+		return nil, fmt.Errorf("invalid snapshot file %s: %w", f.path, err)
+	}
+	if f.Snapshots == nil {
+		f.Snapshots = make(map[string]*Snapshot)
+	}
+	return f, nil
+}
+
+// Path returns the file the snapshots are saved to.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Save writes the snapshots, creating the snapshot directory if needed.
+func (f *File) Save() error {
+	data, err := marshal(f.Snapshots)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, append(data, '\n'), 0o644)
+}
+
+// marshal indents v without escaping <, > and &, which keeps snapshots of
+// HTML and ignored values readable.
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
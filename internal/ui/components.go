@@ -49,6 +49,8 @@ func RenderHelpBar(currentView ViewType) string {
 			"↑/↓: navigate",
 			"/: filter",
 			"enter: execute",
+			"c: curl",
+			"x: compare",
 			"q: quit",
 		}
 	case ViewResponse:
@@ -57,18 +59,51 @@ func RenderHelpBar(currentView ViewType) string {
 			"f: interactive JSON",
 			"h: toggle headers",
 			"v: toggle variables",
+			"D: diff previous",
 			"esc/b: back",
 			"q: quit",
 		}
 	case ViewLoading:
 		shortcuts = []string{
-			"Loading...",
+			"esc/ctrl+c: cancel",
 		}
 	case ViewError:
 		shortcuts = []string{
 			"esc/b: back to list",
 			"q: quit",
 		}
+	case ViewCurl:
+		shortcuts = []string{
+			"esc/b: back to list",
+			"q: quit",
+		}
+	case ViewHistory:
+		shortcuts = []string{
+			"↑/↓: navigate",
+			"/: filter",
+			"enter: open",
+			"x: compare",
+			"esc: back",
+		}
+	case ViewDiff:
+		shortcuts = []string{
+			"↑/↓: scroll",
+			"esc/b: back",
+			"q: quit",
+		}
+	case ViewWebSocket:
+		shortcuts = []string{
+			"enter: send",
+			"↑/↓: scroll",
+			"esc: close",
+			"ctrl+c: quit",
+		}
+	case ViewEnvironments:
+		shortcuts = []string{
+			"↑/↓: navigate",
+			"enter: switch",
+			"esc: back",
+		}
 	}
 
 	return helpStyle.Render(strings.Join(shortcuts, " • "))
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+
+	"httpyum/internal/cookies"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type cookieItem struct {
+	cookie cookies.Cookie
+}
+
+func (i cookieItem) FilterValue() string {
+	return i.cookie.Domain + " " + i.cookie.Name
+}
+
+func (i cookieItem) Title() string {
+	return fmt.Sprintf("%s = %s", i.cookie.Name, maskValue(i.cookie.Value))
+}
+
+func (i cookieItem) Description() string {
+	domain := i.cookie.Domain
+	if !i.cookie.HostOnly {
+		domain = "." + domain
+	}
+	desc := domain + i.cookie.Path
+	if i.cookie.Expires.IsZero() {
+		desc += " · session"
+	} else {
+		desc += " · expires " + i.cookie.Expires.Local().Format("2006-01-02 15:04:05")
+	}
+	if i.cookie.Secure {
+		desc += " · secure"
+	}
+	if i.cookie.HttpOnly {
+		desc += " · httponly"
+	}
+	return desc
+}
+
+func newCookieList() list.Model {
+	cookieList := list.New(nil, list.NewDefaultDelegate(), 0, listHeight)
+	cookieList.SetShowStatusBar(true)
+	cookieList.SetFilteringEnabled(true)
+	cookieList.SetShowHelp(true)
+	cookieList.DisableQuitKeybindings()
+	cookieList.SetStatusBarItemName("cookie", "cookies")
+
+	extraKeys := []key.Binding{
+		key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "delete")),
+		key.NewBinding(key.WithKeys("X"), key.WithHelp("X", "clear all")),
+	}
+	cookieList.AdditionalShortHelpKeys = func() []key.Binding {
+		return extraKeys
+	}
+	cookieList.AdditionalFullHelpKeys = func() []key.Binding {
+		return extraKeys
+	}
+	return cookieList
+}
+
+// cookieJar returns the jar of the current environment, or nil when
+// cookies are not stored.
+func (m Model) cookieJar() *cookies.Jar {
+	if m.Cookies == nil {
+		return nil
+	}
+	return m.Cookies.Jar(m.Environment)
+}
+
+// openCookies lists the cookies of the current environment and switches to
+// the cookie view.
+func (m Model) openCookies() (tea.Model, tea.Cmd) {
+	m.cookieList.ResetFilter()
+	cmd := m.reloadCookies()
+	m.cookieList.Select(0)
+	m.CurrentView = ViewCookies
+	return m, cmd
+}
+
+func (m *Model) reloadCookies() tea.Cmd {
+	jar := m.cookieJar()
+	stored := jar.List()
+	items := make([]list.Item, len(stored))
+	for i, c := range stored {
+		items[i] = cookieItem{cookie: c}
+	}
+	m.cookieList.Title = "Cookies"
+	if m.Environment != "" {
+		m.cookieList.Title += " · " + environmentTitle(m.Environment)
+	}
+	return m.cookieList.SetItems(items)
+}
+
+func (m Model) handleCookieKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.cookieList.FilterState() != list.Filtering {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+
+		case "b", "esc", "q":
+			if m.cookieList.FilterState() == list.FilterApplied && msg.String() == "esc" {
+				break
+			}
+			m.CurrentView = ViewList
+			return m, nil
+
+		case "d":
+			if item, ok := m.cookieList.SelectedItem().(cookieItem); ok {
+				if err := m.cookieJar().Delete(item.cookie); err != nil {
+					m.ErrorMsg = "Error deleting cookie: " + err.Error()
+					m.CurrentView = ViewError
+					return m, nil
+				}
+			}
+			return m, m.reloadCookies()
+
+		case "X":
+			if err := m.cookieJar().Clear(); err != nil {
+				m.ErrorMsg = "Error clearing cookies: " + err.Error()
+				m.CurrentView = ViewError
+				return m, nil
+			}
+			return m, m.reloadCookies()
+		}
+	}
+
+	m.cookieList, cmd = m.cookieList.Update(msg)
+	return m, cmd
+}
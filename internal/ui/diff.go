@@ -0,0 +1,315 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/diff"
+	"httpyum/internal/history"
+	"httpyum/internal/parser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diffState is the pair of responses shown in the diff view. left is the
+// older or first-marked response.
+type diffState struct {
+	left, right           *client.ExecutionResult
+	leftLabel, rightLabel string
+	result                *diff.Result
+	back                  ViewType
+}
+
+type diffFinishedMsg struct {
+	left, right *client.ExecutionResult
+	// cancelled is whether the requests were cancelled from the loading
+	// view.
+	cancelled bool
+}
+
+// executeDiff runs two requests one after the other and reports both
+// results.
+func executeDiff(ctx context.Context, executor *client.Executor, left, right *parser.Request) tea.Cmd {
+	return func() tea.Msg {
+		msg := diffFinishedMsg{left: executor.Execute(ctx, left)}
+		if ctx.Err() == nil {
+			msg.right = executor.Execute(ctx, right)
+		}
+		msg.cancelled = ctx.Err() != nil
+		return msg
+	}
+}
+
+// resultLabel names a result in the diff view.
+func resultLabel(result *client.ExecutionResult) string {
+	req := result.Request
+	if req.Description != "" {
+		return req.Description
+	}
+	if req.Name != "" {
+		return req.Name
+	}
+	return req.Method + " " + req.URL
+}
+
+// entryLabel names a history entry in the diff view.
+func entryLabel(e *history.Entry) string {
+	return e.Title() + " (" + e.Time.Local().Format("15:04:05") + ")"
+}
+
+// showDiff compares left and right and switches to the diff view; back is
+// the view that esc returns to.
+func (m Model) showDiff(left, right *client.ExecutionResult, leftLabel, rightLabel string, back ViewType) (tea.Model, tea.Cmd) {
+	for _, r := range []*client.ExecutionResult{left, right} {
+		if r.Error != nil {
+			m.ErrorMsg = fmt.Sprintf("Cannot compare, %s failed: %v", resultLabel(r), r.Error)
+			m.CurrentView = ViewError
+			return m, nil
+		}
+	}
+
+	m.diffView = &diffState{
+		left:       left,
+		right:      right,
+		leftLabel:  leftLabel,
+		rightLabel: rightLabel,
+		result:     diff.Responses(left.Response, right.Response, diff.Options{}),
+		back:       back,
+	}
+	m.CurrentView = ViewDiff
+
+	m.viewport.Width = m.Width
+	m.viewport.Height = m.viewportHeight()
+	m.rebuildDiffContent()
+	m.viewport.GotoTop()
+	return m, nil
+}
+
+// diffPrevious compares the current response with the previous response to
+// the same request recorded in the history.
+func (m Model) diffPrevious() (tea.Model, tea.Cmd) {
+	current := m.LastResult
+	if m.History == nil || current == nil || current.Response == nil {
+		return m, nil
+	}
+
+	entries, err := m.History.ListFile(m.ParsedFile.Path)
+	if err != nil {
+		m.ErrorMsg = "Error reading history: " + err.Error()
+		m.CurrentView = ViewError
+		return m, nil
+	}
+	for _, e := range entries {
+		if e.RequestID != current.Request.ID || e.Error != "" {
+			continue
+		}
+		if !e.Time.Before(current.Response.RequestTime) {
+			continue
+		}
+		now := resultLabel(current) + " (" + current.Response.RequestTime.Local().Format("15:04:05") + ")"
+		return m.showDiff(e.Result(), current, entryLabel(e), now, ViewResponse)
+	}
+
+	m.ErrorMsg = "No earlier response to this request in the history"
+	m.CurrentView = ViewError
+	return m, nil
+}
+
+// markRequest marks the selected request for comparison, or compares it
+// with the request marked earlier by running both.
+func (m Model) markRequest() (tea.Model, tea.Cmd) {
+	// Items and SetItem take positions in the unfiltered list.
+	index := m.list.GlobalIndex()
+	selected, ok := m.list.SelectedItem().(requestItem)
+	if !ok {
+		return m, nil
+	}
+
+	if m.markedRequest < 0 || m.markedRequest == index {
+		if m.markedRequest == index {
+			m.markedRequest = -1
+		} else {
+			m.markedRequest = index
+		}
+		selected.marked = m.markedRequest == index
+		return m, m.list.SetItem(index, selected)
+	}
+
+	marked, ok := m.list.Items()[m.markedRequest].(requestItem)
+	if !ok {
+		return m, nil
+	}
+	marked.marked = false
+	cmd := m.list.SetItem(m.markedRequest, marked)
+	m.markedRequest = -1
+
+	ctx := m.startLoading()
+	return m, tea.Batch(cmd, executeDiff(ctx, m.executor, &marked.request, &selected.request), tick())
+}
+
+// markEntry marks the selected history entry for comparison, or compares
+// it with the entry marked earlier. The older entry is shown on the left.
+func (m Model) markEntry() (tea.Model, tea.Cmd) {
+	// Items and SetItem take positions in the unfiltered list.
+	index := m.historyList.GlobalIndex()
+	selected, ok := m.historyList.SelectedItem().(historyItem)
+	if !ok {
+		return m, nil
+	}
+
+	if m.markedEntry < 0 || m.markedEntry == index {
+		if m.markedEntry == index {
+			m.markedEntry = -1
+		} else {
+			m.markedEntry = index
+		}
+		selected.marked = m.markedEntry == index
+		return m, m.historyList.SetItem(index, selected)
+	}
+
+	marked, ok := m.historyList.Items()[m.markedEntry].(historyItem)
+	if !ok {
+		return m, nil
+	}
+	marked.marked = false
+	cmd := m.historyList.SetItem(m.markedEntry, marked)
+	m.markedEntry = -1
+
+	older, newer := marked.entry, selected.entry
+	if newer.Time.Before(older.Time) {
+		older, newer = newer, older
+	}
+	model, diffCmd := m.showDiff(older.Result(), newer.Result(), entryLabel(older), entryLabel(newer), ViewHistory)
+	return model, tea.Batch(cmd, diffCmd)
+}
+
+func (m Model) handleDiffKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+
+	case "b", "esc":
+		m.CurrentView = m.diffView.back
+		if m.CurrentView == ViewResponse {
+			m.rebuildViewportContent()
+		}
+		return m, nil
+
+	case "up", "down", "pgup", "pgdown", "home", "end", "k", "j":
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	return m, nil
+}
+
+func (m *Model) rebuildDiffContent() {
+	if m.diffView == nil {
+		return
+	}
+	m.viewport.SetContent(RenderDiffContent(m.diffView, m.contentWidth(), m.viewportHeight()))
+}
+
+// RenderDiffContent renders the summary of both responses, the list of
+// differences and both bodies side by side, with box side borders.
+func RenderDiffContent(d *diffState, cw, height int) string {
+	leftWidth := cw / 2
+	rightWidth := max(cw-leftWidth-3, 0)
+
+	side := borderStyle.Render("│")
+	colDivider := mutedStyle.Render("│")
+	colSep := func(junction string) string {
+		return borderStyle.Render("├" + strings.Repeat("─", leftWidth+2) + junction + strings.Repeat("─", rightWidth+2) + "┤")
+	}
+	wrapSection := func(section string) []string {
+		lines := strings.Split(section, "\n")
+		for i, line := range lines {
+			pad := strings.Repeat(" ", max(cw-visualLength(line), 0))
+			lines[i] = side + " " + line + pad + " " + side
+		}
+		return lines
+	}
+
+	summary := func(label string, r *client.ExecutionResult, width int) []string {
+		resp := r.Response
+		method, url := r.Request.Method, r.Request.URL
+		if r.Resolved != nil {
+			method, url = r.Resolved.Method, r.Resolved.URL
+		}
+		status := StatusCodeStyle(resp.StatusCode, resp.Status).Render(resp.Status)
+		return []string{
+			sectionTitleStyle.Render(truncate(label, width)),
+			fmt.Sprintf("%s %s", method, truncate(url, max(width-len(method)-1, 0))),
+			status + mutedStyle.Render(fmt.Sprintf(" | %s | %s", resp.Duration, client.FormatSize(resp.Size))),
+		}
+	}
+
+	var allLines []string
+	allLines = append(allLines, wrapSection(twoColumn(
+		summary(d.leftLabel, d.left, leftWidth),
+		summary(d.rightLabel, d.right, rightWidth),
+		leftWidth, rightWidth,
+	))...)
+
+	allLines = append(allLines, colSep("┴"))
+	allLines = append(allLines, wrapSection(renderChanges(d.result, cw))...)
+
+	allLines = append(allLines, colSep("┬"))
+	allLines = append(allLines, wrapSection(twoColumn(
+		diffBodyLines(d.left.Response, leftWidth),
+		diffBodyLines(d.right.Response, rightWidth),
+		leftWidth, rightWidth,
+	))...)
+
+	padLine := side + " " + strings.Repeat(" ", leftWidth) + " " + colDivider + " " + strings.Repeat(" ", rightWidth) + " " + side
+	for len(allLines) < height {
+		allLines = append(allLines, padLine)
+	}
+
+	return strings.Join(allLines, "\n")
+}
+
+// renderChanges lists the differences, colored by kind.
+func renderChanges(result *diff.Result, width int) string {
+	var sb strings.Builder
+
+	changes := result.Changes()
+	sb.WriteString(sectionTitleStyle.Render("Differences"))
+	if len(changes) == 0 {
+		sb.WriteString("\n")
+		sb.WriteString(successStyle.Render("Responses are identical"))
+		return sb.String()
+	}
+	sb.WriteString(mutedStyle.Render(fmt.Sprintf(" (%d)", len(changes))))
+
+	for _, c := range changes {
+		line := truncate(c.String(), width)
+		sb.WriteString("\n")
+		switch c.Kind {
+		case diff.Added:
+			sb.WriteString(successStyle.Render(line))
+		case diff.Removed:
+			sb.WriteString(errorStyle.Render(line))
+		default:
+			sb.WriteString(warningStyle.Render(line))
+		}
+	}
+	return sb.String()
+}
+
+func diffBodyLines(resp *client.Response, width int) []string {
+	lines := []string{sectionTitleStyle.Render("Response Body")}
+	if len(resp.Body) == 0 {
+		return append(lines, mutedStyle.Render("(empty)"))
+	}
+
+	body := string(resp.Body)
+	if pretty, err := client.PrettyPrintJSON(resp.Body); err == nil {
+		body = pretty
+	}
+	return append(lines, wrapText(body, width)...)
+}
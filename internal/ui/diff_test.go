@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"testing"
+
+	"httpyum/internal/history"
+	"httpyum/internal/parser"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+func TestMarkRequestFiltered(t *testing.T) {
+	m := NewModel(&parser.ParsedFile{Requests: []parser.Request{
+		{ID: "1", Name: "users", Method: "GET", URL: "http://example.com/users"},
+		{ID: "2", Name: "orders", Method: "GET", URL: "http://example.com/orders"},
+		{ID: "3", Name: "invoices", Method: "GET", URL: "http://example.com/invoices"},
+	}}, nil, Options{})
+	m.list.SetFilterText("invoices")
+
+	model, _ := m.markRequest()
+	m = model.(Model)
+	if m.markedRequest != 2 {
+		t.Fatalf("markedRequest = %d, want 2", m.markedRequest)
+	}
+	for i, item := range m.list.Items() {
+		if marked := item.(requestItem).marked; marked != (i == 2) {
+			t.Errorf("item %d marked = %v", i, marked)
+		}
+	}
+}
+
+func TestMarkEntryFiltered(t *testing.T) {
+	m := NewModel(&parser.ParsedFile{}, nil, Options{})
+	m.historyList.SetItems([]list.Item{
+		historyItem{entry: &history.Entry{Name: "users", Method: "GET", URL: "http://example.com/users"}},
+		historyItem{entry: &history.Entry{Name: "orders", Method: "GET", URL: "http://example.com/orders"}},
+	})
+	m.historyList.SetFilterText("orders")
+
+	model, _ := m.markEntry()
+	m = model.(Model)
+	if m.markedEntry != 1 {
+		t.Fatalf("markedEntry = %d, want 1", m.markedEntry)
+	}
+	for i, item := range m.historyList.Items() {
+		if marked := item.(historyItem).marked; marked != (i == 1) {
+			t.Errorf("item %d marked = %v", i, marked)
+		}
+	}
+}
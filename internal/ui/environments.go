@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+
+	"httpyum/internal/parser"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const noEnvironment = "(none)"
+
+type environmentItem struct {
+	name   string
+	count  int
+	active bool
+}
+
+func (i environmentItem) FilterValue() string {
+	return i.name
+}
+
+func (i environmentItem) Title() string {
+	if i.active {
+		return i.name + " ✓"
+	}
+	return i.name
+}
+
+func (i environmentItem) Description() string {
+	if i.name == noEnvironment {
+		return "File variables only"
+	}
+	return fmt.Sprintf("%d variables", i.count)
+}
+
+func newEnvironmentList(envs parser.Environments, active string) list.Model {
+	items := environmentItems(envs, active)
+	envList := list.New(items, list.NewDefaultDelegate(), 0, listHeight)
+	envList.Title = "Environments"
+	envList.SetShowStatusBar(false)
+	envList.SetFilteringEnabled(false)
+	envList.SetShowHelp(false)
+	envList.DisableQuitKeybindings()
+	for i, item := range items {
+		if item.(environmentItem).active {
+			envList.Select(i)
+		}
+	}
+	return envList
+}
+
+func environmentItems(envs parser.Environments, active string) []list.Item {
+	items := []list.Item{environmentItem{name: noEnvironment, active: active == ""}}
+	for _, name := range envs.Names() {
+		items = append(items, environmentItem{
+			name:   name,
+			count:  len(envs.Variables(name)),
+			active: name == active,
+		})
+	}
+	return items
+}
+
+func environmentTitle(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "env: " + name
+}
+
+func (m Model) handleEnvironmentKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "b", "esc", "q":
+		m.CurrentView = ViewList
+		return m, nil
+
+	case "enter":
+		if item, ok := m.envList.SelectedItem().(environmentItem); ok {
+			name := item.name
+			if name == noEnvironment {
+				name = ""
+			}
+			m.setEnvironment(name)
+		}
+		m.CurrentView = ViewList
+		return m, nil
+	}
+
+	m.envList, cmd = m.envList.Update(msg)
+	return m, cmd
+}
+
+// setEnvironment switches the active environment, rebuilding the variable
+// map and the executor that requests run with.
+func (m *Model) setEnvironment(name string) {
+	m.Environment = name
+	m.Variables = parser.BuildVariableMap(m.ParsedFile.Variables, m.envVars, m.Environments.Variables(name))
+	m.executor = m.newExecutor()
+	m.list.Title = environmentTitle(name)
+	m.envList.SetItems(environmentItems(m.Environments, name))
+}
@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"fmt"
+
+	"httpyum/internal/client"
+	"httpyum/internal/history"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type historyItem struct {
+	entry  *history.Entry
+	marked bool
+}
+
+func (i historyItem) FilterValue() string {
+	return i.entry.Title() + " " + i.entry.Method + " " + i.entry.URL + " " + i.entry.Status
+}
+
+func (i historyItem) Title() string {
+	status := i.entry.Status
+	if i.entry.Error != "" {
+		status = "error"
+	}
+	title := fmt.Sprintf("%s  %s %s", status, i.entry.Method, i.entry.URL)
+	if i.marked {
+		title = "● " + title
+	}
+	return title
+}
+
+func (i historyItem) Description() string {
+	desc := i.entry.Time.Local().Format("2006-01-02 15:04:05")
+	if i.entry.Error == "" {
+		desc += " · " + i.entry.Duration.String()
+	}
+	if i.entry.Environment != "" {
+		desc += " · env: " + i.entry.Environment
+	}
+	return desc + " · " + i.entry.Title()
+}
+
+func newHistoryList() list.Model {
+	historyList := list.New(nil, list.NewDefaultDelegate(), 0, listHeight)
+	historyList.Title = "History"
+	historyList.SetShowStatusBar(true)
+	historyList.SetFilteringEnabled(true)
+	historyList.SetShowHelp(true)
+	historyList.DisableQuitKeybindings()
+	return historyList
+}
+
+// openHistory loads the stored entries of the file into the history list
+// and switches to the history view.
+func (m Model) openHistory() (tea.Model, tea.Cmd) {
+	entries, err := m.History.ListFile(m.ParsedFile.Path)
+	if err != nil {
+		m.ErrorMsg = "Error reading history: " + err.Error()
+		m.CurrentView = ViewError
+		return m, nil
+	}
+
+	items := make([]list.Item, len(entries))
+	for i, e := range entries {
+		items[i] = historyItem{entry: e}
+	}
+	m.markedEntry = -1
+	m.historyList.ResetFilter()
+	cmd := m.historyList.SetItems(items)
+	m.historyList.Select(0)
+	m.CurrentView = ViewHistory
+	return m, cmd
+}
+
+func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	if m.historyList.FilterState() != list.Filtering {
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+
+		case "b", "esc", "q":
+			if m.historyList.FilterState() == list.FilterApplied && msg.String() == "esc" {
+				break
+			}
+			m.CurrentView = ViewList
+			return m, nil
+
+		case "x":
+			return m.markEntry()
+
+		case "enter":
+			if item, ok := m.historyList.SelectedItem().(historyItem); ok {
+				m.showResult(item.entry.Result(), ViewHistory)
+			}
+			return m, nil
+		}
+	}
+
+	m.historyList, cmd = m.historyList.Update(msg)
+	return m, cmd
+}
+
+// recordHistory stores result when history is enabled. Failures to write
+// are not worth interrupting the user for.
+func (m Model) recordHistory(result *client.ExecutionResult) {
+	if m.History == nil {
+		return
+	}
+	_ = m.History.Add(history.NewEntry(result, m.ParsedFile.Path, m.Environment))
+}
@@ -36,7 +36,7 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 	switch method {
 	case "GET":
 		methodStyle = lipgloss.NewStyle().Foreground(colorSecondary).Bold(true)
-	case "POST":
+	case "POST", "GRAPHQL", "GRPC", "WEBSOCKET":
 		methodStyle = lipgloss.NewStyle().Foreground(colorAccent).Bold(true)
 	case "PUT":
 		methodStyle = lipgloss.NewStyle().Foreground(colorWarning).Bold(true)
@@ -56,6 +56,10 @@ func (d itemDelegate) Render(w io.Writer, m list.Model, index int, listItem list
 		url = url[:27] + "..."
 	}
 
+	if i.marked {
+		method = "● " + method
+	}
+
 	if index == m.Index() {
 		line := selectedItemStyle.Render(fmt.Sprintf("│ %s %s",
 			methodStyle.Render(method),
@@ -2,8 +2,10 @@ package ui
 
 import (
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"httpyum/internal/client"
 	"httpyum/internal/parser"
@@ -67,7 +69,7 @@ func RenderResponseContent(result *client.ExecutionResult, opts RenderOpts) stri
 
 	// Determine which sections exist
 	hasReqHeaders := len(result.Request.Headers) > 0
-	hasResHeaders := opts.ShowHeaders && result.Response != nil && len(result.Response.Headers) > 0
+	hasResHeaders := opts.ShowHeaders && result.Response != nil && (len(result.Response.Headers) > 0 || len(result.Response.Trailers) > 0)
 	hasHeaders := hasReqHeaders || hasResHeaders
 	hasReqBody := result.Request.Body != ""
 
@@ -77,6 +79,24 @@ func RenderResponseContent(result *client.ExecutionResult, opts RenderOpts) stri
 	// Section 1: Request details (single column)
 	allLines = append(allLines, wrapSection(renderRequestDetails(result, opts))...)
 
+	// Section 1b: Assertion results (single column)
+	if len(result.Assertions) > 0 {
+		allLines = append(allLines, plainSep)
+		allLines = append(allLines, wrapSection(renderAssertions(result, cw))...)
+	}
+
+	// Section 1c: GraphQL errors (single column)
+	if len(result.GraphQLErrors) > 0 {
+		allLines = append(allLines, plainSep)
+		allLines = append(allLines, wrapSection(renderGraphQLErrors(result, cw))...)
+	}
+
+	// Section 1d: Script log output (single column)
+	if len(result.Logs) > 0 {
+		allLines = append(allLines, plainSep)
+		allLines = append(allLines, wrapSection(renderLogs(result, cw))...)
+	}
+
 	// Section 2: Headers (two-column)
 	if hasHeaders {
 		allLines = append(allLines, colSep("┬"))
@@ -97,7 +117,7 @@ func RenderResponseContent(result *client.ExecutionResult, opts RenderOpts) stri
 		} else {
 			allLines = append(allLines, plainSep)
 		}
-		allLines = append(allLines, wrapSection(renderBody(result))...)
+		allLines = append(allLines, wrapSection(renderBody(result, cw))...)
 	}
 
 	// Determine if last section is two-column (for padding)
@@ -128,19 +148,30 @@ func RenderTopBorder(width int) string {
 // RenderBottomBorder renders ╰──┴──── 200 OK | 143ms | 2.1 KB ────╯
 // colPos is the junction position for ┴ (0 means no junction).
 func RenderBottomBorder(result *client.ExecutionResult, width, colPos int) string {
-	innerWidth := max(width-2, 0)
-
-	label := ""
 	if result.Error != nil {
-		label = " Error "
-	} else if result.Response != nil {
-		label = fmt.Sprintf(" %s | %s | %s ",
-			result.Response.Status,
-			result.Response.Duration.String(),
-			client.FormatSize(result.Response.Size),
-		)
+		return renderBottomBorder(errorStyle.Render(" Error "), width, colPos)
+	}
+	if result.Response == nil {
+		return renderBottomBorder("", width, colPos)
 	}
 
+	label := fmt.Sprintf(" %s | %s | %s ",
+		result.Response.Status,
+		result.Response.Duration.String(),
+		client.FormatSize(result.Response.Size),
+	)
+	statusStyle := StatusCodeStyle(result.Response.StatusCode, result.Response.Status)
+	return renderBottomBorder(statusStyle.Render(label), width, colPos)
+}
+
+// RenderLabelBorder renders a bottom border with a plain label.
+func RenderLabelBorder(label string, width, colPos int) string {
+	return renderBottomBorder(infoStyle.Render(" "+label+" "), width, colPos)
+}
+
+func renderBottomBorder(labelStyled string, width, colPos int) string {
+	innerWidth := max(width-2, 0)
+
 	// Build the inner dashes, inserting ┴ junction if needed
 	buildDashes := func(n int) string {
 		if colPos <= 0 || colPos >= n {
@@ -149,18 +180,10 @@ func RenderBottomBorder(result *client.ExecutionResult, width, colPos int) strin
 		return strings.Repeat("─", colPos) + "┴" + strings.Repeat("─", n-colPos-1)
 	}
 
-	if label == "" {
+	if labelStyled == "" {
 		return borderStyle.Render("╰" + buildDashes(innerWidth) + "╯")
 	}
 
-	var labelStyled string
-	if result.Error != nil {
-		labelStyled = errorStyle.Render(label)
-	} else {
-		statusStyle := StatusCodeStyle(result.Response.StatusCode, result.Response.Status)
-		labelStyled = statusStyle.Render(label)
-	}
-
 	labelVisual := visualLength(labelStyled)
 	trailDashes := 3
 	leadDashes := innerWidth - labelVisual - trailDashes
@@ -256,6 +279,10 @@ func renderRequestDetails(result *client.ExecutionResult, opts RenderOpts) strin
 	sb.WriteString(sectionTitleStyle.Render("Request"))
 	sb.WriteString("\n")
 	sb.WriteString(successStyle.Render(fmt.Sprintf("%s %s", result.Request.Method, result.Request.URL)))
+	if result.Response != nil && result.Response.Proxy != "" {
+		sb.WriteString("\n")
+		sb.WriteString(mutedStyle.Render("via proxy " + result.Response.Proxy))
+	}
 
 	if opts.ShowVariables && opts.Variables != nil {
 		sb.WriteString("\n\n")
@@ -265,6 +292,88 @@ func renderRequestDetails(result *client.ExecutionResult, opts RenderOpts) strin
 	return sb.String()
 }
 
+// renderAssertions renders a pass/fail line for each assertion.
+func renderAssertions(result *client.ExecutionResult, width int) string {
+	var sb strings.Builder
+
+	failed := result.FailedAssertions()
+	sb.WriteString(sectionTitleStyle.Render("Assertions"))
+	sb.WriteString(mutedStyle.Render(fmt.Sprintf(" (%d/%d passed)", len(result.Assertions)-failed, len(result.Assertions))))
+
+	for _, a := range result.Assertions {
+		sb.WriteString("\n")
+		if a.Passed {
+			sb.WriteString(successStyle.Render("✓ ") + truncate(a.Name, max(width-2, 0)))
+			continue
+		}
+		line := a.Name
+		if a.Message != "" {
+			line += " — " + a.Message
+		}
+		sb.WriteString(errorStyle.Render("✗ ") + truncate(line, max(width-2, 0)))
+	}
+
+	return sb.String()
+}
+
+// renderGraphQLErrors renders the errors array of a GraphQL response, which
+// usually comes with a 200 status.
+func renderGraphQLErrors(result *client.ExecutionResult, width int) string {
+	var sb strings.Builder
+	sb.WriteString(sectionTitleStyle.Render("GraphQL Errors"))
+	sb.WriteString(mutedStyle.Render(fmt.Sprintf(" (%d)", len(result.GraphQLErrors))))
+	for _, e := range result.GraphQLErrors {
+		sb.WriteString("\n")
+		sb.WriteString(errorStyle.Render("✗ ") + truncate(e.Message, max(width-2, 0)))
+		var details []string
+		if e.Code != "" {
+			details = append(details, e.Code)
+		}
+		if e.Path != "" {
+			details = append(details, "path "+e.Path)
+		}
+		if len(e.Locations) > 0 {
+			details = append(details, "at "+strings.Join(e.Locations, ", "))
+		}
+		if len(details) > 0 {
+			sb.WriteString("\n  ")
+			sb.WriteString(mutedStyle.Render(truncate(strings.Join(details, " · "), max(width-2, 0))))
+		}
+	}
+	return sb.String()
+}
+
+// renderLogs renders output written by scripts with client.log.
+func renderLogs(result *client.ExecutionResult, width int) string {
+	var sb strings.Builder
+	sb.WriteString(sectionTitleStyle.Render("Script Log"))
+	for _, line := range result.Logs {
+		for _, wrapped := range wrapText(line, width) {
+			sb.WriteString("\n")
+			sb.WriteString(mutedStyle.Render(wrapped))
+		}
+	}
+	return sb.String()
+}
+
+// writeHeaderValues writes a "Key: value" line for each header, sorted by
+// key.
+func writeHeaderValues(sb *strings.Builder, headers http.Header, width int) {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		sb.WriteString("\n")
+		value := strings.Join(headers[key], ", ")
+		if width > 0 && len(value) > width {
+			value = truncate(value, width)
+		}
+		sb.WriteString(fmt.Sprintf("%s: %s", headerKeyStyle.Render(key), headerValueStyle.Render(value)))
+	}
+}
+
 // renderHeadersTwoColumn renders request headers (left) and response headers (right).
 func renderHeadersTwoColumn(result *client.ExecutionResult, showResHeaders bool, totalWidth int) string {
 	leftWidth := totalWidth / 2
@@ -289,18 +398,12 @@ func renderHeadersTwoColumn(result *client.ExecutionResult, showResHeaders bool,
 		rightSb.WriteString(sectionTitleStyle.Render("Response Headers"))
 		rightSb.WriteString(mutedStyle.Render(" ('h' to hide)"))
 
-		keys := make([]string, 0, len(result.Response.Headers))
-		for key := range result.Response.Headers {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-		for _, key := range keys {
-			rightSb.WriteString("\n")
-			value := strings.Join(result.Response.Headers[key], ", ")
-			if rightWidth > 0 && len(value) > rightWidth {
-				value = truncate(value, rightWidth)
-			}
-			rightSb.WriteString(fmt.Sprintf("%s: %s", headerKeyStyle.Render(key), headerValueStyle.Render(value)))
+		writeHeaderValues(&rightSb, result.Response.Headers, rightWidth)
+
+		if len(result.Response.Trailers) > 0 {
+			rightSb.WriteString("\n\n")
+			rightSb.WriteString(sectionTitleStyle.Render("Response Trailers"))
+			writeHeaderValues(&rightSb, result.Response.Trailers, rightWidth)
 		}
 	}
 
@@ -316,7 +419,7 @@ func buildVariablesText(result *client.ExecutionResult, allVariables map[string]
 
 	usedVars := parser.ExtractUsedVariables(result.Request, allVariables)
 
-	if len(usedVars) == 0 {
+	if len(usedVars) == 0 && len(result.Tokens) == 0 {
 		sb.WriteString("\n")
 		sb.WriteString(mutedStyle.Render("(none)"))
 	} else {
@@ -339,12 +442,36 @@ func buildVariablesText(result *client.ExecutionResult, allVariables map[string]
 			}
 			sb.WriteString(mutedStyle.Render(line))
 		}
+		for _, t := range result.Tokens {
+			sb.WriteString("\n")
+			line := fmt.Sprintf("$oauth2 %s = %s (%s, %s)", t.Provider, maskValue(t.Token), t.Source, tokenExpiry(t.Expiry, time.Now()))
+			if maxWidth > 0 && len(line) > maxWidth {
+				line = truncate(line, maxWidth)
+			}
+			sb.WriteString(mutedStyle.Render(line))
+		}
 	}
 
 	return sb.String()
 }
 
-func renderBody(result *client.ExecutionResult) string {
+// tokenExpiry describes when an OAuth2 token expires.
+func tokenExpiry(expiry, now time.Time) string {
+	switch {
+	case expiry.IsZero():
+		return "no expiry"
+	case !expiry.After(now):
+		return "expired"
+	default:
+		return "expires in " + expiry.Sub(now).Round(time.Second).String()
+	}
+}
+
+func renderBody(result *client.ExecutionResult, width int) string {
+	if result.Response != nil && client.IsStreaming(result.Response.ContentType) {
+		return renderEvents(result, width)
+	}
+
 	var sb strings.Builder
 
 	sb.WriteString(sectionTitleStyle.Render("Response Body"))
@@ -397,7 +524,12 @@ func renderBodyTwoColumn(result *client.ExecutionResult, totalWidth int) string
 		leftSb.WriteString(strings.Join(wrapped, "\n"))
 	}
 
-	// Right column: Response Body
+	// Right column: Response Body, or the events of a stream
+	if result.Response != nil && client.IsStreaming(result.Response.ContentType) {
+		leftLines := strings.Split(leftSb.String(), "\n")
+		rightLines := strings.Split(renderEvents(result, rightWidth), "\n")
+		return twoColumn(leftLines, rightLines, leftWidth, rightWidth)
+	}
 	var rightSb strings.Builder
 	rightSb.WriteString(sectionTitleStyle.Render("Response Body"))
 
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"httpyum/internal/client"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type streamChangedMsg struct {
+	stream *client.ResponseStream
+}
+
+// waitForStream reports the next change of stream: its response starting
+// to stream, an event arriving or the request completing.
+func waitForStream(stream *client.ResponseStream) tea.Cmd {
+	changed := stream.Changed()
+	return func() tea.Msg {
+		<-changed
+		return streamChangedMsg{stream: stream}
+	}
+}
+
+// updateStream shows the progress of the request being executed: a
+// streaming response as its events arrive, following them while the view
+// is scrolled to the bottom, and then the result. A request replaced by
+// another, having been cancelled, is followed until its result arrives, to
+// record it in the history.
+func (m Model) updateStream(msg streamChangedMsg) (tea.Model, tea.Cmd) {
+	if m.stream != msg.stream {
+		if result := msg.stream.Result(); result != nil {
+			m.recordHistory(result)
+			return m, nil
+		}
+		return m, waitForStream(msg.stream)
+	}
+
+	if result := msg.stream.Result(); result != nil {
+		m.stream = nil
+		m.recordHistory(result)
+		switch m.CurrentView {
+		case ViewLoading:
+			m.list.ResetFilter()
+			m.showResult(result, ViewList)
+		case ViewResponse:
+			m.followStream(result)
+		}
+		return m, nil
+	}
+
+	partial := msg.stream.Partial()
+	if partial == nil {
+		return m, waitForStream(msg.stream)
+	}
+	switch m.CurrentView {
+	case ViewLoading:
+		m.list.ResetFilter()
+		m.showResult(partial, ViewList)
+		m.viewport.GotoBottom()
+	case ViewResponse:
+		m.followStream(partial)
+	}
+	return m, waitForStream(msg.stream)
+}
+
+// followStream shows result in place of the one in the response view,
+// keeping the view at the bottom if it was.
+func (m *Model) followStream(result *client.ExecutionResult) {
+	atBottom := m.viewport.AtBottom()
+	m.LastResult = result
+	m.rebuildViewportContent()
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// streaming reports whether the response view shows a response that is
+// still being received.
+func (m Model) streaming() bool {
+	return m.stream != nil && m.CurrentView == ViewResponse
+}
+
+// renderEvents renders the events of a streaming response: each with its
+// time, type and ID, and its data with JSON pretty-printed.
+func renderEvents(result *client.ExecutionResult, width int) string {
+	events := result.Events
+	if events == nil {
+		events = client.ParseStream(result.Response.ContentType, result.Response.Body)
+	}
+
+	title := fmt.Sprintf("Response Events (%d)", len(events))
+	if result.Stopped {
+		title = fmt.Sprintf("Response Events (%d, stopped)", len(events))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sectionTitleStyle.Render(title))
+	sb.WriteString("\n")
+	if len(events) == 0 {
+		sb.WriteString(mutedStyle.Render("(none yet)"))
+		return sb.String()
+	}
+
+	for i, e := range events {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		var meta []string
+		if !e.Time.IsZero() {
+			meta = append(meta, e.Time.Format("15:04:05.000"))
+		}
+		if e.Event != "" {
+			meta = append(meta, "event: "+e.Event)
+		}
+		if e.ID != "" {
+			meta = append(meta, "id: "+e.ID)
+		}
+		if len(meta) > 0 {
+			sb.WriteString(mutedStyle.Render(strings.Join(meta, " · ")))
+			sb.WriteString("\n")
+		}
+
+		data := e.Data
+		if json.Valid([]byte(data)) {
+			if pretty, err := client.PrettyPrintJSON([]byte(data)); err == nil {
+				data = pretty
+			}
+		}
+		sb.WriteString(strings.Join(wrapText(data, width), "\n"))
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
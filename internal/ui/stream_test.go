@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"httpyum/internal/client"
+	"httpyum/internal/history"
+	"httpyum/internal/parser"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestCancelledStreamRecordedAfterNextRequest(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	store, err := history.Open(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewModel(&parser.ParsedFile{Path: "api.http", Requests: []parser.Request{
+		{ID: "1", Method: "GET", URL: server.URL + "/slow"},
+		{ID: "2", Method: "GET", URL: server.URL + "/fast"},
+	}}, nil, Options{History: store})
+
+	enter := tea.KeyMsg{Type: tea.KeyEnter}
+	model, _ := m.Update(enter)
+	m = model.(Model)
+	cancelled := m.stream
+	model, _ = m.cancelLoading()
+	m = model.(Model)
+
+	m.list.Select(1)
+	model, _ = m.Update(enter)
+	m = model.(Model)
+	if m.stream == cancelled {
+		t.Fatal("the second request did not start")
+	}
+
+	// The cancelled request is still being followed.
+	model, cmd := m.updateStream(streamChangedMsg{stream: cancelled})
+	m = model.(Model)
+	if cmd == nil {
+		t.Fatal("updateStream stopped following the cancelled request")
+	}
+
+	waitForResult(t, cancelled)
+	m.updateStream(streamChangedMsg{stream: cancelled})
+
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].RequestID != "1" || entries[0].Error == "" {
+		t.Fatalf("history = %v, want the cancelled request", entries)
+	}
+}
+
+func waitForResult(t *testing.T, stream *client.ResponseStream) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for stream.Result() == nil {
+		select {
+		case <-stream.Changed():
+		case <-deadline:
+			t.Fatal("the cancelled request did not finish")
+		}
+	}
+}
@@ -47,6 +47,9 @@ var (
 			Foreground(colorError).
 			Bold(true)
 
+	warningStyle = lipgloss.NewStyle().
+			Foreground(colorWarning)
+
 	successStyle = lipgloss.NewStyle().
 			Foreground(colorSecondary).
 			Bold(true)
@@ -1,11 +1,18 @@
 package ui
 
 import (
+	"context"
+	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"time"
 
 	"httpyum/internal/client"
+	"httpyum/internal/cookies"
+	"httpyum/internal/curl"
+	"httpyum/internal/history"
 	"httpyum/internal/parser"
 
 	"github.com/charmbracelet/bubbles/key"
@@ -21,14 +28,22 @@ const (
 	ViewResponse ViewType = "response"
 	ViewLoading  ViewType = "loading"
 	ViewError    ViewType = "error"
+
+	ViewEnvironments ViewType = "environments"
+	ViewCurl         ViewType = "curl"
+	ViewHistory      ViewType = "history"
+	ViewDiff         ViewType = "diff"
+	ViewCookies      ViewType = "cookies"
+	ViewWebSocket    ViewType = "websocket"
 )
 
 type requestItem struct {
 	request parser.Request
+	marked  bool
 }
 
 func (i requestItem) FilterValue() string {
-	return i.request.Method + " " + i.request.URL
+	return i.request.Name + " " + i.request.Method + " " + i.request.URL
 }
 
 func (i requestItem) Title() string {
@@ -39,25 +54,57 @@ func (i requestItem) Description() string {
 	return i.request.Description
 }
 
+// Options configures a new Model.
+type Options struct {
+	ShowHeaders  bool
+	Environments parser.Environments
+	Environment  string
+	History      *history.Store
+	Tokens       *client.TokenCache
+	Cookies      *cookies.Store
+	Settings     client.Settings
+}
+
 type Model struct {
 	ParsedFile    *parser.ParsedFile
 	Requests      []parser.Request
 	Variables     map[string]string
+	Environments  parser.Environments
+	Environment   string
+	envVars       map[string]string
 	list          list.Model
+	envList       list.Model
+	historyList   list.Model
+	cookieList    list.Model
 	viewport      viewport.Model
 	CurrentView   ViewType
+	responseBack  ViewType
+	History       *history.Store
+	Cookies       *cookies.Store
 	LastResult    *client.ExecutionResult
+	diffView      *diffState
+	webSocket     *webSocketState
+	stream        *client.ResponseStream
+	markedRequest int
+	markedEntry   int
 	ShowHeaders   bool
 	ShowVariables bool
 	ErrorMsg      string
 	Width         int
 	Height        int
 	SpinnerFrame  int
+	CurlCommand   string
+	LoadingNote   string
+	loadingStart  time.Time
+	cancel        context.CancelFunc
 	executor      *client.Executor
+	tokens        *client.TokenCache
+	settings      client.Settings
+	prompts       chan string
 }
 
-func NewModel(parsedFile *parser.ParsedFile, envVars map[string]string, showHeaders bool) Model {
-	variables := parser.BuildVariableMap(parsedFile.Variables, envVars)
+func NewModel(parsedFile *parser.ParsedFile, envVars map[string]string, opts Options) Model {
+	variables := parser.BuildVariableMap(parsedFile.Variables, envVars, opts.Environments.Variables(opts.Environment))
 
 	items := make([]list.Item, len(parsedFile.Requests))
 	for i, req := range parsedFile.Requests {
@@ -71,38 +118,89 @@ func NewModel(parsedFile *parser.ParsedFile, envVars map[string]string, showHead
 	requestList.SetFilteringEnabled(true)
 	requestList.SetShowHelp(true)
 	requestList.DisableQuitKeybindings()
+	requestList.StatusMessageLifetime = 3 * time.Second
 
+	extraKeys := []key.Binding{
+		key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "curl")),
+		key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "compare")),
+	}
+	if len(opts.Environments.Names()) > 0 {
+		extraKeys = append(extraKeys, key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "environment")))
+	}
+	if opts.History != nil {
+		extraKeys = append(extraKeys, key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "history")))
+	}
+	if opts.Cookies != nil {
+		extraKeys = append(extraKeys, key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "cookies")))
+	}
 	requestList.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{}
+		return extraKeys
 	}
 	requestList.AdditionalFullHelpKeys = func() []key.Binding {
-		return []key.Binding{}
+		return extraKeys
 	}
+	requestList.Title = environmentTitle(opts.Environment)
 
 	vp := viewport.New(80, 20)
 
-	return Model{
+	m := Model{
 		ParsedFile:    parsedFile,
 		Requests:      parsedFile.Requests,
 		Variables:     variables,
+		Environments:  opts.Environments,
+		Environment:   opts.Environment,
+		envVars:       envVars,
 		list:          requestList,
+		envList:       newEnvironmentList(opts.Environments, opts.Environment),
+		historyList:   newHistoryList(),
+		cookieList:    newCookieList(),
+		History:       opts.History,
+		Cookies:       opts.Cookies,
+		markedRequest: -1,
+		markedEntry:   -1,
 		viewport:      vp,
 		CurrentView:   ViewList,
-		ShowHeaders:   showHeaders,
+		ShowHeaders:   opts.ShowHeaders,
 		ShowVariables: true,
 		Width:         80,
 		Height:        24,
 		SpinnerFrame:  0,
-		executor:      client.NewExecutor(variables),
+		tokens:        opts.Tokens,
+		settings:      opts.Settings,
+		prompts:       make(chan string, 8),
 	}
+	m.executor = m.newExecutor()
+	return m
 }
 
-func (m Model) Init() tea.Cmd {
-	return nil
+// newExecutor builds an executor for the current variables and requests.
+func (m Model) newExecutor() *client.Executor {
+	prompts := m.prompts
+	var jar http.CookieJar
+	if m.Cookies != nil {
+		jar = m.cookieJar()
+	}
+	return client.NewExecutor(m.Variables, client.Options{
+		Requests: m.Requests,
+		BaseDir:  filepath.Dir(m.ParsedFile.Path),
+		OAuth2:   m.Environments.OAuth2(m.Environment),
+		Tokens:   m.tokens,
+		Cookies:  jar,
+		Settings: m.settings,
+		TLS:      m.Environments.TLS(m.Environment),
+		// Interactive OAuth2 flows run while the loading view is shown;
+		// their instructions are picked up on the next tick.
+		Prompt: func(message string) {
+			select {
+			case prompts <- message:
+			default:
+			}
+		},
+	})
 }
 
-type executeFinishedMsg struct {
-	result *client.ExecutionResult
+func (m Model) Init() tea.Cmd {
+	return nil
 }
 
 type tickMsg time.Time
@@ -113,14 +211,51 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		if m.CurrentView == ViewList {
+			filtering := m.list.FilterState() == list.Filtering
 			switch msg.String() {
 			case "q", "ctrl+c":
 				return m, tea.Quit
+			case "e":
+				if !filtering && len(m.Environments.Names()) > 0 {
+					m.CurrentView = ViewEnvironments
+					return m, nil
+				}
+				m.list, cmd = m.list.Update(msg)
+				return m, cmd
+			case "H":
+				if !filtering && m.History != nil {
+					return m.openHistory()
+				}
+				m.list, cmd = m.list.Update(msg)
+				return m, cmd
+			case "C":
+				if !filtering && m.Cookies != nil {
+					return m.openCookies()
+				}
+				m.list, cmd = m.list.Update(msg)
+				return m, cmd
+			case "x":
+				if !filtering {
+					return m.markRequest()
+				}
+				m.list, cmd = m.list.Update(msg)
+				return m, cmd
+			case "c":
+				if selectedItem, ok := m.list.SelectedItem().(requestItem); ok && !filtering {
+					m.CurlCommand = curl.CommandFunc(&selectedItem.request, m.executor.Substitute)
+					m.CurrentView = ViewCurl
+					return m, nil
+				}
+				m.list, cmd = m.list.Update(msg)
+				return m, cmd
 			case "enter":
 				if selectedItem, ok := m.list.SelectedItem().(requestItem); ok {
-					m.CurrentView = ViewLoading
-					m.SpinnerFrame = 0
-					return m, tea.Batch(executeRequest(m.executor, &selectedItem.request), tick())
+					ctx := m.startLoading()
+					if selectedItem.request.IsWebSocket() {
+						return m, tea.Batch(openWebSocket(ctx, m.executor, &selectedItem.request), tick())
+					}
+					m.stream = m.executor.ExecuteStream(ctx, &selectedItem.request)
+					return m, tea.Batch(waitForStream(m.stream), tick())
 				}
 			default:
 				m.list, cmd = m.list.Update(msg)
@@ -134,32 +269,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.Width = msg.Width
 		m.Height = msg.Height
 
+		h, v := docStyle.GetFrameSize()
 		if m.CurrentView == ViewList {
-			h, v := docStyle.GetFrameSize()
 			m.list.SetSize(msg.Width-h, msg.Height-v)
 		}
+		m.envList.SetSize(msg.Width-h, msg.Height-v)
+		m.historyList.SetSize(msg.Width-h, msg.Height-v)
+		m.cookieList.SetSize(msg.Width-h, msg.Height-v)
 
 		m.viewport.Width = m.Width
 		m.viewport.Height = m.viewportHeight()
 		if m.CurrentView == ViewResponse {
 			m.rebuildViewportContent()
 		}
+		if m.CurrentView == ViewDiff {
+			m.rebuildDiffContent()
+		}
+		if m.CurrentView == ViewWebSocket {
+			m.resizeWebSocket()
+			m.rebuildWebSocketContent()
+		}
 		return m, nil
 
-	case executeFinishedMsg:
-		m.LastResult = msg.result
-		m.CurrentView = ViewResponse
+	case streamChangedMsg:
+		return m.updateStream(msg)
 
-		m.list.ResetFilter()
+	case webSocketOpenedMsg:
+		return m.showWebSocket(msg)
 
-		m.viewport.Width = m.Width
-		m.viewport.Height = m.viewportHeight()
-		m.rebuildViewportContent()
-		m.viewport.GotoTop()
-		return m, nil
+	case webSocketChangedMsg, webSocketSentMsg, webSocketClosedMsg:
+		return m.updateWebSocket(msg)
+
+	case diffFinishedMsg:
+		m.recordHistory(msg.left)
+		if msg.right != nil {
+			m.recordHistory(msg.right)
+		}
+		if msg.cancelled {
+			return m, nil
+		}
+		m.list.ResetFilter()
+		return m.showDiff(msg.left, msg.right, resultLabel(msg.left), resultLabel(msg.right), ViewList)
 
 	case tickMsg:
 		m.SpinnerFrame++
+		for drained := false; !drained; {
+			select {
+			case note := <-m.prompts:
+				m.LoadingNote = note
+			default:
+				drained = true
+			}
+		}
 		if m.CurrentView == ViewLoading {
 			return m, tick()
 		}
@@ -176,15 +337,70 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch m.CurrentView {
+	case ViewLoading:
+		return m.handleLoadingKeys(msg)
 	case ViewResponse:
 		return m.handleResponseKeys(msg)
 	case ViewError:
 		return m.handleErrorKeys(msg)
+	case ViewEnvironments:
+		return m.handleEnvironmentKeys(msg)
+	case ViewCurl:
+		return m.handleErrorKeys(msg)
+	case ViewHistory:
+		return m.handleHistoryKeys(msg)
+	case ViewDiff:
+		return m.handleDiffKeys(msg)
+	case ViewCookies:
+		return m.handleCookieKeys(msg)
+	case ViewWebSocket:
+		return m.handleWebSocketKeys(msg)
 	default:
 		return m, nil
 	}
 }
 
+// startLoading switches to the loading view and returns the context of the
+// request it waits for, which is cancelled by cancelLoading.
+func (m *Model) startLoading() context.Context {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.CurrentView = ViewLoading
+	m.SpinnerFrame = 0
+	m.LoadingNote = ""
+	m.loadingStart = time.Now()
+	return ctx
+}
+
+func (m Model) handleLoadingKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "ctrl+c":
+		return m.cancelLoading()
+	}
+	return m, nil
+}
+
+// cancelLoading cancels the request the loading view waits for and returns
+// to the list. Its result, failed as cancelled, is still recorded in the
+// history when it arrives.
+func (m Model) cancelLoading() (tea.Model, tea.Cmd) {
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+	m.CurrentView = ViewList
+
+	status := "Cancelled"
+	if selectedItem, ok := m.list.SelectedItem().(requestItem); ok {
+		status += " " + selectedItem.request.Method + " " + selectedItem.request.URL
+	}
+	status += fmt.Sprintf(" after %.1fs", time.Since(m.loadingStart).Seconds())
+	return m, m.list.NewStatusMessage(warningStyle.Render(status))
+}
+
 func (m Model) handleResponseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
@@ -202,10 +418,22 @@ func (m Model) handleResponseKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.rebuildViewportContent()
 		return m, nil
 
+	case "s":
+		if m.streaming() {
+			m.stream.Stop()
+		}
+		return m, nil
+
 	case "b", "esc":
-		m.CurrentView = ViewList
+		if m.streaming() {
+			m.stream.Stop()
+		}
+		m.CurrentView = m.responseBack
 		return m, nil
 
+	case "D":
+		return m.diffPrevious()
+
 	case "f":
 		if m.LastResult != nil && m.LastResult.Response != nil {
 			if client.IsJSON(m.LastResult.Response.ContentType) {
@@ -252,18 +480,23 @@ func (m Model) View() string {
 		return m.RenderLoadingView()
 	case ViewError:
 		return m.RenderErrorView()
+	case ViewEnvironments:
+		return m.RenderEnvironmentView()
+	case ViewCurl:
+		return m.RenderCurlView()
+	case ViewHistory:
+		return m.RenderHistoryView()
+	case ViewDiff:
+		return m.RenderDiffView()
+	case ViewCookies:
+		return m.RenderCookieView()
+	case ViewWebSocket:
+		return m.RenderWebSocketView()
 	default:
 		return "Unknown view"
 	}
 }
 
-func executeRequest(executor *client.Executor, req *parser.Request) tea.Cmd {
-	return func() tea.Msg {
-		result := executor.Execute(req)
-		return executeFinishedMsg{result: result}
-	}
-}
-
 func tick() tea.Cmd {
 	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg {
 		return tickMsg(t)
@@ -315,6 +548,19 @@ func (m Model) viewportHeight() int {
 	return max(m.Height-4, 3)
 }
 
+// showResult displays result in the response view; back is the view that
+// esc returns to.
+func (m *Model) showResult(result *client.ExecutionResult, back ViewType) {
+	m.LastResult = result
+	m.CurrentView = ViewResponse
+	m.responseBack = back
+
+	m.viewport.Width = m.Width
+	m.viewport.Height = m.viewportHeight()
+	m.rebuildViewportContent()
+	m.viewport.GotoTop()
+}
+
 func (m *Model) rebuildViewportContent() {
 	if m.LastResult == nil {
 		return
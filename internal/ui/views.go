@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 func (m Model) RenderListView() string {
@@ -41,23 +42,70 @@ func (m Model) RenderResponseView() string {
 	sb.WriteString("\n")
 	sb.WriteString(strings.Join(vpLines, "\n"))
 	sb.WriteString("\n")
-	sb.WriteString(margin + RenderBottomBorder(m.LastResult, bw, colPos))
+	if m.streaming() {
+		label := fmt.Sprintf("● streaming · %d events · s: stop", len(m.LastResult.Events))
+		sb.WriteString(margin + RenderLabelBorder(label, bw, colPos))
+	} else {
+		sb.WriteString(margin + RenderBottomBorder(m.LastResult, bw, colPos))
+	}
 	sb.WriteString("\n")
 	sb.WriteString(RenderHelpBar(ViewResponse))
 
 	return sb.String()
 }
 
+func (m Model) RenderDiffView() string {
+	if m.diffView == nil {
+		return errorStyle.Render("No diff to display")
+	}
+
+	bw := m.boxWidth()
+	margin := " "
+	colPos := m.contentWidth()/2 + 2
+
+	vpLines := strings.Split(m.viewport.View(), "\n")
+	for i, line := range vpLines {
+		vpLines[i] = margin + line
+	}
+
+	label := "no differences"
+	if n := len(m.diffView.result.Changes()); n == 1 {
+		label = "1 difference"
+	} else if n > 1 {
+		label = fmt.Sprintf("%d differences", n)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(margin + RenderTopBorder(bw))
+	sb.WriteString("\n")
+	sb.WriteString(strings.Join(vpLines, "\n"))
+	sb.WriteString("\n")
+	sb.WriteString(margin + RenderLabelBorder(label, bw, colPos))
+	sb.WriteString("\n")
+	sb.WriteString(RenderHelpBar(ViewDiff))
+
+	return sb.String()
+}
+
 func (m Model) RenderLoadingView() string {
 	var sb strings.Builder
 
 	sb.WriteString(RenderSpinner(m.SpinnerFrame))
+	sb.WriteString(mutedStyle.Render(fmt.Sprintf(" %.1fs", time.Since(m.loadingStart).Seconds())))
 	sb.WriteString("\n\n")
 
 	if selectedItem, ok := m.list.SelectedItem().(requestItem); ok {
 		sb.WriteString(mutedStyle.Render(fmt.Sprintf("Executing: %s %s", selectedItem.request.Method, selectedItem.request.URL)))
 	}
 
+	if m.LoadingNote != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(warningStyle.Render(m.LoadingNote))
+	}
+
+	sb.WriteString("\n\n")
+	sb.WriteString(RenderHelpBar(ViewLoading))
+
 	return docStyle.Render(sb.String())
 }
 
@@ -72,3 +120,28 @@ func (m Model) RenderErrorView() string {
 
 	return sb.String()
 }
+
+func (m Model) RenderEnvironmentView() string {
+	return docStyle.Render(m.envList.View())
+}
+
+func (m Model) RenderCookieView() string {
+	return docStyle.Render(m.cookieList.View())
+}
+
+func (m Model) RenderHistoryView() string {
+	return docStyle.Render(m.historyList.View())
+}
+
+func (m Model) RenderCurlView() string {
+	var sb strings.Builder
+
+	sb.WriteString(titleStyle.Render("curl"))
+	sb.WriteString("\n\n")
+	// Unwrapped, so that it can be copied as is.
+	sb.WriteString(m.CurlCommand)
+	sb.WriteString("\n\n")
+	sb.WriteString(RenderHelpBar(ViewCurl))
+
+	return docStyle.Render(sb.String())
+}
@@ -0,0 +1,271 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"httpyum/internal/client"
+	"httpyum/internal/parser"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// webSocketState is the open session of the WebSocket view.
+type webSocketState struct {
+	session *client.WebSocketSession
+	input   textinput.Model
+	// sendErr is the error of the last message that could not be sent.
+	sendErr string
+}
+
+type webSocketOpenedMsg struct {
+	session *client.WebSocketSession
+	result  *client.ExecutionResult
+	// cancelled is whether opening was cancelled from the loading view.
+	cancelled bool
+}
+
+type webSocketChangedMsg struct {
+	session *client.WebSocketSession
+}
+
+type webSocketSentMsg struct {
+	err error
+}
+
+type webSocketClosedMsg struct {
+	result *client.ExecutionResult
+}
+
+func openWebSocket(ctx context.Context, executor *client.Executor, req *parser.Request) tea.Cmd {
+	return func() tea.Msg {
+		session, result := executor.OpenWebSocket(ctx, req)
+		return webSocketOpenedMsg{session: session, result: result, cancelled: ctx.Err() != nil}
+	}
+}
+
+// waitForFrames reports the next message sent or received on session, or
+// its closing.
+func waitForFrames(session *client.WebSocketSession) tea.Cmd {
+	changed := session.Changed()
+	return func() tea.Msg {
+		<-changed
+		return webSocketChangedMsg{session: session}
+	}
+}
+
+func sendWebSocket(session *client.WebSocketSession, message string) tea.Cmd {
+	return func() tea.Msg {
+		return webSocketSentMsg{err: session.Send(message)}
+	}
+}
+
+func closeWebSocket(session *client.WebSocketSession) tea.Cmd {
+	return func() tea.Msg {
+		return webSocketClosedMsg{result: session.Close()}
+	}
+}
+
+// showWebSocket switches to the live view of a session that was opened, or
+// shows why it could not be.
+func (m Model) showWebSocket(msg webSocketOpenedMsg) (tea.Model, tea.Cmd) {
+	if msg.cancelled {
+		if msg.session != nil {
+			go msg.session.Close()
+		} else {
+			m.recordHistory(msg.result)
+		}
+		return m, nil
+	}
+
+	m.list.ResetFilter()
+	if msg.session == nil {
+		m.recordHistory(msg.result)
+		m.showResult(msg.result, ViewList)
+		return m, nil
+	}
+
+	input := textinput.New()
+	input.Placeholder = "message"
+	input.Prompt = "› "
+	input.CharLimit = 0
+	input.Focus()
+	m.webSocket = &webSocketState{session: msg.session, input: input}
+	m.CurrentView = ViewWebSocket
+	m.resizeWebSocket()
+	m.rebuildWebSocketContent()
+	return m, tea.Batch(waitForFrames(msg.session), textinput.Blink)
+}
+
+func (m Model) handleWebSocketKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	ws := m.webSocket
+	ws.sendErr = ""
+
+	switch msg.String() {
+	case "ctrl+c":
+		ws.session.Close()
+		return m, tea.Quit
+
+	case "esc":
+		return m, closeWebSocket(ws.session)
+
+	case "enter":
+		message := ws.input.Value()
+		if message == "" {
+			return m, nil
+		}
+		ws.input.Reset()
+		return m, sendWebSocket(ws.session, message)
+
+	case "up", "down", "pgup", "pgdown":
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+	}
+
+	ws.input, cmd = ws.input.Update(msg)
+	return m, cmd
+}
+
+// updateWebSocket handles the messages of an open session.
+func (m Model) updateWebSocket(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case webSocketChangedMsg:
+		if m.webSocket == nil || m.webSocket.session != msg.session {
+			return m, nil
+		}
+		atBottom := m.viewport.AtBottom()
+		m.rebuildWebSocketContent()
+		if atBottom {
+			m.viewport.GotoBottom()
+		}
+		if closed, _ := msg.session.Closed(); closed {
+			return m, nil
+		}
+		return m, waitForFrames(msg.session)
+
+	case webSocketSentMsg:
+		if m.webSocket == nil {
+			return m, nil
+		}
+		m.webSocket.sendErr = ""
+		if msg.err != nil {
+			m.webSocket.sendErr = msg.err.Error()
+		}
+		return m, nil
+
+	case webSocketClosedMsg:
+		m.webSocket = nil
+		m.recordHistory(msg.result)
+		m.showResult(msg.result, ViewList)
+		return m, nil
+	}
+	return m, nil
+}
+
+// resizeWebSocket fits the viewport and input to the window, leaving room
+// for the input line below the box.
+func (m *Model) resizeWebSocket() {
+	m.viewport.Width = m.Width
+	m.viewport.Height = max(m.viewportHeight()-1, 3)
+	m.webSocket.input.Width = max(m.contentWidth()-2, 10)
+}
+
+// rebuildWebSocketContent renders the transcript of the session in the box
+// of the response view, padded to the height of the viewport.
+func (m *Model) rebuildWebSocketContent() {
+	if m.webSocket == nil {
+		return
+	}
+	cw := m.contentWidth()
+	side := borderStyle.Render("│")
+
+	lines := strings.Split(RenderWebSocketFrames(m.webSocket.session.Frames(), cw), "\n")
+	for i, line := range lines {
+		lines[i] = side + " " + line + strings.Repeat(" ", max(cw-visualLength(line), 0)) + " " + side
+	}
+	for len(lines) < m.viewport.Height {
+		lines = append(lines, side+strings.Repeat(" ", cw+2)+side)
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+}
+
+// RenderWebSocketFrames renders a session transcript: each message with
+// its time and an arrow for its direction, JSON messages pretty-printed.
+func RenderWebSocketFrames(frames []client.WebSocketFrame, width int) string {
+	if len(frames) == 0 {
+		return mutedStyle.Render("Waiting for messages...")
+	}
+
+	var sb strings.Builder
+	for _, f := range frames {
+		arrow := successStyle.Render("←")
+		if f.Sent {
+			arrow = infoStyle.Render("→")
+		}
+		text := f.Text()
+		trimmed := strings.TrimSpace(text)
+		if !f.Binary && (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid(f.Data) {
+			text, _ = client.PrettyPrintJSON(f.Data)
+		}
+
+		prefix := mutedStyle.Render(f.Time.Format("15:04:05.000")) + " " + arrow + " "
+		indent := strings.Repeat(" ", 15)
+		for i, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+			for j, part := range wrapText(line, max(width-15, 10)) {
+				if i == 0 && j == 0 {
+					sb.WriteString(prefix)
+				} else {
+					sb.WriteString(indent)
+				}
+				sb.WriteString(part)
+				sb.WriteString("\n")
+			}
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (m Model) RenderWebSocketView() string {
+	ws := m.webSocket
+	if ws == nil {
+		return errorStyle.Render("No WebSocket session")
+	}
+
+	bw := m.boxWidth()
+	margin := " "
+
+	vpLines := strings.Split(m.viewport.View(), "\n")
+	for i, line := range vpLines {
+		vpLines[i] = margin + line
+	}
+
+	frames := ws.session.Frames()
+	label := fmt.Sprintf("connected · %d messages", len(frames))
+	if closed, err := ws.session.Closed(); closed {
+		label = fmt.Sprintf("closed · %d messages", len(frames))
+		if err != nil {
+			label = "closed: " + truncate(err.Error(), max(bw-30, 10))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(margin + RenderTopBorder(bw))
+	sb.WriteString("\n")
+	sb.WriteString(strings.Join(vpLines, "\n"))
+	sb.WriteString("\n")
+	sb.WriteString(margin + RenderLabelBorder(label, bw, 0))
+	sb.WriteString("\n")
+	if ws.sendErr != "" {
+		sb.WriteString(margin + errorStyle.Render("✗ "+ws.sendErr))
+	} else {
+		sb.WriteString(margin + ws.input.View())
+	}
+	sb.WriteString("\n")
+	sb.WriteString(RenderHelpBar(ViewWebSocket))
+
+	return sb.String()
+}